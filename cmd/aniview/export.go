@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/daannte/aniview/internal"
+)
+
+// runExport runs the export command, which writes a profile's whole list to a file
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	profileName := flags.String("profile", "", "profile to export, defaults to the default profile")
+	format := flags.String("format", internal.ExportJSON, "export format: json (lossless) or xml (MyAnimeList)")
+	output := flags.String("o", "-", "file to write the export to, - for stdout")
+	flags.Parse(args)
+
+	if *format != internal.ExportJSON && *format != internal.ExportXML {
+		fmt.Fprintf(os.Stderr, "Unknown format %q, use json or xml\n", *format)
+		return 1
+	}
+
+	config, err := commandProfile(*profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	tracker, _ := internal.NewProfileTracker(config)
+
+	export, err := internal.ExportList(config, tracker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching list: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating export: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	skipped := 0
+	if *format == internal.ExportXML {
+		skipped, err = export.WriteMALXML(w)
+	} else {
+		err = export.WriteJSON(w)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d entries.\n", len(export.Entries)-skipped)
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Left out %d entries that aren't on MyAnimeList.\n", skipped)
+	}
+	return 0
+}
+
+// runImport runs the import command, which applies an export to a profile's list
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	profileName := flags.String("profile", "", "profile to import into, defaults to the default profile")
+	dryRun := flags.Bool("dry-run", false, "print the planned changes without applying them")
+	yes := flags.Bool("yes", false, "apply the changes without asking")
+	rate := flags.Int("rate", 30, "maximum number of changes sent a minute")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: aniview import [flags] <file>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening export: %v\n", err)
+		return 1
+	}
+	imported, err := internal.ReadExport(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	config, err := commandProfile(*profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	// Imports are retried on rate limits instead of being queued in the journal
+	tracker, anilist := internal.NewProfileTracker(config)
+
+	if err := internal.ResolveMediaIDs(&imported, anilist); err != nil {
+		fmt.Fprintf(os.Stderr, "Error looking up anime: %v\n", err)
+		return 1
+	}
+	current, err := internal.ExportList(config, tracker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching list: %v\n", err)
+		return 1
+	}
+
+	changes, unmatched := internal.PlanImport(imported, current)
+	for _, entry := range unmatched {
+		fmt.Printf("Skipping %s, it isn't on AniList\n", entry.Title)
+	}
+	if len(unmatched) > 0 {
+		fmt.Println()
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("List already matches the export.")
+		return 0
+	}
+	printImportReport(changes)
+
+	if *dryRun {
+		fmt.Println("Dry run, nothing was changed.")
+		return 0
+	}
+	if !*yes && !confirm(fmt.Sprintf("Apply %d change(s) to %s on %s?", len(changes), config.Username, tracker.Name())) {
+		fmt.Println("Nothing was changed.")
+		return 0
+	}
+
+	internal.ApplyImport(changes, tracker, *rate, 10, func(done int) {
+		fmt.Printf("Sent %d of %d change(s)\n", done, len(changes))
+	})
+
+	failed := 0
+	for _, change := range changes {
+		if change.Err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to import %s: %v\n", change.Title, change.Err)
+			failed++
+		}
+	}
+	fmt.Printf("Applied %d of %d change(s).\n", len(changes)-failed, len(changes))
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// commandProfile returns the named profile, or the default one, without creating it
+func commandProfile(name string) (*internal.Config, error) {
+	profiles, err := internal.LoadProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize config: %w", err)
+	}
+
+	if name == "" {
+		name = profiles.DefaultName()
+	}
+	config, ok := profiles.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q doesn't exist, log in with aniview --profile %s first", name, name)
+	}
+	return config, nil
+}
+
+// printImportReport prints the entries an import adds and the fields it changes
+func printImportReport(changes []internal.ImportChange) {
+	var added, updated []internal.ImportChange
+	for _, change := range changes {
+		if change.Added {
+			added = append(added, change)
+		} else {
+			updated = append(updated, change)
+		}
+	}
+
+	if len(added) > 0 {
+		fmt.Printf("Add (%d)\n", len(added))
+		for _, change := range added {
+			fmt.Printf("  %s: %s, %d episode(s)\n", change.Title, *change.Update.Status, *change.Update.Progress)
+		}
+		fmt.Println()
+	}
+	if len(updated) > 0 {
+		fmt.Printf("Update (%d)\n", len(updated))
+		for _, change := range updated {
+			fmt.Printf("  %s\n", change.Title)
+			for _, diff := range change.Diffs {
+				fmt.Printf("    %-10s %-12s -> %s\n", diff.Field, orNone(diff.Current), orNone(diff.Imported))
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// confirm asks a yes or no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
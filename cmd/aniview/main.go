@@ -1,28 +1,85 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/daannte/aniview/internal"
 	"github.com/daannte/aniview/internal/ui"
+	"github.com/pkg/browser"
 )
 
 func main() {
-	// Ensure config exists
-	config, err := internal.EnsureConfigExists()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "sync":
+			os.Exit(runSync(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
+		}
+	}
+
+	profileName := flag.String("profile", "", "name of the profile to use, created if it doesn't exist")
+	trackerName := flag.String("tracker", internal.TrackerAniList, "tracker to keep a new profile's list on: anilist or mal")
+	flag.Parse()
+
+	if *trackerName != internal.TrackerAniList && *trackerName != internal.TrackerMyAnimeList {
+		fmt.Fprintf(os.Stderr, "Unknown tracker %q, use anilist or mal\n", *trackerName)
+		os.Exit(1)
+	}
+
+	profiles, err := internal.LoadProfiles()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing config: %v\n", err)
 		os.Exit(1)
 	}
 
-	anilist := internal.NewAniListClient(config.Token)
+	// Let the user pick in the TUI when several profiles exist and none was asked for
+	var config *internal.Config
+	var tracker internal.Tracker
+	var anilist *internal.AniListClient
+	if *profileName != "" || len(profiles.Profiles) <= 1 {
+		name := *profileName
+		if name == "" {
+			name = profiles.DefaultName()
+		}
+
+		// Ensure the profile exists
+		config, err = internal.EnsureProfile(profiles, name, *trackerName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing config: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Queue list changes on disk while AniList is unreachable
+		tracker, anilist, err = internal.NewProfileClients(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening journal: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Keep the browser's output from drawing over the UI when logging in again from it
+	browser.Stdout = io.Discard
+	browser.Stderr = io.Discard
 
 	// Start the UI
-	m := ui.NewModel(config, anilist)
+	presence := internal.NewPresence()
+	presence.Configure(profiles.Discord)
+	// Now playing on the desktop is optional, aniview works the same without it
+	nowPlaying, _ := internal.StartMPRIS()
+	m := ui.NewModel(profiles, config, tracker, anilist, presence, nowPlaying)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	_, err = p.Run()
+	presence.Close()
+	if nowPlaying != nil {
+		nowPlaying.Close()
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running UI: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/daannte/aniview/internal"
+)
+
+// runSync runs the sync command, which makes the AniList and MyAnimeList lists match
+func runSync(args []string) int {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	anilistName := flags.String("anilist", "", "AniList profile to sync, defaults to the first AniList profile")
+	malName := flags.String("mal", "", "MyAnimeList profile to sync, defaults to the first MyAnimeList profile")
+	policy := flags.String("policy", internal.SyncNewest, "which entry wins a conflict: "+strings.Join(internal.SyncPolicies, ", "))
+	includePrivate := flags.Bool("include-private", false, "also sync private AniList entries, which are public on MyAnimeList")
+	dryRun := flags.Bool("dry-run", false, "print the planned changes without applying them")
+	flags.Parse(args)
+
+	profiles, err := internal.LoadProfiles()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing config: %v\n", err)
+		return 1
+	}
+
+	anilistConfig, err := syncProfile(profiles, *anilistName, internal.TrackerAniList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	malConfig, err := syncProfile(profiles, *malName, internal.TrackerMyAnimeList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	anilist, _, err := internal.NewProfileClients(anilistConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening journal: %v\n", err)
+		return 1
+	}
+	mal, _, err := internal.NewProfileClients(malConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening journal: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Comparing AniList (%s) with MyAnimeList (%s)...\n\n", anilistConfig.Username, malConfig.Username)
+	lists, err := internal.FetchSyncLists(anilist, anilistConfig.UserID, mal)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching lists: %v\n", err)
+		return 1
+	}
+	changes, private, err := internal.PlanSync(lists, *policy, *includePrivate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(private) > 0 {
+		fmt.Printf("Left out private AniList entries (%d), sync them with --include-private\n", len(private))
+		for _, title := range private {
+			fmt.Printf("  %s\n", title)
+		}
+		fmt.Println()
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("Lists are already in sync.")
+		return 0
+	}
+	printSyncReport(changes)
+
+	if *dryRun {
+		fmt.Println("Dry run, nothing was changed.")
+		return 0
+	}
+
+	internal.ApplySync(changes, anilist, mal)
+
+	queued, failed := 0, 0
+	for _, change := range changes {
+		switch {
+		case errors.Is(change.Err, internal.ErrQueued):
+			fmt.Printf("Queued %s on %s until it is reachable\n", change.Title, internal.TrackerName(change.Target))
+			queued++
+		case change.Err != nil:
+			fmt.Fprintf(os.Stderr, "Failed to update %s on %s: %v\n", change.Title, internal.TrackerName(change.Target), change.Err)
+			failed++
+		}
+	}
+	fmt.Printf("Applied %d, queued %d and failed %d of %d change(s).\n", len(changes)-queued-failed, queued, failed, len(changes))
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// syncProfile returns the named profile, or picks the default or first profile on the tracker
+func syncProfile(profiles *internal.Profiles, name string, tracker string) (*internal.Config, error) {
+	matches := func(config *internal.Config) bool {
+		return config.UsesAniList() == (tracker == internal.TrackerAniList)
+	}
+
+	if name != "" {
+		config, ok := profiles.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("profile %q doesn't exist", name)
+		}
+		if !matches(config) {
+			return nil, fmt.Errorf("profile %q isn't a %s profile", name, internal.TrackerName(tracker))
+		}
+		return config, nil
+	}
+
+	if config, ok := profiles.Profiles[profiles.DefaultName()]; ok && matches(config) {
+		return config, nil
+	}
+	for _, name := range profiles.Names() {
+		if config := profiles.Profiles[name]; matches(config) {
+			return config, nil
+		}
+	}
+	return nil, fmt.Errorf("no %s profile, create one with --profile <name> --tracker %s", internal.TrackerName(tracker), tracker)
+}
+
+// printSyncReport prints the planned changes grouped by the list they are sent to
+func printSyncReport(changes []internal.SyncChange) {
+	for _, target := range []string{internal.TrackerAniList, internal.TrackerMyAnimeList} {
+		var added, updated []internal.SyncChange
+		for _, change := range changes {
+			if change.Target != target {
+				continue
+			}
+			if change.Added {
+				added = append(added, change)
+			} else {
+				updated = append(updated, change)
+			}
+		}
+
+		name := internal.TrackerName(target)
+		if len(added) > 0 {
+			fmt.Printf("Add to %s (%d)\n", name, len(added))
+			for _, change := range added {
+				fmt.Printf("  %s: %s, %d episode(s)\n", change.Title, *change.Update.Status, *change.Update.Progress)
+			}
+			fmt.Println()
+		}
+		if len(updated) > 0 {
+			fmt.Printf("Update on %s (%d)\n", name, len(updated))
+			for _, change := range updated {
+				fmt.Printf("  %s\n", change.Title)
+				for _, diff := range change.Diffs {
+					fmt.Printf("    %-10s AniList %-12s MyAnimeList %s\n", diff.Field, orNone(diff.AniList), orNone(diff.MAL))
+				}
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// orNone shows an empty value as "none"
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -1,19 +1,21 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"os/exec"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unicode"
-
-	"github.com/daannte/rich-go/client"
 )
 
 // Constants
@@ -153,8 +155,8 @@ func SearchAnime(query, mode string) (map[string]string, error) {
 	for _, anime := range response.Data.Shows.Edges {
 		var episodesStr string
 		if episodes, ok := anime.AvailableEpisodes.(map[string]interface{}); ok {
-			if subEpisodes, ok := episodes["sub"].(float64); ok {
-				episodesStr = fmt.Sprintf("%d", int(subEpisodes))
+			if count, ok := episodes[mode].(float64); ok {
+				episodesStr = fmt.Sprintf("%d", int(count))
 			} else {
 				episodesStr = "Unknown"
 			}
@@ -166,13 +168,14 @@ func SearchAnime(query, mode string) (map[string]string, error) {
 	return animeList, nil
 }
 
-// GetEpisodeURL gets stream URLs for a specific episode of an anime
-func GetEpisodeURL(id string, epNo int) ([]string, error) {
+// GetEpisodeURL gets stream URLs for a specific episode of an anime, in the source's
+// translation and with each provider's links in order of preferred quality
+func GetEpisodeURL(id string, epNo int, source SourceConfig) ([]string, error) {
 	// Prepare GraphQL query
 	query := `query($showId:String!,$translationType:VaildTranslationTypeEnumType!,$episodeString:String!){episode(showId:$showId,translationType:$translationType,episodeString:$episodeString){episodeString sourceUrls}}`
 	variables := map[string]string{
 		"showId":          id,
-		"translationType": "sub",
+		"translationType": source.Translation,
 		"episodeString":   fmt.Sprintf("%d", epNo),
 	}
 
@@ -216,13 +219,13 @@ func GetEpisodeURL(id string, epNo int) ([]string, error) {
 	}
 
 	// Process source URLs
-	return processSourceURLs(response.Data.Episode.SourceUrls)
+	return processSourceURLs(response.Data.Episode.SourceUrls, source.Quality)
 }
 
 // processSourceURLs processes the source URLs from the API response
 func processSourceURLs(sourceUrls []struct {
 	SourceUrl string `json:"sourceUrl"`
-},
+}, quality string,
 ) ([]string, error) {
 	// Pre-count valid URLs and create slice to preserve order
 	validURLs := make([]string, 0)
@@ -256,11 +259,11 @@ func processSourceURLs(sourceUrls []struct {
 		return nil, fmt.Errorf("no valid source URLs found in response")
 	}
 
-	return extractVideoLinks(validURLs)
+	return extractVideoLinks(validURLs, quality)
 }
 
 // extractVideoLinks extracts video links from the provider URLs concurrently
-func extractVideoLinks(validURLs []string) ([]string, error) {
+func extractVideoLinks(validURLs []string, quality string) ([]string, error) {
 	// Create channels for results
 	results := make(chan episodeResult, len(validURLs))
 	orderedResults := make([][]string, len(validURLs))
@@ -272,7 +275,7 @@ func extractVideoLinks(validURLs []string) ([]string, error) {
 
 	// Launch goroutines to process each URL concurrently
 	for i, sourceUrl := range validURLs {
-		go processProviderURL(i, sourceUrl, rateLimiter.C, results, highPriorityLink)
+		go processProviderURL(i, sourceUrl, quality, rateLimiter.C, results, highPriorityLink)
 	}
 
 	// First, try to get a high priority link with a short timeout
@@ -318,8 +321,9 @@ func extractVideoLinks(validURLs []string) ([]string, error) {
 	return allLinks, nil
 }
 
-// processProviderURL processes a single provider URL and extracts video links
-func processProviderURL(idx int, url string, rateLimiterC <-chan time.Time, results chan<- episodeResult, highPriorityLink chan<- []string) {
+// processProviderURL processes a single provider URL and extracts video links, ordered by
+// how close they are to the preferred quality
+func processProviderURL(idx int, url string, quality string, rateLimiterC <-chan time.Time, results chan<- episodeResult, highPriorityLink chan<- []string) {
 	<-rateLimiterC // Rate limit the requests
 
 	// Decode the provider ID
@@ -347,6 +351,7 @@ func processProviderURL(idx int, url string, rateLimiterC <-chan time.Time, resu
 
 	// Extract links from response
 	var links []string
+	var resolutions []string
 	for _, linkInterface := range linksInterface {
 		linkMap, ok := linkInterface.(map[string]interface{})
 		if !ok {
@@ -358,8 +363,11 @@ func processProviderURL(idx int, url string, rateLimiterC <-chan time.Time, resu
 			continue
 		}
 
+		resolution, _ := linkMap["resolutionStr"].(string)
 		links = append(links, link)
+		resolutions = append(resolutions, resolution)
 	}
+	sortByQuality(links, resolutions, quality)
 
 	// Check if any links are high priority
 	for _, link := range links {
@@ -382,6 +390,39 @@ func processProviderURL(idx int, url string, rateLimiterC <-chan time.Time, resu
 	}
 }
 
+// sortByQuality orders links by how close their resolution, such as "1080p", is to the
+// preferred quality. Links without a resolution go last. Links from one provider are
+// sorted, so quality only picks between the links of the domain PrioritizeLink chooses.
+func sortByQuality(links []string, resolutions []string, quality string) {
+	heights := make(map[string]int, len(links))
+	for i, link := range links {
+		heights[link], _ = strconv.Atoi(strings.TrimSuffix(strings.ToLower(resolutions[i]), "p"))
+	}
+
+	wanted, _ := strconv.Atoi(strings.TrimSuffix(quality, "p"))
+	rank := func(height int) int {
+		switch {
+		case height == 0:
+			return math.MaxInt
+		case quality == QualityWorst:
+			return height
+		case quality == QualityBest || wanted == 0:
+			return -height
+		}
+		// The closest resolution, preferring the higher one on a tie
+		distance := wanted - height
+		if distance < 0 {
+			distance = -distance*2 - 1
+		} else {
+			distance *= 2
+		}
+		return distance
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		return rank(heights[links[i]]) < rank(heights[links[j]])
+	})
+}
+
 // decodeProviderID decodes the encrypted provider ID
 func decodeProviderID(encoded string) string {
 	// Split the string into pairs of characters
@@ -484,7 +525,8 @@ func flattenResults(results [][]string) []string {
 	return allLinks
 }
 
-// PrioritizeLink selects the best link from available options
+// PrioritizeLink selects the first link of the highest priority domain. The domain always
+// wins over quality, which only orders the links within it.
 func PrioritizeLink(links []string) string {
 	if len(links) == 0 {
 		return ""
@@ -527,8 +569,44 @@ func PrioritizeLink(links []string) string {
 	return bestLink
 }
 
-// PlayEpisode plays an episode using IINA with a custom title
-func PlayEpisode(links []string, anime AnimeEntry) error {
+// playerCommand builds the command that plays a link in the configured player. Both
+// players take mpv's options, including the IPC socket playback is followed through.
+func playerCommand(player PlayerConfig, anime AnimeEntry, link string) (*exec.Cmd, error) {
+	path := player.Path
+	var cmdArgs []string
+	switch player.Name {
+	case PlayerIINA:
+		if path == "" {
+			path = iinaCliPath
+		}
+		cmdArgs = []string{
+			"--no-stdin",
+			"--keep-running",
+			"--mpv-force-media-title=" + anime.Title,
+			"--mpv-input-ipc-server=" + socketPath,
+		}
+	default:
+		if path == "" {
+			path = PlayerMPV
+		}
+		cmdArgs = []string{
+			"--force-media-title=" + anime.Title,
+			"--input-ipc-server=" + socketPath,
+		}
+	}
+	cmdArgs = append(cmdArgs, player.Args...)
+	cmdArgs = append(cmdArgs, link)
+
+	// Ensure that the player is available
+	if _, err := exec.LookPath(path); err != nil {
+		return nil, fmt.Errorf("%s not found: %w", player.Name, err)
+	}
+	return exec.Command(path, cmdArgs...), nil
+}
+
+// PlayEpisode plays an episode in the configured player with a custom title, telling the
+// listeners about playback until the player quits
+func PlayEpisode(player PlayerConfig, links []string, anime AnimeEntry, listeners ...PlaybackListener) error {
 	if len(links) == 0 {
 		return fmt.Errorf("no links available to play")
 	}
@@ -536,60 +614,36 @@ func PlayEpisode(links []string, anime AnimeEntry) error {
 	// Choose the best link
 	link := PrioritizeLink(links)
 
-	// Ensure that iina-cli is available
-	_, err := exec.LookPath(iinaCliPath)
+	cmd, err := playerCommand(player, anime, link)
 	if err != nil {
-		return fmt.Errorf("iina-cli not found: %w", err)
+		return err
 	}
 
-	// Prepare arguments for IINA
-	cmdArgs := []string{
-		"--no-stdin",
-		"--keep-running",
-		"--mpv-force-media-title=" + anime.Title,
-		"--mpv-input-ipc-server=" + socketPath,
-		link,
-	}
-
-	cmd := exec.Command(iinaCliPath, cmdArgs...)
-
-	// Set up Discord presence updating
-	quit := make(chan struct{})
+	// Pass playback changes on to the listeners while the player runs
+	ctx, cancel := context.WithCancel(context.Background())
 	var wg sync.WaitGroup
 	wg.Add(1)
-
 	go func() {
 		defer wg.Done()
-		defer client.Logout()
-
-		// Wait for the socket file to exist before proceeding
-		if err := waitForSocket(socketPath); err != nil {
-			fmt.Printf("Error waiting for socket: %v\n", err)
-			return
-		}
-
-		// Update Discord presence while video is playing
-		for {
-			select {
-			case <-quit:
-				return
-			default:
-				if err := DiscordPresence("1285024019447287921", anime); err != nil {
-					fmt.Printf("Error updating Discord presence: %v\n", err)
-				}
-				time.Sleep(1 * time.Second)
+		WatchPlayback(ctx, socketPath, func(state PlaybackState) {
+			for _, listener := range listeners {
+				listener.Playing(anime, state)
 			}
-		}
+		})
 	}()
 
-	// Run IINA
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to run IINA: %w", err)
-	}
+	// Run the player
+	err = cmd.Run()
 
 	// Clean up
-	close(quit)
+	cancel()
 	wg.Wait()
+	for _, listener := range listeners {
+		listener.Stopped()
+	}
 
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", player.Name, err)
+	}
 	return nil
 }
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -15,10 +18,53 @@ const (
 	timeout       = 10 * time.Second
 )
 
+// maxBatchSize is the most mutations sent in a single request, keeping it below AniList's query complexity limit
+const maxBatchSize = 25
+
+// savedEntryFields are the fields requested for a saved list entry
+const savedEntryFields = `
+			id
+			status
+			score
+			progress
+			repeat
+			notes
+			private
+			hiddenFromStatusLists
+			updatedAt
+			startedAt {
+				year
+				month
+				day
+			}
+			completedAt {
+				year
+				month
+				day
+			}
+		`
+
+// saveEntryArgumentTypes are the GraphQL types of the SaveMediaListEntry arguments
+var saveEntryArgumentTypes = map[string]string{
+	"mediaId":               "Int",
+	"status":                "MediaListStatus",
+	"score":                 "Float",
+	"progress":              "Int",
+	"repeat":                "Int",
+	"notes":                 "String",
+	"private":               "Boolean",
+	"hiddenFromStatusLists": "Boolean",
+	"startedAt":             "FuzzyDateInput",
+	"completedAt":           "FuzzyDateInput",
+	"priority":              "Int",
+	"customLists":           "[String]",
+}
+
 // AniListClient handles communication with the AniList API
 type AniListClient struct {
 	httpClient *http.Client
 	token      string
+	mutationQueue
 }
 
 // NewAniListClient creates a new AniList client with the given token
@@ -33,6 +79,19 @@ func NewAniListClient(token string) *AniListClient {
 
 // UpdateUserInfo fetches user information from AniList and updates the config
 func (c *AniListClient) UpdateUserInfo(config *Config) error {
+	if err := c.loadViewer(config); err != nil {
+		return err
+	}
+
+	// Save the updated config
+	if err := SaveConfig(config); err != nil {
+		return fmt.Errorf("failed to save updated config: %w", err)
+	}
+	return nil
+}
+
+// loadViewer fills in the config with the user the client's token belongs to
+func (c *AniListClient) loadViewer(config *Config) error {
 	query := `
 	query {
 		Viewer {
@@ -48,11 +107,6 @@ func (c *AniListClient) UpdateUserInfo(config *Config) error {
 
 	config.Username = response.Data.Viewer.Name
 	config.UserID = response.Data.Viewer.ID
-
-	// Save the updated config
-	if err := SaveConfig(config); err != nil {
-		return fmt.Errorf("failed to save updated config: %w", err)
-	}
 	return nil
 }
 
@@ -61,22 +115,37 @@ func (c *AniListClient) GetPlanned(userID int) ([]AnimeEntry, error) {
 	return c.getAnimeList(userID, "PLANNING")
 }
 
-// GetCurrentlyWatching fetches the user's currently watching anime list
+// GetCurrentlyWatching fetches the user's currently watching anime list, including rewatches
 func (c *AniListClient) GetCurrentlyWatching(userID int) ([]AnimeEntry, error) {
-	return c.getAnimeList(userID, "CURRENT")
+	return c.getAnimeList(userID, "CURRENT", "REPEATING")
+}
+
+// GetCompleted fetches the user's completed anime list
+func (c *AniListClient) GetCompleted(userID int) ([]AnimeEntry, error) {
+	return c.getAnimeList(userID, "COMPLETED")
+}
+
+// GetList fetches the user's anime list with any of the specified statuses
+func (c *AniListClient) GetList(userID int, statuses ...string) ([]AnimeEntry, error) {
+	return c.getAnimeList(userID, statuses...)
 }
 
-// getAnimeList fetches the user's anime list with the specified status
-func (c *AniListClient) getAnimeList(userID int, status string) ([]AnimeEntry, error) {
+// getAnimeList fetches the user's anime list with any of the specified statuses
+func (c *AniListClient) getAnimeList(userID int, statuses ...string) ([]AnimeEntry, error) {
 	query := `
-	query ($userId: Int, $status: MediaListStatus) {
-		MediaListCollection(userId: $userId, type: ANIME, status: $status) {
+	query ($userId: Int, $statuses: [MediaListStatus]) {
+		MediaListCollection(userId: $userId, type: ANIME, status_in: $statuses) {
 			lists {
 				name
 				entries {
 					id
 					status
+					score
 					progress
+					repeat
+					notes
+					private
+					hiddenFromStatusLists
 					updatedAt
 					startedAt {
 						year
@@ -118,49 +187,674 @@ func (c *AniListClient) getAnimeList(userID int, status string) ([]AnimeEntry, e
 	}
 	`
 	variables := map[string]interface{}{
-		"userId": userID,
-		"status": status,
+		"userId":   userID,
+		"statuses": statuses,
 	}
 
 	var response MediaListCollection
 	if err := c.executeQuery(query, variables, &response); err != nil {
-		return nil, fmt.Errorf("failed to fetch anime list with status %s: %w", status, err)
+		return nil, fmt.Errorf("failed to fetch anime list with status %s: %w", strings.Join(statuses, "/"), err)
 	}
 
 	return convertToAnimeEntries(response), nil
 }
 
-// UpdateProgress updates the progress of an anime
-func (c *AniListClient) UpdateProgress(mediaID int, progress int) error {
-	return c.UpdateAnime(mediaID, progress, "")
+// GetFullList fetches every entry on the user's list with all of its fields, including
+// the ones aniview doesn't show, along with the user's score format
+func (c *AniListClient) GetFullList(userID int) (FullMediaListCollectionResponse, error) {
+	query := `
+	query ($userId: Int) {
+		MediaListCollection(userId: $userId, type: ANIME) {
+			user {
+				id
+				name
+				mediaListOptions {
+					scoreFormat
+				}
+			}
+			lists {
+				name
+				isCustomList
+				entries {
+					id
+					status
+					score
+					progress
+					repeat
+					priority
+					notes
+					private
+					hiddenFromStatusLists
+					customLists(asArray: false)
+					advancedScores
+					createdAt
+					updatedAt
+					startedAt {
+						year
+						month
+						day
+					}
+					completedAt {
+						year
+						month
+						day
+					}
+					media {
+						id
+						idMal
+						title {
+							romaji
+							english
+							native
+						}
+						episodes
+						format
+					}
+				}
+			}
+		}
+	}
+	`
+	variables := map[string]interface{}{
+		"userId": userID,
+	}
+
+	var response FullMediaListCollectionResponse
+	if err := c.executeQuery(query, variables, &response); err != nil {
+		return response, fmt.Errorf("failed to fetch full anime list: %w", err)
+	}
+	return response, nil
 }
 
-// UpdateAnime updates both progress and status of an anime
-func (c *AniListClient) UpdateAnime(mediaID int, progress int, status string) error {
+// GetMediaByMalIDs fetches the AniList media for MyAnimeList IDs, keyed by MyAnimeList ID
+func (c *AniListClient) GetMediaByMalIDs(malIDs []int) (map[int]Media, error) {
 	query := `
-	mutation ($mediaId: Int, $progress: Int, $status: MediaListStatus) {
-		SaveMediaListEntry(mediaId: $mediaId, progress: $progress, status: $status) {
-			id
-			progress
-			status
+	query ($ids: [Int], $page: Int) {
+		Page(page: $page, perPage: 50) {
+			pageInfo {
+				currentPage
+				hasNextPage
+			}
+			media(idMal_in: $ids, type: ANIME) {
+				id
+				idMal
+				title {
+					romaji
+					english
+					native
+				}
+				episodes
+				format
+				status
+				description
+				coverImage {
+					medium
+					large
+				}
+				averageScore
+				seasonYear
+				season
+				nextAiringEpisode {
+					episode
+					timeUntilAiring
+				}
+			}
+		}
+	}
+	`
+
+	media := make(map[int]Media, len(malIDs))
+	// Ask for a page worth of IDs at a time
+	for start := 0; start < len(malIDs); start += 50 {
+		end := min(start+50, len(malIDs))
+		variables := map[string]interface{}{
+			"ids":  malIDs[start:end],
+			"page": 1,
+		}
+
+		var response MediaPageResponse
+		if err := c.executeQuery(query, variables, &response); err != nil {
+			return nil, fmt.Errorf("failed to fetch media by MyAnimeList ID: %w", err)
+		}
+		for _, m := range response.Data.Page.Media {
+			media[m.MalId] = m
+		}
+	}
+
+	return media, nil
+}
+
+// GetMalID fetches the MyAnimeList ID of an AniList media, 0 if it has none
+func (c *AniListClient) GetMalID(mediaID int) (int, error) {
+	query := `
+	query ($id: Int) {
+		Media(id: $id, type: ANIME) {
+			idMal
+		}
+	}
+	`
+	var response struct {
+		Data struct {
+			Media Media `json:"Media"`
+		} `json:"data"`
+	}
+	if err := c.executeQuery(query, map[string]interface{}{"id": mediaID}, &response); err != nil {
+		return 0, fmt.Errorf("failed to fetch MyAnimeList ID: %w", err)
+	}
+
+	return response.Data.Media.MalId, nil
+}
+
+// SearchMedia searches the AniList catalog for anime matching the filter
+func (c *AniListClient) SearchMedia(filter MediaSearchFilter, page int) ([]Media, PageInfo, error) {
+	query := `
+	query ($page: Int, $search: String, $format: MediaFormat, $season: MediaSeason, $seasonYear: Int, $genre: String, $sort: [MediaSort]) {
+		Page(page: $page, perPage: 50) {
+			pageInfo {
+				currentPage
+				hasNextPage
+			}
+			media(type: ANIME, isAdult: false, search: $search, format: $format, season: $season, seasonYear: $seasonYear, genre: $genre, sort: $sort) {
+				id
+				title {
+					romaji
+					english
+					native
+				}
+				episodes
+				format
+				status
+				description
+				coverImage {
+					medium
+					large
+				}
+				idMal
+				averageScore
+				seasonYear
+				season
+				genres
+				popularity
+				startDate {
+					year
+					month
+					day
+				}
+				mediaListEntry {
+					id
+					status
+				}
+			}
+		}
+	}
+	`
+	variables := map[string]interface{}{
+		"page": page,
+		"sort": []string{"POPULARITY_DESC"},
+	}
+	if filter.Search != "" {
+		variables["search"] = filter.Search
+		variables["sort"] = []string{"SEARCH_MATCH", "POPULARITY_DESC"}
+	}
+	if filter.Format != "" {
+		variables["format"] = filter.Format
+	}
+	if filter.Season != "" {
+		variables["season"] = filter.Season
+	}
+	if filter.SeasonYear != 0 {
+		variables["seasonYear"] = filter.SeasonYear
+	}
+	if filter.Genre != "" {
+		variables["genre"] = filter.Genre
+	}
+
+	var response MediaPageResponse
+	if err := c.executeQuery(query, variables, &response); err != nil {
+		return nil, PageInfo{}, fmt.Errorf("failed to search anime: %w", err)
+	}
+
+	return response.Data.Page.Media, response.Data.Page.PageInfo, nil
+}
+
+// GetSeasonChart fetches every anime of the given season, following pagination
+func (c *AniListClient) GetSeasonChart(season string, year int) ([]Media, error) {
+	filter := MediaSearchFilter{
+		Season:     season,
+		SeasonYear: year,
+	}
+
+	var media []Media
+	for page := 1; ; page++ {
+		results, pageInfo, err := c.SearchMedia(filter, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s %d chart: %w", season, year, err)
+		}
+		media = append(media, results...)
+
+		if !pageInfo.HasNextPage {
+			break
+		}
+	}
+
+	return media, nil
+}
+
+// GetAiringSchedule fetches the episodes of the given anime airing between from and to
+func (c *AniListClient) GetAiringSchedule(mediaIDs []int, from, to time.Time) ([]AiringSchedule, error) {
+	query := `
+	query ($page: Int, $mediaIds: [Int], $from: Int, $to: Int) {
+		Page(page: $page, perPage: 50) {
+			pageInfo {
+				currentPage
+				hasNextPage
+			}
+			airingSchedules(mediaId_in: $mediaIds, airingAt_greater: $from, airingAt_lesser: $to, sort: TIME) {
+				id
+				episode
+				airingAt
+				mediaId
+			}
+		}
+	}
+	`
+
+	var schedules []AiringSchedule
+	if len(mediaIDs) == 0 {
+		return schedules, nil
+	}
+
+	for page := 1; ; page++ {
+		variables := map[string]interface{}{
+			"page":     page,
+			"mediaIds": mediaIDs,
+			"from":     from.Unix() - 1,
+			"to":       to.Unix(),
+		}
+
+		var response AiringSchedulePageResponse
+		if err := c.executeQuery(query, variables, &response); err != nil {
+			return nil, fmt.Errorf("failed to fetch airing schedule: %w", err)
+		}
+		schedules = append(schedules, response.Data.Page.AiringSchedules...)
+
+		if !response.Data.Page.PageInfo.HasNextPage {
+			break
+		}
+	}
+
+	return schedules, nil
+}
+
+// GetNotifications fetches the user's most recent notifications and unread count.
+// When markRead is set AniList resets the unread count, marking every notification as read.
+func (c *AniListClient) GetNotifications(markRead bool) ([]Notification, int, error) {
+	query := `
+	query ($reset: Boolean) {
+		Viewer {
+			unreadNotificationCount
+		}
+		Page(page: 1, perPage: 50) {
+			pageInfo {
+				currentPage
+				hasNextPage
+			}
+			notifications(resetNotificationCount: $reset) {
+				... on AiringNotification {
+					id
+					type
+					createdAt
+					episode
+					contexts
+					animeId
+					media {
+						id
+						title {
+							romaji
+							english
+						}
+					}
+				}
+				... on RelatedMediaAdditionNotification {
+					id
+					type
+					createdAt
+					context
+					media {
+						id
+						title {
+							romaji
+							english
+						}
+					}
+				}
+				... on MediaDataChangeNotification {
+					id
+					type
+					createdAt
+					context
+					reason
+					media {
+						id
+						title {
+							romaji
+							english
+						}
+					}
+				}
+				... on ActivityLikeNotification {
+					id
+					type
+					createdAt
+					context
+					user {
+						name
+					}
+				}
+				... on ActivityReplyNotification {
+					id
+					type
+					createdAt
+					context
+					user {
+						name
+					}
+				}
+				... on ActivityMentionNotification {
+					id
+					type
+					createdAt
+					context
+					user {
+						name
+					}
+				}
+				... on FollowingNotification {
+					id
+					type
+					createdAt
+					context
+					user {
+						name
+					}
+				}
+			}
 		}
 	}
 	`
 	variables := map[string]interface{}{
-		"mediaId":  mediaID,
-		"progress": progress,
+		"reset": markRead,
+	}
+
+	var response NotificationPageResponse
+	if err := c.executeQuery(query, variables, &response); err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch notifications: %w", err)
 	}
 
-	if status != "" {
-		variables["status"] = status
+	unread := response.Data.Viewer.UnreadNotificationCount
+	if markRead {
+		unread = 0
 	}
 
-	var response map[string]interface{}
+	return response.Data.Page.Notifications, unread, nil
+}
+
+// GetUnreadNotificationCount fetches the number of unread notifications
+func (c *AniListClient) GetUnreadNotificationCount() (int, error) {
+	query := `
+	query {
+		Viewer {
+			unreadNotificationCount
+		}
+	}
+	`
+	var response NotificationPageResponse
+	if err := c.executeQuery(query, nil, &response); err != nil {
+		return 0, fmt.Errorf("failed to fetch notification count: %w", err)
+	}
+
+	return response.Data.Viewer.UnreadNotificationCount, nil
+}
+
+// GetRelations fetches the sequels, prequels and side stories of an anime
+func (c *AniListClient) GetRelations(mediaID int) ([]MediaRelation, error) {
+	query := `
+	query ($id: Int) {
+		Media(id: $id) {
+			relations {
+				edges {
+					relationType(version: 2)
+					node {
+						id
+						type
+						title {
+							romaji
+							english
+							native
+						}
+						episodes
+						format
+						status
+						averageScore
+						mediaListEntry {
+							id
+							status
+						}
+					}
+				}
+			}
+		}
+	}
+	`
+	variables := map[string]interface{}{
+		"id": mediaID,
+	}
+
+	var response MediaRelationsResponse
 	if err := c.executeQuery(query, variables, &response); err != nil {
-		return fmt.Errorf("failed to update anime (mediaID: %d): %w", mediaID, err)
+		return nil, fmt.Errorf("failed to fetch relations (mediaID: %d): %w", mediaID, err)
 	}
 
-	return nil
+	var relations []MediaRelation
+	for _, edge := range response.Data.Media.Relations.Edges {
+		if edge.Node.Type != "ANIME" {
+			continue
+		}
+		switch edge.RelationType {
+		case "SEQUEL", "PREQUEL", "SIDE_STORY":
+			relations = append(relations, MediaRelation{
+				RelationType: edge.RelationType,
+				Media:        edge.Node,
+			})
+		}
+	}
+
+	return relations, nil
+}
+
+// Name returns the name of the service shown to the user
+func (c *AniListClient) Name() string {
+	return "AniList"
+}
+
+// SaveEntry saves a list entry and returns it as stored by AniList. If AniList cannot be
+// reached and a journal is set, the change is queued and an error wrapping ErrQueued is returned.
+func (c *AniListClient) SaveEntry(update ListEntryUpdate) (*MediaListEntry, error) {
+	return c.save(update, c.saveEntry)
+}
+
+// ReplayPending sends the queued list changes to AniList in order
+func (c *AniListClient) ReplayPending() (int, error) {
+	return c.replay(c.saveEntry)
+}
+
+// saveEntry sends a SaveMediaListEntry mutation and returns the entry as stored by AniList
+func (c *AniListClient) saveEntry(update ListEntryUpdate) (*MediaListEntry, error) {
+	query := `
+	mutation ($mediaId: Int, $status: MediaListStatus, $score: Float, $progress: Int, $repeat: Int, $notes: String, $private: Boolean, $hiddenFromStatusLists: Boolean, $startedAt: FuzzyDateInput, $completedAt: FuzzyDateInput, $priority: Int, $customLists: [String]) {
+		SaveMediaListEntry(mediaId: $mediaId, status: $status, score: $score, progress: $progress, repeat: $repeat, notes: $notes, private: $private, hiddenFromStatusLists: $hiddenFromStatusLists, startedAt: $startedAt, completedAt: $completedAt, priority: $priority, customLists: $customLists) {` + savedEntryFields + `}
+	}
+	`
+
+	var response SaveMediaListEntryResponse
+	if err := c.executeQuery(query, update.variables(), &response); err != nil {
+		return nil, fmt.Errorf("failed to update anime (mediaID: %d): %w", update.MediaID, err)
+	}
+
+	return &response.Data.SaveMediaListEntry, nil
+}
+
+// SaveEntries saves many list entries, sending up to maxBatchSize of them per request.
+// The returned entries and errors line up with the updates. Changes that fail because
+// AniList cannot be reached are queued like with SaveEntry.
+func (c *AniListClient) SaveEntries(updates []ListEntryUpdate) ([]*MediaListEntry, []error) {
+	return c.saveAll(updates, c.saveEntries)
+}
+
+// saveEntries sends the updates in batches of aliased SaveMediaListEntry mutations
+func (c *AniListClient) saveEntries(updates []ListEntryUpdate) ([]*MediaListEntry, []error) {
+	entries := make([]*MediaListEntry, len(updates))
+	errs := make([]error, len(updates))
+	for start := 0; start < len(updates); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(updates))
+		c.saveBatch(updates[start:end], entries[start:end], errs[start:end])
+	}
+	return entries, errs
+}
+
+// saveBatch sends the updates in a single GraphQL document, one aliased mutation each,
+// and fills in the result of every mutation
+func (c *AniListClient) saveBatch(updates []ListEntryUpdate, entries []*MediaListEntry, errs []error) {
+	var declarations, mutations []string
+	variables := make(map[string]interface{})
+	for i, update := range updates {
+		alias := fmt.Sprintf("e%d", i)
+
+		fields := update.variables()
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		arguments := make([]string, len(names))
+		for j, name := range names {
+			variable := alias + "_" + name
+			declarations = append(declarations, fmt.Sprintf("$%s: %s", variable, saveEntryArgumentTypes[name]))
+			arguments[j] = fmt.Sprintf("%s: $%s", name, variable)
+			variables[variable] = fields[name]
+		}
+		mutations = append(mutations, fmt.Sprintf("\t%s: SaveMediaListEntry(%s) {%s}", alias, strings.Join(arguments, ", "), savedEntryFields))
+	}
+	query := fmt.Sprintf("mutation (%s) {\n%s\n}", strings.Join(declarations, ", "), strings.Join(mutations, "\n"))
+
+	var response struct {
+		Data map[string]*MediaListEntry `json:"data"`
+	}
+	err := c.executeQuery(query, variables, &response)
+
+	// AniList fails the request when any mutation fails, but still returns the others
+	var apiErrs APIErrors
+	if err != nil && (IsTemporary(err) || IsAuthError(err) || !errors.As(err, &apiErrs) || len(response.Data) == 0) {
+		for i, update := range updates {
+			errs[i] = fmt.Errorf("failed to update anime (mediaID: %d): %w", update.MediaID, err)
+		}
+		return
+	}
+
+	for i, update := range updates {
+		alias := fmt.Sprintf("e%d", i)
+		if entry := response.Data[alias]; entry != nil {
+			entries[i] = entry
+			continue
+		}
+		var itemErr error = errors.New("no result returned")
+		if apiErr := apiErrs.ForPath(alias); apiErr != nil {
+			itemErr = apiErr
+		}
+		errs[i] = fmt.Errorf("failed to update anime (mediaID: %d): %w", update.MediaID, itemErr)
+	}
+}
+
+// GetScoreFormat fetches the score format the user has chosen on AniList
+func (c *AniListClient) GetScoreFormat() (string, error) {
+	query := `
+	query {
+		Viewer {
+			mediaListOptions {
+				scoreFormat
+			}
+		}
+	}
+	`
+	var response ViewerOptionsResponse
+	if err := c.executeQuery(query, nil, &response); err != nil {
+		return "", fmt.Errorf("failed to fetch score format: %w", err)
+	}
+
+	return response.Data.Viewer.MediaListOptions.ScoreFormat, nil
+}
+
+// variables converts the update into GraphQL variables, skipping unset fields
+func (u ListEntryUpdate) variables() map[string]interface{} {
+	variables := map[string]interface{}{
+		"mediaId": u.MediaID,
+	}
+
+	if u.Status != nil {
+		variables["status"] = *u.Status
+	}
+	if u.Score != nil {
+		variables["score"] = *u.Score
+	}
+	if u.Progress != nil {
+		variables["progress"] = *u.Progress
+	}
+	if u.Repeat != nil {
+		variables["repeat"] = *u.Repeat
+	}
+	if u.Notes != nil {
+		variables["notes"] = *u.Notes
+	}
+	if u.Private != nil {
+		variables["private"] = *u.Private
+	}
+	if u.HiddenFromStatusLists != nil {
+		variables["hiddenFromStatusLists"] = *u.HiddenFromStatusLists
+	}
+	if u.StartedAt != nil {
+		variables["startedAt"] = fuzzyDateInput(*u.StartedAt)
+	}
+	if u.CompletedAt != nil {
+		variables["completedAt"] = fuzzyDateInput(*u.CompletedAt)
+	}
+	if u.Priority != nil {
+		variables["priority"] = *u.Priority
+	}
+	if u.CustomLists != nil {
+		variables["customLists"] = u.CustomLists
+	}
+
+	return variables
+}
+
+// fuzzyDateInput converts a date into a FuzzyDateInput, sending null for unset parts so they get cleared
+func fuzzyDateInput(date Date) map[string]interface{} {
+	input := map[string]interface{}{
+		"year":  nil,
+		"month": nil,
+		"day":   nil,
+	}
+	if date.Year != 0 {
+		input["year"] = date.Year
+	}
+	if date.Month != 0 {
+		input["month"] = date.Month
+	}
+	if date.Day != 0 {
+		input["day"] = date.Day
+	}
+	return input
 }
 
 // executeQuery executes a GraphQL query against the AniList API
@@ -190,12 +884,15 @@ func (c *AniListClient) executeQuery(query string, variables map[string]interfac
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	// Catalog queries also work without a token, e.g. for MyAnimeList profiles
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
 
 	// Send the request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return &RequestError{Err: err}
 	}
 	defer resp.Body.Close()
 
@@ -205,50 +902,66 @@ func (c *AniListClient) executeQuery(query string, variables map[string]interfac
 		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("request failed with status code %d: %s", resp.StatusCode, respBody)
+	// AniList reports errors in a GraphQL errors array, with either a 200 or an error status
+	apiErrs := parseAPIErrors(respBody, resp.StatusCode)
+	if resp.StatusCode == http.StatusUnauthorized || errors.Is(apiErrs, ErrUnauthorized) {
+		return &AuthError{&RequestError{StatusCode: resp.StatusCode, Body: string(respBody), Err: errorOrNil(apiErrs)}}
+	}
+	if resp.StatusCode != http.StatusOK && apiErrs == nil {
+		return &RequestError{StatusCode: resp.StatusCode, Body: string(respBody), RetryAfter: retryAfter(resp.Header)}
 	}
 
-	// Parse the response
-	if err := json.Unmarshal(respBody, result); err != nil {
+	// Parse the response, which holds the results that succeeded even when some failed
+	if err := json.Unmarshal(respBody, result); err != nil && apiErrs == nil {
 		return fmt.Errorf("failed to parse response: %w", err)
 	}
+	if apiErrs != nil {
+		return &RequestError{StatusCode: resp.StatusCode, Body: string(respBody), Err: apiErrs, RetryAfter: retryAfter(resp.Header)}
+	}
 
 	return nil
 }
 
+// errorOrNil keeps a nil APIErrors from becoming a non-nil error
+func errorOrNil(errs APIErrors) error {
+	if errs == nil {
+		return nil
+	}
+	return errs
+}
+
 // Helper function to convert MediaListCollection to AnimeEntry slice
 func convertToAnimeEntries(response MediaListCollection) []AnimeEntry {
 	var animeList []AnimeEntry
 
 	for _, list := range response.Data.MediaListCollection.Lists {
 		for _, entry := range list.Entries {
-			nextEp := entry.Progress + 1
+			anime := newAnimeEntry(entry.Media)
+			anime.ApplyListEntry(entry)
 
-			title := entry.Media.Title.English
-			if title == "" {
-				title = entry.Media.Title.Romaji
-			}
-
-			maxEpisodes := entry.Media.Episodes
-			if !entry.Media.NextAiringEpisode.IsEmpty() {
-				maxEpisodes = entry.Media.NextAiringEpisode.Episode - 1
-			}
-
-			animeList = append(animeList, AnimeEntry{
-				Title:             title,
-				Progress:          entry.Progress,
-				Episodes:          maxEpisodes,
-				ID:                entry.Media.ID,
-				MalId:             entry.Media.MalId,
-				CoverImage:        entry.Media.CoverImage.Medium,
-				Description:       entry.Media.Description,
-				NextEpisode:       nextEp,
-				IsAiring:          !entry.Media.NextAiringEpisode.IsEmpty(),
-				NextAiringEpisode: entry.Media.NextAiringEpisode,
-			})
+			animeList = append(animeList, anime)
 		}
 	}
 
 	return animeList
 }
+
+// newAnimeEntry creates an anime entry for a media, without list entry fields
+func newAnimeEntry(media Media) AnimeEntry {
+	maxEpisodes := media.Episodes
+	if !media.NextAiringEpisode.IsEmpty() {
+		maxEpisodes = media.NextAiringEpisode.Episode - 1
+	}
+
+	return AnimeEntry{
+		Title:             media.Title.Preferred(),
+		Episodes:          maxEpisodes,
+		ID:                media.ID,
+		MalId:             media.MalId,
+		CoverImage:        media.CoverImage.Medium,
+		Description:       media.Description,
+		IsAiring:          !media.NextAiringEpisode.IsEmpty(),
+		NextAiringEpisode: media.NextAiringEpisode,
+		MediaStatus:       media.Status,
+	}
+}
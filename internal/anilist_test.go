@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSaveBatch(t *testing.T) {
+	aliases := regexp.MustCompile(`(e\d+): SaveMediaListEntry`)
+
+	tests := []struct {
+		name    string
+		updates int
+		status  int            // HTTP status of every response
+		failed  map[int]string // Errors returned for media IDs, by path below the alias
+		noData  bool           // Whether AniList returns no data at all
+		// Expected mutations per request and the error kind of each failed media ID
+		wantBatches []int
+		wantErrs    map[int]error
+	}{
+		{
+			name:        "all saved",
+			updates:     3,
+			status:      http.StatusOK,
+			wantBatches: []int{3},
+		},
+		{
+			name:        "split into batches with some failing",
+			updates:     maxBatchSize + 2,
+			status:      http.StatusOK,
+			failed:      map[int]string{4: "", 26: "progress"},
+			wantBatches: []int{maxBatchSize, 2},
+			wantErrs:    map[int]error{4: ErrNotFound, 26: ErrValidation},
+		},
+		{
+			name:        "request failed",
+			updates:     2,
+			status:      http.StatusBadGateway,
+			wantBatches: []int{2},
+			wantErrs:    map[int]error{1: nil, 2: nil},
+		},
+		{
+			name:        "no data returned",
+			updates:     2,
+			status:      http.StatusOK,
+			failed:      map[int]string{1: ""},
+			noData:      true,
+			wantBatches: []int{2},
+			wantErrs:    map[int]error{1: ErrNotFound, 2: ErrNotFound},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var batches []int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var request struct {
+					Query     string                 `json:"query"`
+					Variables map[string]interface{} `json:"variables"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				matches := aliases.FindAllStringSubmatch(request.Query, -1)
+				mu.Lock()
+				batches = append(batches, len(matches))
+				mu.Unlock()
+
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+					fmt.Fprint(w, "<html>Bad Gateway</html>")
+					return
+				}
+
+				// Answer every mutation with its media ID, or an error for the mutation
+				data := make(map[string]interface{})
+				var errs []map[string]interface{}
+				for _, match := range matches {
+					alias := match[1]
+					mediaID := int(request.Variables[alias+"_mediaId"].(float64))
+					field, failed := tt.failed[mediaID]
+					if !failed {
+						data[alias] = map[string]interface{}{"id": 1000 + mediaID, "media": map[string]int{"id": mediaID}}
+						continue
+					}
+					data[alias] = nil
+					if field == "" {
+						errs = append(errs, map[string]interface{}{"message": "Not Found.", "status": 404, "path": []string{alias}})
+					} else {
+						errs = append(errs, map[string]interface{}{"message": "validation", "status": 400, "path": []string{alias, field}, "validation": map[string][]string{field: {"Too high."}}})
+					}
+				}
+				response := map[string]interface{}{"data": data, "errors": errs}
+				if tt.noData {
+					response["data"] = nil
+				}
+				json.NewEncoder(w).Encode(response)
+			}))
+			defer server.Close()
+
+			client := NewAniListClient("token")
+			client.httpClient.Transport = redirectTransport{server.URL}
+
+			updates := make([]ListEntryUpdate, tt.updates)
+			for i := range updates {
+				progress := i
+				updates[i] = ListEntryUpdate{MediaID: i + 1, Progress: &progress}
+			}
+			entries, errs := client.saveEntries(updates)
+
+			if !slices.Equal(batches, tt.wantBatches) {
+				t.Errorf("sent batches of %v mutations, want %v", batches, tt.wantBatches)
+			}
+			if len(entries) != len(updates) || len(errs) != len(updates) {
+				t.Fatalf("saveEntries() returned %d entries and %d errors, want %d of each", len(entries), len(errs), len(updates))
+			}
+			for i, update := range updates {
+				wantKind, wantErr := tt.wantErrs[update.MediaID]
+				if !wantErr {
+					if errs[i] != nil {
+						t.Errorf("update %d (media %d) failed: %v", i, update.MediaID, errs[i])
+					}
+					if entries[i] == nil || entries[i].Media.ID != update.MediaID {
+						t.Errorf("entry %d = %+v, want the entry of media %d", i, entries[i], update.MediaID)
+					}
+					continue
+				}
+				if entries[i] != nil {
+					t.Errorf("entry %d = %+v for a failed update, want nil", i, entries[i])
+				}
+				if errs[i] == nil || !strings.Contains(errs[i].Error(), fmt.Sprintf("mediaID: %d", update.MediaID)) {
+					t.Errorf("update %d error = %v, want an error for media %d", i, errs[i], update.MediaID)
+				}
+				if wantKind != nil && !errors.Is(errs[i], wantKind) {
+					t.Errorf("update %d error = %v, want %v", i, errs[i], wantKind)
+				}
+			}
+		})
+	}
+}
+
+// redirectTransport sends every request to a test server instead of AniList
+type redirectTransport struct {
+	serverURL string
+}
+
+func (t redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	target, err := url.Parse(t.serverURL)
+	if err != nil {
+		return nil, err
+	}
+	r = r.Clone(r.Context())
+	r.URL.Scheme = target.Scheme
+	r.URL.Host = target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
@@ -2,111 +2,522 @@ package internal
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/browser"
 )
 
 const (
-	configDir  = ".config/aniview"
-	configFile = "aniview.conf"
-	clientID   = "24933"
+	configDir      = "aniview"
+	configFile     = "aniview.conf"
+	clientID       = "24933"
+	defaultProfile = "default"
 )
 
-// EnsureConfigExists checks if config file exists and creates it if it doesn't
-func EnsureConfigExists() (*Config, error) {
+// LoadProfiles reads every profile and the shared settings from the config file, migrating
+// files written by older versions. A commented default file is written on first run.
+func LoadProfiles() (*Profiles, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return nil, err
 	}
+	if err := migrateConfigDir(configPath); err != nil {
+		return nil, err
+	}
 
 	// Create directory if it doesn't exist
 	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Check if config file exists
-	var config Config
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		// Config doesn't exist, create new one
-		token, err := getAniListToken()
-		if err != nil {
+	profiles := &Profiles{Version: ConfigVersion, Profiles: make(map[string]*Config)}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		if err := profiles.openCredentials(); err != nil {
+			return nil, err
+		}
+		if err := profiles.setSettings(DefaultSettings()); err != nil {
+			return nil, err
+		}
+		// Write the defaults so there is a file to edit
+		if err := profiles.writeConfig(configPath); err != nil {
 			return nil, err
 		}
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+
+	data = stripComments(data)
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, parseError(data, err))
+	}
+	version, err := configVersion(file)
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := migrateConfig(file)
+	if err != nil {
+		return nil, err
+	}
 
-		config = Config{
-			Token: token,
+	settings, err := decodeConfig(file, profiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	if err := profiles.openCredentials(); err != nil {
+		return nil, err
+	}
+	credentials, err := profiles.credentials.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var invalid []error
+	if err := settings.Validate(); err != nil {
+		invalid = append(invalid, err)
+	}
+	var rawProfiles map[string]json.RawMessage
+	if err := json.Unmarshal(file["profiles"], &rawProfiles); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: profiles: %w", configPath, err)
+	}
+	for name, raw := range rawProfiles {
+		if err := ValidateProfileName(name); err != nil {
+			invalid = append(invalid, fmt.Errorf("profiles: %w", err))
+			continue
+		}
+		// Settings missing from the profile keep their defaults
+		config := DefaultConfig()
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse profile %s: %w", name, err)
+		}
+		config.Profile = name
+		if err := validateProfile(name, config); err != nil {
+			invalid = append(invalid, err)
 		}
 
-		// Save the new config
-		if err := SaveConfig(&config); err != nil {
-			return nil, err
+		// Move a token saved in plaintext next to the preferences into the credential store
+		var legacy struct {
+			Token string `json:"token"`
+		}
+		json.Unmarshal(raw, &legacy)
+		if legacy.Token != "" {
+			config.SetToken(legacy.Token)
+			migrated = true
+		} else if stored, ok := credentials[name]; ok {
+			config.SetCredentials(stored)
 		}
 
-		// Create AniList client
-		client := NewAniListClient(config.Token)
+		profiles.Profiles[name] = &config
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("invalid settings in %s:\n%w", configPath, errors.Join(invalid...))
+	}
 
-		// Get user info to complete the config
-		if err := client.UpdateUserInfo(&config); err != nil {
+	if err := profiles.setSettings(settings); err != nil {
+		return nil, err
+	}
+
+	if migrated {
+		if err := profiles.Save(); err != nil {
+			return nil, fmt.Errorf("failed to migrate config file: %w", err)
+		}
+	}
+	// The journal is only moved once the config naming its profile is saved
+	if version == 0 {
+		if err := migrateJournal(configPath, defaultProfile); err != nil {
 			return nil, err
 		}
+	}
 
-		return &config, nil
+	return profiles, nil
+}
+
+// decodeConfig reads the top level of a migrated config file into profiles and returns the
+// shared settings. Settings missing from the file keep their defaults.
+func decodeConfig(file map[string]json.RawMessage, profiles *Profiles) (Settings, error) {
+	settings := DefaultSettings()
+	fields := map[string]interface{}{
+		"version":          &profiles.Version,
+		"default_profile":  &profiles.Default,
+		"credential_store": &profiles.CredentialStore,
+		"player":           &settings.Player,
+		"source":           &settings.Source,
+		"discord":          &settings.Discord,
+		"ui":               &settings.UI,
+		"profiles":         nil, // Read one by one
 	}
 
-	// Config exists, load it
-	file, err := os.Open(configPath)
+	for key, raw := range file {
+		field, ok := fields[key]
+		if !ok {
+			return settings, fmt.Errorf("unknown setting %q", key)
+		}
+		if field == nil {
+			continue
+		}
+		if err := decodeStrict(raw, field); err != nil {
+			return settings, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return settings, nil
+}
+
+// setSettings sets the shared settings read from the file and applies the environment overrides
+func (p *Profiles) setSettings(settings Settings) error {
+	p.saved = settings
+	p.Settings = settings
+	if err := p.Settings.applyEnv(os.LookupEnv); err != nil {
+		return fmt.Errorf("invalid environment override:\n%w", err)
+	}
+	if err := p.Settings.Validate(); err != nil {
+		return fmt.Errorf("invalid environment override:\n%w", err)
+	}
+	return nil
+}
+
+// configMigrations upgrade a config file from the version at their index to the next one
+var configMigrations = []func(file map[string]json.RawMessage) error{
+	migrateToProfiles,
+	migrateToSharedSettings,
+}
+
+// configVersion returns the version of a config file. Files from before the version was
+// recorded are version 0 without profiles and 1 with them.
+func configVersion(file map[string]json.RawMessage) (int, error) {
+	version := 0
+	if raw, ok := file["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return 0, fmt.Errorf("config file version isn't a number: %w", err)
+		}
+	} else if _, ok := file["profiles"]; ok {
+		version = 1
+	}
+	return version, nil
+}
+
+// migrateConfig upgrades a config file to ConfigVersion, telling whether it changed
+func migrateConfig(file map[string]json.RawMessage) (bool, error) {
+	version, err := configVersion(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open config file: %w", err)
+		return false, err
+	}
+	if version > ConfigVersion {
+		return false, fmt.Errorf("config file is version %d, which needs a newer aniview than this one (version %d)", version, ConfigVersion)
+	}
+	if version == ConfigVersion {
+		return false, nil
+	}
+
+	for ; version < ConfigVersion; version++ {
+		if err := configMigrations[version](file); err != nil {
+			return false, fmt.Errorf("failed to migrate config file from version %d: %w", version, err)
+		}
+	}
+	file["version"] = json.RawMessage(strconv.Itoa(ConfigVersion))
+	return true, nil
+}
+
+// migrateToProfiles turns a config file from before profiles existed into a profile named "default"
+func migrateToProfiles(file map[string]json.RawMessage) error {
+	profile, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	profiles, err := json.Marshal(map[string]json.RawMessage{defaultProfile: profile})
+	if err != nil {
+		return err
+	}
+
+	for key := range file {
+		delete(file, key)
+	}
+	file["default_profile"] = json.RawMessage(strconv.Quote(defaultProfile))
+	file["profiles"] = profiles
+	return nil
+}
+
+// migrateToSharedSettings moves the Discord settings out of the profiles, since presence is
+// shared by every profile. The default profile's settings are kept.
+func migrateToSharedSettings(file map[string]json.RawMessage) error {
+	var profiles map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(file["profiles"], &profiles); err != nil {
+		return err
+	}
+	var defaultName string
+	json.Unmarshal(file["default_profile"], &defaultName)
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	names = append([]string{defaultName}, names...)
+	for _, name := range names {
+		if discord, ok := profiles[name]["discord"]; ok {
+			file["discord"] = discord
+			break
+		}
+	}
+
+	for _, profile := range profiles {
+		delete(profile, "discord")
+	}
+	data, err := json.Marshal(profiles)
+	if err != nil {
+		return err
+	}
+	file["profiles"] = data
+	return nil
+}
+
+// openCredentials opens the credential store chosen in the config file
+func (p *Profiles) openCredentials() error {
+	store, kind, err := openCredentialStore(p.CredentialStore)
+	if err != nil {
+		return err
+	}
+	p.credentials = store
+	p.CredentialStore = kind
+	return nil
+}
+
+// EnsureProfile returns the named profile, creating it on the given tracker by logging in if it doesn't exist
+func EnsureProfile(profiles *Profiles, name string, tracker string) (*Config, error) {
+	if config, ok := profiles.Profiles[name]; ok {
+		return config, nil
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Creating profile %q on %s.\n", name, TrackerName(tracker))
+	config := DefaultConfig()
+	config.Profile = name
+	if tracker != TrackerAniList {
+		config.Tracker = tracker
+	}
+	if err := login(&config); err != nil {
+		return nil, err
+	}
+
+	profiles.Profiles[name] = &config
+	if profiles.Default == "" {
+		profiles.Default = name
 	}
-	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	// Save the new profile
+	if err := profiles.Save(); err != nil {
+		return nil, err
 	}
 
 	return &config, nil
 }
 
-// SaveConfig saves the configuration to disk
+// SaveConfig saves a profile's configuration to disk, keeping the other profiles
 func SaveConfig(config *Config) error {
-	configPath, err := getConfigPath()
+	profiles, err := LoadProfiles()
 	if err != nil {
 		return err
 	}
 
-	file, err := os.Create(configPath)
+	profiles.Profiles[config.Profile] = config
+	if profiles.Default == "" {
+		profiles.Default = config.Profile
+	}
+
+	return profiles.Save()
+}
+
+// Save writes every profile to the config file and their tokens to the credential store
+func (p *Profiles) Save() error {
+	configPath, err := getConfigPath()
 	if err != nil {
-		return fmt.Errorf("failed to create config file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(config); err != nil {
+	// Save the credentials first so a migrated token is never lost
+	credentials := make(map[string]Credentials, len(p.Profiles))
+	for name, config := range p.Profiles {
+		if config.Token != "" {
+			credentials[name] = config.Credentials()
+		}
+	}
+	if err := p.credentials.Save(credentials); err != nil {
+		// Tokens are never moved to a weaker store without the user choosing it
+		if p.CredentialStore == CredentialStoreKeyring {
+			return fmt.Errorf("%w, unlock the keyring or set credential_store to %s or %s in %s", err, CredentialStoreEncrypted, CredentialStoreFile, configPath)
+		}
+		return err
+	}
+
+	return p.writeConfig(configPath)
+}
+
+// writeConfig writes the config file with comments explaining the settings. The shared
+// settings are written as they were read, without the environment overrides.
+func (p *Profiles) writeConfig(configPath string) error {
+	file := *p
+	file.Version = ConfigVersion
+	file.Settings = p.saved
+
+	data, err := json.MarshalIndent(&file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := writeFileAtomic(configPath, append(commentConfig(data), '\n'), 0o600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	return nil
+}
 
+// writeFileAtomic writes a file through a temporary file so it is never left half written
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	// WriteFile keeps the permissions of an existing file
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
 	return nil
 }
 
-// getConfigPath returns the full path to the config file
+// migrateJournal renames the journal from before profiles existed to the journal of the given profile
+func migrateJournal(configPath string, profile string) error {
+	legacyPath := filepath.Join(filepath.Dir(configPath), "journal.json")
+	if _, err := os.Stat(legacyPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := os.Rename(legacyPath, journalPath(configPath, profile)); err != nil {
+		return fmt.Errorf("failed to migrate journal: %w", err)
+	}
+	return nil
+}
+
+// journalPath returns the path of a profile's journal next to the config file
+func journalPath(configPath string, profile string) string {
+	return filepath.Join(filepath.Dir(configPath), fmt.Sprintf("journal-%s.json", profile))
+}
+
+// getConfigPath returns the full path to the config file, in $XDG_CONFIG_HOME/aniview
+// or ~/.config/aniview when it isn't set
 func getConfigPath() (string, error) {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(configHome) {
+		return filepath.Join(configHome, configDir, configFile), nil
+	}
+	return legacyConfigPath()
+}
+
+// legacyConfigPath returns where the config file was kept before XDG_CONFIG_HOME was followed
+func legacyConfigPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
-	return filepath.Join(homeDir, configDir, configFile), nil
+	return filepath.Join(homeDir, ".config", configDir, configFile), nil
+}
+
+// migrateConfigDir moves the config directory from ~/.config to XDG_CONFIG_HOME when it is set
+// elsewhere and has no config yet. The journals and credentials move along with it.
+func migrateConfigDir(configPath string) error {
+	legacyPath, err := legacyConfigPath()
+	if err != nil || legacyPath == configPath {
+		return nil
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		return nil
+	}
+
+	// An empty directory is replaced, files already in one are never overwritten or mixed
+	// with the old ones
+	oldDir, newDir := filepath.Dir(legacyPath), filepath.Dir(configPath)
+	if err := os.Remove(newDir); err != nil && !os.IsNotExist(err) {
+		if entries, readErr := os.ReadDir(newDir); readErr == nil && len(entries) > 0 {
+			return fmt.Errorf("%s has files but no %s, move the config from %s into it by hand", newDir, configFile, oldDir)
+		}
+		return fmt.Errorf("failed to replace %s with the config from %s: %w", newDir, oldDir, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(newDir), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.Rename(oldDir, newDir); err != nil {
+		return fmt.Errorf("failed to move config from %s to %s, move it by hand: %w", oldDir, newDir, err)
+	}
+	return nil
+}
+
+// login authenticates with AniList and stores the token and the user it belongs to in the config
+func login(config *Config) error {
+	if config.Tracker == TrackerMyAnimeList {
+		return loginMAL(config)
+	}
+
+	token, err := getAniListToken()
+	if err != nil {
+		return err
+	}
+
+	// Check the token works before it is saved
+	return setAniListToken(config, token)
 }
 
-// getAniListToken opens the browser for authentication and gets the token from user input
+// loginMAL authenticates with MyAnimeList through the loopback redirect, which the code exchange needs
+func loginMAL(config *Config) error {
+	session, err := StartLogin(config)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	fmt.Println("Opening browser to authenticate with MyAnimeList...")
+	if err := browser.OpenURL(session.AuthURL); err != nil {
+		fmt.Printf("Failed to open browser automatically. Please open the following URL manually:\n%s\n", session.AuthURL)
+	}
+	fmt.Println("Waiting for MyAnimeList to redirect back...")
+
+	code, err := session.Wait(callbackTimeout)
+	if err != nil {
+		return err
+	}
+	return session.Complete(config, code)
+}
+
+// getAniListToken opens the browser for authentication and captures the token, falling back to manual paste
 func getAniListToken() (string, error) {
-	authURL := fmt.Sprintf("https://anilist.co/api/v2/oauth/authorize?client_id=%s&response_type=token", clientID)
+	token, err := captureToken()
+	if err == nil {
+		return token, nil
+	}
+
+	if !errors.Is(err, errPasteInstead) {
+		fmt.Printf("Automatic login failed: %v\n", err)
+		fmt.Println("Falling back to pasting the token manually.")
+	}
+	return pasteToken()
+}
+
+// pasteToken lets the user paste the token from the redirect URL into a text editor
+func pasteToken() (string, error) {
+	authURL := ManualAuthURL()
 	fmt.Println("Opening browser to authenticate with AniList...")
 	if err := browser.OpenURL(authURL); err != nil {
 		fmt.Printf("Failed to open browser automatically. Please open the following URL manually:\n%s\n", authURL)
@@ -123,7 +534,7 @@ func getAniListToken() (string, error) {
 	tempFile.Close()
 
 	fmt.Println("\nAfter authenticating, you'll be redirected to a page with the access token.")
-	fmt.Printf("A text editor will open. Please paste the token from the URL into the file and save it.\n")
+	fmt.Printf("A text editor will open. Please paste the token or the whole URL into the file and save it.\n")
 
 	// Try to use the user's preferred editor, falling back to nano and vim
 	if err := openEditorForToken(tempFilePath); err != nil {
@@ -139,12 +550,19 @@ func getAniListToken() (string, error) {
 	// Clean up the temporary file
 	os.Remove(tempFilePath)
 
-	token := strings.TrimSpace(string(tokenBytes))
-	if idx := strings.Index(token, "&"); idx > 0 {
+	return parsePastedToken(string(tokenBytes)), nil
+}
+
+// parsePastedToken extracts the access token from a pasted token or redirect URL
+func parsePastedToken(pasted string) string {
+	token := strings.TrimSpace(pasted)
+	if idx := strings.Index(token, "access_token="); idx >= 0 {
+		token = token[idx+len("access_token="):]
+	}
+	if idx := strings.Index(token, "&"); idx >= 0 {
 		token = token[:idx]
 	}
-
-	return token, nil
+	return token
 }
 
 // openEditorForToken tries various editors to let user input the token
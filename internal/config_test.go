@@ -0,0 +1,312 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no comments", `{"a": 1}`, `{"a": 1}`},
+		{"line comment", "// header\n{\"a\": 1}", "\n{\"a\": 1}"},
+		{"trailing comment", "{\n  \"a\": 1 // one\n}", "{\n  \"a\": 1 \n}"},
+		{"comment at end of file", `{"a": 1} // end`, `{"a": 1} `},
+		{"slashes in a string", `{"url": "https://anilist.co"}`, `{"url": "https://anilist.co"}`},
+		{"escaped quote in a string", `{"a": "say \"//hi\""} // c`, `{"a": "say \"//hi\""} `},
+		{"escaped backslash ends the string", `{"a": "\\"} // c`, `{"a": "\\"} `},
+		{"single slash", `{"a": "1/2"} /`, `{"a": "1/2"} /`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(stripComments([]byte(tt.in))); got != tt.want {
+				t.Errorf("stripComments(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripCommentsKeepsLines(t *testing.T) {
+	data := commentConfig([]byte("{\n  \"version\": 2,\n  \"player\": {\n    \"name\": \"mpv\"\n  }\n}"))
+	stripped := stripComments(data)
+	if strings.Count(string(stripped), "\n") != strings.Count(string(data), "\n") {
+		t.Errorf("stripComments changed the number of lines:\n%s", stripped)
+	}
+	if strings.Contains(string(stripped), "//") {
+		t.Errorf("stripComments left a comment:\n%s", stripped)
+	}
+	var file map[string]interface{}
+	if err := json.Unmarshal(stripped, &file); err != nil {
+		t.Errorf("commented config doesn't parse after stripping comments: %v", err)
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    string
+		changed bool
+		wantErr string
+	}{
+		{
+			name:    "before profiles",
+			file:    `{"token": "abc", "tracker": "anilist", "discord": {"enabled": false}}`,
+			want:    `{"version": 2, "default_profile": "default", "discord": {"enabled": false}, "profiles": {"default": {"token": "abc", "tracker": "anilist"}}}`,
+			changed: true,
+		},
+		{
+			name:    "discord in profiles",
+			file:    `{"default_profile": "b", "profiles": {"a": {"discord": {"enabled": true}}, "b": {"discord": {"enabled": false}}}}`,
+			want:    `{"version": 2, "default_profile": "b", "discord": {"enabled": false}, "profiles": {"a": {}, "b": {}}}`,
+			changed: true,
+		},
+		{
+			name:    "discord in a profile other than the default",
+			file:    `{"version": 1, "default_profile": "b", "profiles": {"a": {"discord": {"enabled": false}}, "b": {}}}`,
+			want:    `{"version": 2, "default_profile": "b", "discord": {"enabled": false}, "profiles": {"a": {}, "b": {}}}`,
+			changed: true,
+		},
+		{
+			name: "current version",
+			file: `{"version": 2, "profiles": {}}`,
+			want: `{"version": 2, "profiles": {}}`,
+		},
+		{
+			name:    "newer version",
+			file:    `{"version": 3, "profiles": {}}`,
+			wantErr: "needs a newer aniview",
+		},
+		{
+			name:    "version isn't a number",
+			file:    `{"version": "2", "profiles": {}}`,
+			wantErr: "isn't a number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var file map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.file), &file); err != nil {
+				t.Fatal(err)
+			}
+			changed, err := migrateConfig(file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("migrateConfig() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("migrateConfig() = %v", err)
+			}
+			if changed != tt.changed {
+				t.Errorf("migrateConfig() changed = %v, want %v", changed, tt.changed)
+			}
+
+			data, err := json.Marshal(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got, want interface{}
+			json.Unmarshal(data, &got)
+			json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("migrated file = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateToProfilesMovesJournal(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+	configPath, err := getConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, filepath.Dir(configPath), map[string]string{
+		configFile:     `{"tracker": "anilist"}`,
+		"journal.json": "[]",
+	})
+	legacyJournal := filepath.Join(filepath.Dir(configPath), "journal.json")
+
+	// Migrating the file alone leaves the disk alone
+	file := map[string]json.RawMessage{"tracker": json.RawMessage(`"anilist"`)}
+	if _, err := migrateConfig(file); err != nil {
+		t.Fatalf("migrateConfig() = %v", err)
+	}
+	if _, err := os.Stat(legacyJournal); err != nil {
+		t.Errorf("migrateConfig() moved the journal: %v", err)
+	}
+
+	if _, err := LoadProfiles(); err != nil {
+		t.Fatalf("LoadProfiles() = %v", err)
+	}
+	if _, err := os.Stat(journalPath(configPath, defaultProfile)); err != nil {
+		t.Errorf("journal wasn't moved to the default profile: %v", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]json.RawMessage
+	if err := json.Unmarshal(stripComments(data), &saved); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := configVersion(saved); err != nil || version != ConfigVersion {
+		t.Errorf("saved config is version %d, %v, want %d", version, err, ConfigVersion)
+	}
+}
+
+func TestMigrateConfigDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   map[string]string // Files already in the new config directory, nil when it doesn't exist
+		wantErr  bool
+		wantMove bool
+	}{
+		{name: "no new directory", wantMove: true},
+		{name: "empty new directory", target: map[string]string{}, wantMove: true},
+		{name: "new directory with other files", target: map[string]string{"journal-default.json": "[]"}, wantErr: true},
+		{name: "new directory with a config", target: map[string]string{configFile: "{}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+
+			legacyPath, err := legacyConfigPath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			configPath, err := getConfigPath()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			writeFiles(t, filepath.Dir(legacyPath), map[string]string{configFile: `{"version": 2}`, credentialsFile: "{}"})
+			if tt.target != nil {
+				writeFiles(t, filepath.Dir(configPath), tt.target)
+			}
+
+			err = migrateConfigDir(configPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("migrateConfigDir() = %v, want error %v", err, tt.wantErr)
+			}
+
+			moved := []string{configFile, credentialsFile}
+			for _, name := range moved {
+				_, newErr := os.Stat(filepath.Join(filepath.Dir(configPath), name))
+				_, oldErr := os.Stat(filepath.Join(filepath.Dir(legacyPath), name))
+				if tt.wantMove && (newErr != nil || oldErr == nil) {
+					t.Errorf("%s wasn't moved: new location %v, old location %v", name, newErr, oldErr)
+				}
+				if !tt.wantMove && oldErr != nil {
+					t.Errorf("%s was removed from the old location: %v", name, oldErr)
+				}
+			}
+			for name, content := range tt.target {
+				data, err := os.ReadFile(filepath.Join(filepath.Dir(configPath), name))
+				if err != nil || string(data) != content {
+					t.Errorf("%s in the new directory = %q, %v, want it untouched", name, data, err)
+				}
+			}
+		})
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(Settings) bool
+		wantErr string
+	}{
+		{
+			name:  "nothing set",
+			check: func(s Settings) bool { return reflect.DeepEqual(s, DefaultSettings()) },
+		},
+		{
+			name:  "string",
+			env:   map[string]string{"ANIVIEW_SOURCE_QUALITY": "720"},
+			check: func(s Settings) bool { return s.Source.Quality == "720" },
+		},
+		{
+			name:  "bool",
+			env:   map[string]string{"ANIVIEW_DISCORD_ENABLED": "false"},
+			check: func(s Settings) bool { return !s.Discord.Enabled },
+		},
+		{
+			name:  "string list",
+			env:   map[string]string{"ANIVIEW_PLAYER_ARGS": "--fs, --mute=yes,,"},
+			check: func(s Settings) bool { return slices.Equal(s.Player.Args, []string{"--fs", "--mute=yes"}) },
+		},
+		{
+			name:  "empty list",
+			env:   map[string]string{"ANIVIEW_DISCORD_BUTTONS": ""},
+			check: func(s Settings) bool { return s.Discord.Buttons != nil && len(s.Discord.Buttons) == 0 },
+		},
+		{
+			name:  "int list",
+			env:   map[string]string{"ANIVIEW_DISCORD_EXCLUDE_SHOWS": "21,1535"},
+			check: func(s Settings) bool { return slices.Equal(s.Discord.ExcludeShows, []int{21, 1535}) },
+		},
+		{
+			name:    "invalid bool",
+			env:     map[string]string{"ANIVIEW_DISCORD_ENABLED": "nope"},
+			wantErr: "ANIVIEW_DISCORD_ENABLED",
+		},
+		{
+			name:    "invalid int",
+			env:     map[string]string{"ANIVIEW_DISCORD_EXCLUDE_SHOWS": "21,one"},
+			wantErr: "ANIVIEW_DISCORD_EXCLUDE_SHOWS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := DefaultSettings()
+			err := settings.applyEnv(func(name string) (string, bool) {
+				value, ok := tt.env[name]
+				return value, ok
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyEnv() = %v, want an error naming %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyEnv() = %v", err)
+			}
+			if !tt.check(settings) {
+				t.Errorf("applyEnv() set %+v", settings)
+			}
+		})
+	}
+}
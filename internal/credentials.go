@@ -0,0 +1,349 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Credential stores that can be chosen with credential_store in the config file
+const (
+	CredentialStoreFile      = "file"      // Plaintext file readable only by the user
+	CredentialStoreEncrypted = "encrypted" // File encrypted with a passphrase
+	CredentialStoreKeyring   = "keyring"   // Secret Service on Linux, Keychain on macOS
+)
+
+const (
+	credentialsFile  = "credentials.json"
+	keyringService   = "aniview"
+	keyringAccount   = "credentials"
+	passphraseEnv    = "ANIVIEW_PASSPHRASE"
+	pbkdf2Iterations = 600000
+
+	securityItemNotFound = 44 // Exit status of security when the keychain has no such item
+)
+
+// Credentials holds the secrets of a profile, kept apart from its preferences
+type Credentials struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"`
+}
+
+// Credentials returns the secrets of a profile
+func (c *Config) Credentials() Credentials {
+	return Credentials{Token: c.Token, RefreshToken: c.RefreshToken, ExpiresAt: c.TokenExpiresAt}
+}
+
+// SetCredentials replaces the secrets of a profile
+func (c *Config) SetCredentials(credentials Credentials) {
+	c.Token = credentials.Token
+	c.RefreshToken = credentials.RefreshToken
+	c.TokenExpiresAt = credentials.ExpiresAt
+}
+
+// CredentialStore keeps the credentials of every profile, keyed by profile name
+type CredentialStore interface {
+	Load() (map[string]Credentials, error)
+	Save(credentials map[string]Credentials) error
+}
+
+var (
+	storeMu sync.Mutex
+	stores  = make(map[string]CredentialStore) // Opened stores, so a passphrase is only asked once
+)
+
+// openCredentialStore opens the named credential store. An empty name picks the keyring
+// when it is available and the plaintext file otherwise.
+func openCredentialStore(kind string) (CredentialStore, string, error) {
+	if kind == "" {
+		kind = CredentialStoreFile
+		if keyringAvailable() {
+			kind = CredentialStoreKeyring
+		}
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if store, ok := stores[kind]; ok {
+		return store, kind, nil
+	}
+
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(filepath.Dir(configPath), credentialsFile)
+
+	var store CredentialStore
+	switch kind {
+	case CredentialStoreFile:
+		store = &fileCredentials{path: path}
+	case CredentialStoreEncrypted:
+		_, statErr := os.Stat(path)
+		passphrase, err := readPassphrase(os.IsNotExist(statErr))
+		if err != nil {
+			return nil, "", err
+		}
+		store = &encryptedCredentials{path: path, passphrase: passphrase}
+	case CredentialStoreKeyring:
+		if !keyringAvailable() {
+			return nil, "", fmt.Errorf("no keyring is available on this system")
+		}
+		store = keyringCredentials{}
+	default:
+		return nil, "", fmt.Errorf("unknown credential store %q", kind)
+	}
+
+	stores[kind] = store
+	return store, kind, nil
+}
+
+// fileCredentials stores credentials in a plaintext file readable only by the user
+type fileCredentials struct {
+	path string
+}
+
+func (f *fileCredentials) Load() (map[string]Credentials, error) {
+	credentials := make(map[string]Credentials)
+
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return credentials, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+func (f *fileCredentials) Save(credentials map[string]Credentials) error {
+	data, err := json.MarshalIndent(credentials, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	return writeFileAtomic(f.path, data, 0o600)
+}
+
+// encryptedCredentials stores credentials in a file encrypted with AES-GCM, using a key derived from a passphrase
+type encryptedCredentials struct {
+	path       string
+	passphrase []byte
+}
+
+// encryptedFile is the layout of the encrypted credentials file
+type encryptedFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+func (e *encryptedCredentials) Load() (map[string]Credentials, error) {
+	credentials := make(map[string]Credentials)
+
+	data, err := os.ReadFile(e.path)
+	if os.IsNotExist(err) {
+		return credentials, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Data == nil {
+		return nil, fmt.Errorf("credentials file is not encrypted, remove it or change credential_store")
+	}
+
+	gcm, err := newCredentialsCipher(e.passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials, is the passphrase correct?")
+	}
+
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+func (e *encryptedCredentials) Save(credentials map[string]Credentials) error {
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	file := encryptedFile{Salt: make([]byte, 16)}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := newCredentialsCipher(e.passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Data = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	return writeFileAtomic(e.path, data, 0o600)
+}
+
+// newCredentialsCipher derives the AES-256 key from the passphrase and salt
+func newCredentialsCipher(passphrase []byte, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2.Key(passphrase, salt, pbkdf2Iterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
+
+// readPassphrase reads the passphrase from the environment or asks for it on the terminal
+func readPassphrase(confirm bool) ([]byte, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return nil, fmt.Errorf("set %s to unlock the encrypted credentials", passphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase for aniview credentials: ")
+	passphrase, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase can't be empty")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		repeated, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if !bytes.Equal(passphrase, repeated) {
+			return nil, fmt.Errorf("passphrases don't match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+// keyringCredentials stores credentials in the system keyring through its command line tool
+type keyringCredentials struct{}
+
+// keyringAvailable reports whether the system keyring can be used
+func keyringAvailable() bool {
+	switch runtime.GOOS {
+	case "darwin":
+		_, err := exec.LookPath("security")
+		return err == nil
+	case "linux":
+		// Secret Service runs on the session bus
+		if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return false
+		}
+		_, err := exec.LookPath("secret-tool")
+		return err == nil
+	}
+	return false
+}
+
+func (keyringCredentials) Load() (map[string]Credentials, error) {
+	credentials := make(map[string]Credentials)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", keyringAccount)
+	}
+
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if keyringItemMissing(exitErr) {
+			return credentials, nil
+		}
+		// A locked or unavailable keyring must not look empty, saving would then drop the other profiles' tokens
+		return nil, fmt.Errorf("failed to read credentials from keyring: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials from keyring: %w", err)
+	}
+	secret := strings.TrimSpace(string(output))
+	if secret == "" {
+		return credentials, nil
+	}
+
+	if err := json.Unmarshal([]byte(secret), &credentials); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials from keyring: %w", err)
+	}
+	return credentials, nil
+}
+
+// keyringItemMissing reports whether reading the keyring failed only because nothing is stored
+// yet. security exits with errSecItemNotFound, secret-tool exits with 1 without a message.
+func keyringItemMissing(err *exec.ExitError) bool {
+	if runtime.GOOS == "darwin" {
+		return err.ExitCode() == securityItemNotFound
+	}
+	return err.ExitCode() == 1 && len(bytes.TrimSpace(err.Stderr)) == 0
+}
+
+func (keyringCredentials) Save(credentials map[string]Credentials) error {
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	// The secret goes through stdin, arguments can be read by other users with ps
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		// security reads commands from stdin in interactive mode, hex keeps the JSON from needing quotes
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", keyringService, keyringAccount, hex.EncodeToString(data)))
+	} else {
+		cmd = exec.Command("secret-tool", "store", "--label=aniview credentials", "service", keyringService, "account", keyringAccount)
+		cmd.Stdin = bytes.NewReader(data)
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to save credentials to keyring: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCredentialStoreRoundTrip(t *testing.T) {
+	credentials := map[string]Credentials{
+		"default": {Token: "anilist-token"},
+		"mal":     {Token: "mal-token", RefreshToken: "refresh", ExpiresAt: 1700000000},
+	}
+
+	tests := []struct {
+		name  string
+		store func(path string) CredentialStore
+	}{
+		{"file", func(path string) CredentialStore { return &fileCredentials{path: path} }},
+		{"encrypted", func(path string) CredentialStore {
+			return &encryptedCredentials{path: path, passphrase: []byte("correct horse")}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), credentialsFile)
+
+			empty, err := tt.store(path).Load()
+			if err != nil || len(empty) != 0 {
+				t.Fatalf("Load() before saving = %v, %v, want no credentials", empty, err)
+			}
+
+			if err := tt.store(path).Save(credentials); err != nil {
+				t.Fatalf("Save() = %v", err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := info.Mode().Perm(); perm != 0o600 {
+				t.Errorf("credentials file has mode %o, want 600", perm)
+			}
+
+			loaded, err := tt.store(path).Load()
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if !reflect.DeepEqual(loaded, credentials) {
+				t.Errorf("Load() = %+v, want %+v", loaded, credentials)
+			}
+		})
+	}
+}
+
+func TestEncryptedCredentialsRejects(t *testing.T) {
+	credentials := map[string]Credentials{"default": {Token: "secret-token"}}
+
+	tests := []struct {
+		name       string
+		save       func(path string) error
+		passphrase string
+	}{
+		{
+			name: "wrong passphrase",
+			save: func(path string) error {
+				return (&encryptedCredentials{path: path, passphrase: []byte("right")}).Save(credentials)
+			},
+			passphrase: "wrong",
+		},
+		{
+			name: "plaintext file",
+			save: func(path string) error {
+				return (&fileCredentials{path: path}).Save(credentials)
+			},
+			passphrase: "right",
+		},
+		{
+			name: "tampered file",
+			save: func(path string) error {
+				if err := (&encryptedCredentials{path: path, passphrase: []byte("right")}).Save(credentials); err != nil {
+					return err
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				var file encryptedFile
+				if err := json.Unmarshal(data, &file); err != nil {
+					return err
+				}
+				file.Data[0] ^= 1
+				if data, err = json.Marshal(file); err != nil {
+					return err
+				}
+				return os.WriteFile(path, data, 0o600)
+			},
+			passphrase: "right",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), credentialsFile)
+			if err := tt.save(path); err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := (&encryptedCredentials{path: path, passphrase: []byte(tt.passphrase)}).Load()
+			if err == nil {
+				t.Errorf("Load() = %+v, want an error", loaded)
+			}
+		})
+	}
+}
+
+func TestEncryptedCredentialsUseNewSalt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), credentialsFile)
+	store := &encryptedCredentials{path: path, passphrase: []byte("passphrase")}
+	credentials := map[string]Credentials{"default": {Token: "token"}}
+
+	var files [2][]byte
+	for i := range files {
+		if err := store.Save(credentials); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[i] = data
+	}
+	if string(files[0]) == string(files[1]) {
+		t.Error("saving the same credentials twice wrote the same file, want a new salt and nonce")
+	}
+}
+
+func TestKeyringItemMissing(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("secret-tool exit statuses are only checked on Linux")
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		want   bool
+	}{
+		{"no item", "exit 1", true},
+		{"locked keyring", "echo 'Cannot create an item in a locked collection' >&2; exit 1", false},
+		{"other status", "exit 2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := exec.Command("sh", "-c", tt.script).Output()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("command error = %v, want an exit error", err)
+			}
+			if got := keyringItemMissing(exitErr); got != tt.want {
+				t.Errorf("keyringItemMissing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,77 +2,246 @@ package internal
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+)
 
-	"github.com/daannte/rich-go/client"
+const (
+	defaultDiscordClientID = "1285024019447287921"
+	presenceRetry          = 15 * time.Second // Wait before reconnecting when Discord isn't running
+	presenceCheck          = 30 * time.Second // Time between checks that the connection is still open
 )
 
-func DiscordPresence(clientId string, anime AnimeEntry) error {
-	err := client.Login(clientId)
-	if err != nil {
-		return err
+// Presence shows the episode being watched on Discord. It connects once, only sends an
+// update when playback changes and reconnects when Discord restarts, which it notices
+// by pinging Discord while an activity is shown.
+type Presence struct {
+	mu      sync.Mutex
+	config  DiscordConfig
+	desired presenceUpdate
+
+	notify chan struct{}
+	done   chan struct{}
+	closed chan struct{}
+}
+
+// presenceUpdate is an activity along with the Discord application to show it as
+type presenceUpdate struct {
+	clientID string
+	activity *discordActivity // nil to show none
+}
+
+// NewPresence starts the presence service, which shows nothing until it is configured
+func NewPresence() *Presence {
+	p := &Presence{
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
 	}
+	go p.run()
+	return p
+}
 
-	timePos, err := getCurrentPlaybackTime("/tmp/iinasocket")
-	if err != nil {
-		return err
+// Configure sets the templates and privacy settings used from the next update on
+func (p *Presence) Configure(config DiscordConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+}
+
+// Playing shows the episode being played, unless the settings leave it out
+func (p *Presence) Playing(anime AnimeEntry, state PlaybackState) {
+	p.mu.Lock()
+	config := p.config
+	p.mu.Unlock()
+
+	if !config.Shows(anime) {
+		p.set(presenceUpdate{})
+		return
 	}
+	clientID := config.ClientID
+	if clientID == "" {
+		clientID = defaultDiscordClientID
+	}
+	p.set(presenceUpdate{clientID: clientID, activity: presenceActivity(config, anime, state)})
+}
 
-	isPaused, err := getPausedState("/tmp/iinasocket")
+// Stopped clears the activity while browsing
+func (p *Presence) Stopped() {
+	p.set(presenceUpdate{})
+}
+
+// Close clears the activity and disconnects from Discord
+func (p *Presence) Close() {
+	close(p.done)
+	<-p.closed
+}
+
+// set replaces the update to send. Only the latest one is sent when several arrive
+// while Discord is busy.
+func (p *Presence) set(update presenceUpdate) {
+	p.mu.Lock()
+	p.desired = update
+	p.mu.Unlock()
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run sends activity changes to Discord until the service is closed
+func (p *Presence) run() {
+	defer close(p.closed)
+
+	var conn *discordConn
+	var retry, check <-chan time.Time
+	for {
+		select {
+		case <-p.done:
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		case <-p.notify:
+		case <-retry:
+		case <-check:
+			// A lost connection only shows when talking to Discord, so the activity
+			// is sent again on a new one when the ping fails
+			if err := conn.ping(); err == nil {
+				check = time.After(presenceCheck)
+				continue
+			}
+			conn.Close()
+			conn = nil
+		}
+		retry, check = nil, nil
+
+		p.mu.Lock()
+		update := p.desired
+		p.mu.Unlock()
+
+		var err error
+		conn, err = p.apply(conn, update)
+		if err != nil && update.activity != nil {
+			retry = time.After(presenceRetry)
+		}
+		if conn != nil && update.activity != nil {
+			check = time.After(presenceCheck)
+		}
+	}
+}
+
+// apply shows the activity, connecting first when needed. A failed update is retried
+// once on a new connection, since Discord may have restarted.
+func (p *Presence) apply(conn *discordConn, update presenceUpdate) (*discordConn, error) {
+	if conn != nil && update.activity != nil && conn.clientID != update.clientID {
+		// The application changed, a closed connection clears the old one's activity
+		conn.Close()
+		conn = nil
+	}
+	if conn != nil {
+		err := conn.setActivity(update.activity)
+		if err == nil {
+			return conn, nil
+		}
+		conn.Close()
+	}
+	if update.activity == nil {
+		// A closed connection shows no activity
+		return nil, nil
+	}
+
+	conn, err := dialDiscord(update.clientID)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if err := conn.setActivity(update.activity); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
 
-	if anime.EpisodeDuration == 0 {
-		dur, err := getVideoDuration("/tmp/iinasocket")
-		if err != nil {
-			return err
+// Shows tells whether the presence may show an anime
+func (c DiscordConfig) Shows(anime AnimeEntry) bool {
+	if !c.Enabled || (c.ExcludePrivate && anime.Private) {
+		return false
+	}
+	for _, status := range c.ExcludeStatuses {
+		if strings.EqualFold(status, anime.Status) {
+			return false
 		}
-		anime.EpisodeDuration = int(dur)
-	}
-
-	var state string
-	var startTime *time.Time
-	var endTime *time.Time
-
-	if isPaused {
-		state = fmt.Sprintf("\nEpisode %d (Paused)", anime.CurrentEpisode)
-		startTime = nil
-		endTime = nil
-	} else {
-		state = fmt.Sprintf("\nEpisode %d", anime.CurrentEpisode)
-		now := time.Now()
-		calculatedStartTime := now.Add(-time.Duration(timePos) * time.Second)
-		calculatedEndTime := calculatedStartTime.Add(time.Duration(anime.EpisodeDuration) * time.Second)
-		startTime = &calculatedStartTime
-		endTime = &calculatedEndTime
-	}
-
-	err = client.SetActivity(client.Activity{
-		Type:       3,
-		Details:    anime.Title,
-		LargeImage: anime.CoverImage,
-		LargeText:  anime.Title,
-		State:      state,
-		Timestamps: &client.Timestamps{
-			Start: startTime,
-			End:   endTime,
+	}
+	for _, id := range c.ExcludeShows {
+		if id == anime.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// presenceActivity describes the episode being played using the configured templates
+func presenceActivity(config DiscordConfig, anime AnimeEntry, state PlaybackState) *discordActivity {
+	episodes := "?"
+	if anime.Episodes > 0 {
+		episodes = strconv.Itoa(anime.Episodes)
+	}
+	fields := strings.NewReplacer(
+		"{title}", anime.Title,
+		"{episode}", strconv.Itoa(anime.CurrentEpisode),
+		"{episodes}", episodes,
+		"{status}", strings.ToLower(anime.Status),
+	)
+
+	activity := &discordActivity{
+		Type:    3, // Watching
+		Details: fields.Replace(config.Details),
+		Assets: &discordAssets{
+			LargeImage: anime.CoverImage,
+			LargeText:  fields.Replace(config.ImageText),
 		},
-		Buttons: []*client.Button{
-			{
+		Buttons: presenceButtons(config.Buttons, anime),
+	}
+
+	if state.Paused {
+		activity.State = fields.Replace(config.PausedState)
+		return activity
+	}
+	activity.State = fields.Replace(config.State)
+
+	// Discord counts the time from the timestamps, so nothing is sent while playing
+	start := time.Now().Add(-state.Position)
+	activity.Timestamps = &discordTimestamps{Start: start.UnixMilli()}
+	if state.Duration > 0 {
+		activity.Timestamps.End = start.Add(state.Duration).UnixMilli()
+	}
+	return activity
+}
+
+// presenceButtons returns the chosen buttons, leaving out links to sites the anime isn't on
+func presenceButtons(names []string, anime AnimeEntry) []discordButton {
+	var buttons []discordButton
+	for _, name := range names {
+		switch {
+		case name == ButtonAniList && anime.ID != 0:
+			buttons = append(buttons, discordButton{
 				Label: "View on AniList",
-				Url:   fmt.Sprintf("https://anilist.co/anime/%d", anime.ID),
-			},
-			{
+				URL:   fmt.Sprintf("https://anilist.co/anime/%d", anime.ID),
+			})
+		case name == ButtonMyAnimeList && anime.MalId != 0:
+			buttons = append(buttons, discordButton{
 				Label: "View on MAL",
-				Url:   fmt.Sprintf("https://myanimelist.net/anime/%d", anime.MalId),
-			},
-		},
-	})
-	if err != nil {
-		return err
+				URL:   fmt.Sprintf("https://myanimelist.net/anime/%d", anime.MalId),
+			})
+		}
+	}
+	if len(buttons) > 2 {
+		buttons = buttons[:2]
 	}
-	return nil
+	return buttons
 }
 
 func FormatTime(seconds int) string {
@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Discord IPC opcodes
+const (
+	discordOpHandshake = 0
+	discordOpFrame     = 1
+	discordOpClose     = 2
+	discordOpPing      = 3
+)
+
+const discordIOTimeout = 5 * time.Second
+
+// discordConn is a connection to the Discord client's IPC socket
+type discordConn struct {
+	conn     net.Conn
+	clientID string // Application the connection identified as
+}
+
+// discordActivity is the activity shown on a Discord profile
+type discordActivity struct {
+	Type       int                `json:"type"`
+	Details    string             `json:"details,omitempty"`
+	State      string             `json:"state,omitempty"`
+	Assets     *discordAssets     `json:"assets,omitempty"`
+	Timestamps *discordTimestamps `json:"timestamps,omitempty"`
+	Buttons    []discordButton    `json:"buttons,omitempty"`
+}
+
+type discordAssets struct {
+	LargeImage string `json:"large_image,omitempty"`
+	LargeText  string `json:"large_text,omitempty"`
+}
+
+// discordTimestamps are Unix times in milliseconds
+type discordTimestamps struct {
+	Start int64 `json:"start,omitempty"`
+	End   int64 `json:"end,omitempty"`
+}
+
+type discordButton struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// discordResponse is a message from Discord
+type discordResponse struct {
+	Cmd  string `json:"cmd"`
+	Evt  string `json:"evt"`
+	Data struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"data"`
+	// Set instead of Data when Discord closes the connection
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// discordSocketPaths returns the places the Discord IPC socket may be, including the
+// Flatpak and Snap sandboxes
+func discordSocketPaths() []string {
+	var dirs []string
+	for _, name := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if dir := os.Getenv(name); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, "/tmp")
+
+	var paths []string
+	for _, dir := range dirs {
+		for _, sub := range []string{"", "app/com.discordapp.Discord", ".flatpak/com.discordapp.Discord/xdg-run", "snap.discord"} {
+			for i := 0; i < 10; i++ {
+				paths = append(paths, filepath.Join(dir, sub, fmt.Sprintf("discord-ipc-%d", i)))
+			}
+		}
+	}
+	return paths
+}
+
+// dialDiscord connects to the running Discord client and identifies as the given application
+func dialDiscord(clientID string) (*discordConn, error) {
+	var conn net.Conn
+	var err error
+	for _, path := range discordSocketPaths() {
+		conn, err = net.DialTimeout("unix", path, discordIOTimeout)
+		if err == nil {
+			break
+		}
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("discord isn't running: %w", err)
+	}
+
+	d := &discordConn{conn: conn, clientID: clientID}
+	response, err := d.send(discordOpHandshake, map[string]interface{}{
+		"v":         1,
+		"client_id": clientID,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("discord handshake failed: %w", err)
+	}
+	if response.Evt != "READY" {
+		conn.Close()
+		return nil, fmt.Errorf("discord handshake failed: %s", response.Message)
+	}
+	return d, nil
+}
+
+// setActivity shows the activity, or clears it when activity is nil
+func (d *discordConn) setActivity(activity *discordActivity) error {
+	args := map[string]interface{}{"pid": os.Getpid()}
+	if activity != nil {
+		args["activity"] = activity
+	}
+
+	response, err := d.send(discordOpFrame, map[string]interface{}{
+		"cmd":   "SET_ACTIVITY",
+		"args":  args,
+		"nonce": nonce(),
+	})
+	if err != nil {
+		return err
+	}
+	if response.Evt == "ERROR" {
+		return fmt.Errorf("discord rejected the activity: %s", response.Data.Message)
+	}
+	return nil
+}
+
+// ping checks that Discord is still reading from the connection
+func (d *discordConn) ping() error {
+	_, err := d.send(discordOpPing, map[string]interface{}{"nonce": nonce()})
+	return err
+}
+
+// send writes a message and reads Discord's response
+func (d *discordConn) send(opcode uint32, payload interface{}) (discordResponse, error) {
+	var response discordResponse
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return response, err
+	}
+	frame := make([]byte, 8, 8+len(data))
+	binary.LittleEndian.PutUint32(frame[0:4], opcode)
+	binary.LittleEndian.PutUint32(frame[4:8], uint32(len(data)))
+	frame = append(frame, data...)
+
+	d.conn.SetDeadline(time.Now().Add(discordIOTimeout))
+	if _, err := d.conn.Write(frame); err != nil {
+		return response, err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(d.conn, header); err != nil {
+		return response, err
+	}
+	body := make([]byte, binary.LittleEndian.Uint32(header[4:8]))
+	if _, err := io.ReadFull(d.conn, body); err != nil {
+		return response, err
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return response, fmt.Errorf("failed to parse discord response: %w", err)
+	}
+
+	if binary.LittleEndian.Uint32(header[0:4]) == discordOpClose {
+		return response, errors.New("discord closed the connection: " + response.Message)
+	}
+	return response, nil
+}
+
+// Close closes the connection, which also clears the activity
+func (d *discordConn) Close() error {
+	return d.conn.Close()
+}
+
+// nonce returns a random ID for matching responses to requests
+func nonce() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:])
+}
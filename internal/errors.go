@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrQueued is returned when a list change could not reach AniList and was queued to be sent later
+var ErrQueued = errors.New("change queued until AniList is reachable")
+
+// RequestError is returned when AniList could not be reached or answered with an error status
+type RequestError struct {
+	StatusCode int // 0 when no response was received
+	Body       string
+	Err        error
+	RetryAfter time.Duration // How long the server asked to wait before retrying, 0 when it didn't say
+}
+
+func (e *RequestError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("failed to send request: %v", e.Err)
+	}
+	if e.Err != nil {
+		// The server explained the error
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("request failed with status code %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether the request may succeed when retried later
+func (e *RequestError) Temporary() bool {
+	return e.StatusCode == 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500 || errors.Is(e.Err, ErrRateLimited)
+}
+
+// IsTemporary reports whether err is a request error that may succeed when retried later
+func IsTemporary(err error) bool {
+	var requestErr *RequestError
+	return errors.As(err, &requestErr) && requestErr.Temporary()
+}
+
+// RetryAfter returns how long the server asked to wait before retrying a rate limited request,
+// or fallback when it didn't say
+func RetryAfter(err error, fallback time.Duration) time.Duration {
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) && requestErr.RetryAfter > 0 {
+		return requestErr.RetryAfter
+	}
+	return fallback
+}
+
+// IsRateLimited reports whether err is a request error caused by sending too many requests
+func IsRateLimited(err error) bool {
+	var requestErr *RequestError
+	return errors.Is(err, ErrRateLimited) || (errors.As(err, &requestErr) && requestErr.StatusCode == http.StatusTooManyRequests)
+}
+
+// AuthError is returned when the tracker rejects the token, usually because it expired
+type AuthError struct {
+	*RequestError
+}
+
+func (e *AuthError) Error() string {
+	return "login expired or was revoked, log in again"
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.RequestError
+}
+
+// IsAuthError reports whether err was caused by the tracker rejecting the token
+func IsAuthError(err error) bool {
+	var authErr *AuthError
+	return errors.As(err, &authErr)
+}
+
+// IsRejected reports whether the tracker refused a change outright, so sending it again cannot succeed
+func IsRejected(err error) bool {
+	return errors.Is(err, ErrValidation) || errors.Is(err, ErrNotFound)
+}
+
+// DroppedError is returned for a queued list change the tracker refused, which was removed from the queue
+type DroppedError struct {
+	Update ListEntryUpdate
+	Err    error
+}
+
+func (e *DroppedError) Error() string {
+	return fmt.Sprintf("dropped queued change to anime %d: %v", e.Update.MediaID, e.Err)
+}
+
+func (e *DroppedError) Unwrap() error {
+	return e.Err
+}
+
+// Kinds of errors AniList reports in GraphQL responses, matched with errors.Is
+var (
+	ErrNotFound     = errors.New("not found on AniList")
+	ErrValidation   = errors.New("rejected by AniList")
+	ErrRateLimited  = errors.New("too many requests to AniList")
+	ErrUnauthorized = errors.New("not authorized by AniList")
+)
+
+// APIError is an error AniList reported in the errors array of a GraphQL response
+type APIError struct {
+	Kind       error               // One of the error kinds above, nil when AniList gave no known reason
+	Message    string              // Message from AniList
+	Status     int                 // Status AniList gave the error, which may differ from the HTTP status
+	Path       []string            // Field of the response the error belongs to, e.g. the alias of a mutation
+	Validation map[string][]string // Messages per rejected argument
+}
+
+func (e *APIError) Error() string {
+	if len(e.Validation) > 0 {
+		fields := make([]string, 0, len(e.Validation))
+		for field := range e.Validation {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		messages := make([]string, len(fields))
+		for i, field := range fields {
+			messages[i] = fmt.Sprintf("%s: %s", field, strings.Join(e.Validation[field], " "))
+		}
+		return "invalid " + strings.Join(messages, ", ")
+	}
+	return strings.TrimSuffix(e.Message, ".")
+}
+
+// Is lets errors.Is match an API error against its kind
+func (e *APIError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// APIErrors are all the errors of a GraphQL response
+type APIErrors []*APIError
+
+func (e APIErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e APIErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ForPath returns the error reported for a field of the response, if any
+func (e APIErrors) ForPath(field string) *APIError {
+	for _, err := range e {
+		if len(err.Path) > 0 && err.Path[0] == field {
+			return err
+		}
+	}
+	return nil
+}
+
+// retryAfter reads the Retry-After header of a rate limited response
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// graphQLError is an entry of the errors array in an AniList response
+type graphQLError struct {
+	Message    string              `json:"message"`
+	Status     int                 `json:"status"`
+	Path       []interface{}       `json:"path"`
+	Validation map[string][]string `json:"validation"`
+}
+
+// parseAPIErrors reads the errors array of a GraphQL response, returning nil when there is none
+func parseAPIErrors(body []byte, httpStatus int) APIErrors {
+	var envelope struct {
+		Errors []graphQLError `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || len(envelope.Errors) == 0 {
+		return nil
+	}
+
+	errs := make(APIErrors, len(envelope.Errors))
+	for i, e := range envelope.Errors {
+		status := e.Status
+		if status == 0 {
+			status = httpStatus
+		}
+
+		apiErr := &APIError{Message: e.Message, Status: status, Validation: e.Validation}
+		for _, part := range e.Path {
+			apiErr.Path = append(apiErr.Path, fmt.Sprint(part))
+		}
+
+		message := strings.ToLower(e.Message)
+		switch {
+		case status == http.StatusUnauthorized || strings.Contains(message, "invalid token") || strings.HasPrefix(message, "unauthorized"):
+			apiErr.Kind = ErrUnauthorized
+		case status == http.StatusTooManyRequests:
+			apiErr.Kind = ErrRateLimited
+		case status == http.StatusNotFound:
+			apiErr.Kind = ErrNotFound
+		case len(e.Validation) > 0 || message == "validation" || status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+			apiErr.Kind = ErrValidation
+		}
+		errs[i] = apiErr
+	}
+	return errs
+}
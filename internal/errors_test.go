@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestParseAPIErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		httpStatus int
+		want       []APIError // Expected errors, comparing Kind, Status, Path and Message
+		wantText   string
+	}{
+		{
+			name:       "no errors",
+			body:       `{"data": {"Viewer": {"id": 1}}}`,
+			httpStatus: http.StatusOK,
+		},
+		{
+			name:       "not JSON",
+			body:       `<html>Bad Gateway</html>`,
+			httpStatus: http.StatusBadGateway,
+		},
+		{
+			name:       "not found",
+			body:       `{"errors": [{"message": "Not Found.", "status": 404, "locations": [{"line": 1, "column": 2}]}], "data": {"Media": null}}`,
+			httpStatus: http.StatusNotFound,
+			want:       []APIError{{Kind: ErrNotFound, Status: 404, Message: "Not Found."}},
+			wantText:   "Not Found",
+		},
+		{
+			name:       "invalid token",
+			body:       `{"errors": [{"message": "Invalid token", "status": 400}]}`,
+			httpStatus: http.StatusBadRequest,
+			want:       []APIError{{Kind: ErrUnauthorized, Status: 400, Message: "Invalid token"}},
+			wantText:   "Invalid token",
+		},
+		{
+			name:       "unauthorized",
+			body:       `{"errors": [{"message": "Unauthorized.", "status": 401}]}`,
+			httpStatus: http.StatusUnauthorized,
+			want:       []APIError{{Kind: ErrUnauthorized, Status: 401, Message: "Unauthorized."}},
+		},
+		{
+			name:       "rate limited",
+			body:       `{"errors": [{"message": "Too Many Requests.", "status": 429}]}`,
+			httpStatus: http.StatusTooManyRequests,
+			want:       []APIError{{Kind: ErrRateLimited, Status: 429, Message: "Too Many Requests."}},
+		},
+		{
+			name:       "status taken from the response",
+			body:       `{"errors": [{"message": "Too Many Requests."}]}`,
+			httpStatus: http.StatusTooManyRequests,
+			want:       []APIError{{Kind: ErrRateLimited, Status: 429, Message: "Too Many Requests."}},
+		},
+		{
+			name:       "validation",
+			body:       `{"errors": [{"message": "validation", "status": 400, "validation": {"score": ["The score must be between 0 and 100."], "progress": ["The progress must be an integer."]}}]}`,
+			httpStatus: http.StatusBadRequest,
+			want:       []APIError{{Kind: ErrValidation, Status: 400, Message: "validation"}},
+			wantText:   "invalid progress: The progress must be an integer., score: The score must be between 0 and 100.",
+		},
+		{
+			name:       "unknown error",
+			body:       `{"errors": [{"message": "Internal Server Error", "status": 500}]}`,
+			httpStatus: http.StatusInternalServerError,
+			want:       []APIError{{Status: 500, Message: "Internal Server Error"}},
+		},
+		{
+			name: "errors of batched mutations",
+			body: `{"errors": [
+				{"message": "Not Found.", "status": 404, "path": ["m1"]},
+				{"message": "validation", "status": 400, "path": ["m2", "progress"], "validation": {"progress": ["Too high."]}}
+			], "data": {"m0": {"id": 1}, "m1": null, "m2": null}}`,
+			httpStatus: http.StatusOK,
+			want: []APIError{
+				{Kind: ErrNotFound, Status: 404, Path: []string{"m1"}, Message: "Not Found."},
+				{Kind: ErrValidation, Status: 400, Path: []string{"m2", "progress"}, Message: "validation"},
+			},
+			wantText: "Not Found; invalid progress: Too high.",
+		},
+		{
+			name:       "numeric path",
+			body:       `{"errors": [{"message": "Not Found.", "status": 404, "path": ["Page", "media", 3]}]}`,
+			httpStatus: http.StatusOK,
+			want:       []APIError{{Kind: ErrNotFound, Status: 404, Path: []string{"Page", "media", "3"}, Message: "Not Found."}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := parseAPIErrors([]byte(tt.body), tt.httpStatus)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("parseAPIErrors() = %v, want %d error(s)", errs, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := errs[i]
+				if got.Kind != want.Kind || got.Status != want.Status || got.Message != want.Message || !slices.Equal(got.Path, want.Path) {
+					t.Errorf("error %d = %+v, want %+v", i, *got, want)
+				}
+				if want.Kind != nil && !errors.Is(got, want.Kind) {
+					t.Errorf("errors.Is(%v, %v) = false, want true", got, want.Kind)
+				}
+			}
+			if tt.wantText != "" && errs.Error() != tt.wantText {
+				t.Errorf("Error() = %q, want %q", errs.Error(), tt.wantText)
+			}
+		})
+	}
+}
+
+func TestAPIErrorsMatchKinds(t *testing.T) {
+	errs := parseAPIErrors([]byte(`{"errors": [
+		{"message": "Not Found.", "status": 404, "path": ["m1"]},
+		{"message": "validation", "status": 400, "path": ["m2"]}
+	]}`), http.StatusOK)
+
+	tests := []struct {
+		name string
+		err  error
+		kind error
+		want bool
+	}{
+		{"joined errors match the first kind", errs, ErrNotFound, true},
+		{"joined errors match the second kind", errs, ErrValidation, true},
+		{"joined errors don't match other kinds", errs, ErrRateLimited, false},
+		{"error for one mutation", errs.ForPath("m2"), ErrValidation, true},
+		{"error for one mutation only matches its kind", errs.ForPath("m2"), ErrNotFound, false},
+		{"wrapped in a request error", &RequestError{StatusCode: 400, Err: errs.ForPath("m2")}, ErrValidation, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.kind); got != tt.want {
+				t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, tt.kind, got, tt.want)
+			}
+		})
+	}
+
+	if errs.ForPath("m3") != nil {
+		t.Errorf("ForPath(m3) = %v, want nil", errs.ForPath("m3"))
+	}
+}
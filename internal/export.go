@@ -0,0 +1,641 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats a list can be exported to
+const (
+	ExportJSON = "json" // Lossless aniview format
+	ExportXML  = "xml"  // MyAnimeList export format, accepted by MyAnimeList's importer
+)
+
+const (
+	exportVersion = 1
+	rateLimitWait = time.Minute // AniList and MyAnimeList rate limits reset within a minute
+	importRetries = 3
+)
+
+// ListExport is a user's whole list in the lossless aniview format
+type ListExport struct {
+	Version     int             `json:"version"`
+	ExportedAt  int64           `json:"exported_at"`
+	Tracker     string          `json:"tracker"` // Tracker the list was exported from, one of the Tracker constants
+	User        string          `json:"user"`
+	UserID      int             `json:"user_id"`
+	ScoreFormat string          `json:"score_format"`
+	Entries     []ExportedEntry `json:"entries"`
+}
+
+// ExportedEntry is a list entry with every field AniList keeps for it
+type ExportedEntry struct {
+	MediaID               int                `json:"media_id"`
+	MalID                 int                `json:"mal_id,omitempty"`
+	Title                 string             `json:"title"`
+	Episodes              int                `json:"episodes,omitempty"`
+	Format                string             `json:"format,omitempty"`
+	Status                string             `json:"status"`
+	Score                 float64            `json:"score"`
+	Progress              int                `json:"progress"`
+	Repeat                int                `json:"repeat"`
+	Priority              int                `json:"priority"`
+	Notes                 string             `json:"notes"`
+	Private               bool               `json:"private"`
+	HiddenFromStatusLists bool               `json:"hidden_from_status_lists"`
+	CustomLists           map[string]bool    `json:"custom_lists,omitempty"`
+	AdvancedScores        map[string]float64 `json:"advanced_scores,omitempty"`
+	StartedAt             Date               `json:"started_at"`
+	CompletedAt           Date               `json:"completed_at"`
+	CreatedAt             int64              `json:"created_at,omitempty"`
+	UpdatedAt             int64              `json:"updated_at,omitempty"`
+}
+
+// ExportList fetches the profile's whole list. AniList profiles export every field of
+// their entries, MyAnimeList profiles the fields MyAnimeList keeps.
+func ExportList(config *Config, tracker Tracker) (ListExport, error) {
+	export := ListExport{
+		Version:    exportVersion,
+		ExportedAt: time.Now().Unix(),
+		Tracker:    TrackerAniList,
+		User:       config.Username,
+		UserID:     config.UserID,
+	}
+
+	anilist, ok := tracker.(*AniListClient)
+	if !ok {
+		export.Tracker = TrackerMyAnimeList
+		entries, err := tracker.GetList(config.UserID, ListStatuses...)
+		if err != nil {
+			return export, err
+		}
+		if export.ScoreFormat, err = tracker.GetScoreFormat(); err != nil {
+			return export, err
+		}
+		for _, entry := range entries {
+			export.Entries = append(export.Entries, ExportedEntry{
+				MediaID:     entry.ID,
+				MalID:       entry.MalId,
+				Title:       entry.Title,
+				Episodes:    entry.Episodes,
+				Status:      entry.Status,
+				Score:       entry.Score,
+				Progress:    entry.Progress,
+				Repeat:      entry.Repeat,
+				Notes:       entry.Notes,
+				StartedAt:   entry.StartedAt,
+				CompletedAt: entry.CompletedAt,
+				UpdatedAt:   entry.UpdatedAt,
+			})
+		}
+		sortExportedEntries(export.Entries)
+		return export, nil
+	}
+
+	response, err := anilist.GetFullList(config.UserID)
+	if err != nil {
+		return export, err
+	}
+	collection := response.Data.MediaListCollection
+	export.ScoreFormat = collection.User.MediaListOptions.ScoreFormat
+
+	// Entries on custom lists show up once per list they are on
+	seen := make(map[int]bool)
+	for _, list := range collection.Lists {
+		for _, entry := range list.Entries {
+			if seen[entry.ID] {
+				continue
+			}
+			seen[entry.ID] = true
+
+			// Only keep the custom lists the entry is on
+			var customLists map[string]bool
+			for name, on := range entry.CustomLists {
+				if !on {
+					continue
+				}
+				if customLists == nil {
+					customLists = make(map[string]bool)
+				}
+				customLists[name] = true
+			}
+
+			export.Entries = append(export.Entries, ExportedEntry{
+				MediaID:               entry.Media.ID,
+				MalID:                 entry.Media.MalId,
+				Title:                 entry.Media.Title.Preferred(),
+				Episodes:              entry.Media.Episodes,
+				Format:                entry.Media.Format,
+				Status:                entry.Status,
+				Score:                 entry.Score,
+				Progress:              entry.Progress,
+				Repeat:                entry.Repeat,
+				Priority:              entry.Priority,
+				Notes:                 entry.Notes,
+				Private:               entry.Private,
+				HiddenFromStatusLists: entry.HiddenFromStatusLists,
+				CustomLists:           customLists,
+				AdvancedScores:        entry.AdvancedScores,
+				StartedAt:             entry.StartedAt,
+				CompletedAt:           entry.CompletedAt,
+				CreatedAt:             entry.CreatedAt,
+				UpdatedAt:             int64(entry.UpdatedAt),
+			})
+		}
+	}
+
+	sortExportedEntries(export.Entries)
+	return export, nil
+}
+
+// sortExportedEntries sorts entries by title so exports are stable
+func sortExportedEntries(entries []ExportedEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Title < entries[j].Title
+	})
+}
+
+// WriteJSON writes the export in the lossless aniview format
+func (e ListExport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(e); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// malExport is the layout of a MyAnimeList list export
+type malExport struct {
+	XMLName xml.Name      `xml:"myanimelist"`
+	MyInfo  malMyInfo     `xml:"myinfo"`
+	Anime   []malXMLAnime `xml:"anime"`
+}
+
+type malMyInfo struct {
+	UserID         int    `xml:"user_id"`
+	UserName       string `xml:"user_name"`
+	ExportType     int    `xml:"user_export_type"`
+	TotalAnime     int    `xml:"user_total_anime"`
+	TotalWatching  int    `xml:"user_total_watching"`
+	TotalCompleted int    `xml:"user_total_completed"`
+	TotalOnHold    int    `xml:"user_total_onhold"`
+	TotalDropped   int    `xml:"user_total_dropped"`
+	TotalPlanned   int    `xml:"user_total_plantowatch"`
+}
+
+type malXMLAnime struct {
+	ID             int    `xml:"series_animedb_id"`
+	Title          cdata  `xml:"series_title"`
+	Type           string `xml:"series_type"`
+	Episodes       int    `xml:"series_episodes"`
+	Watched        int    `xml:"my_watched_episodes"`
+	StartDate      string `xml:"my_start_date"`
+	FinishDate     string `xml:"my_finish_date"`
+	Score          int    `xml:"my_score"`
+	Status         string `xml:"my_status"`
+	Comments       cdata  `xml:"my_comments"`
+	TimesWatched   int    `xml:"my_times_watched"`
+	Priority       string `xml:"my_priority"`
+	Rewatching     int    `xml:"my_rewatching"`
+	UpdateOnImport int    `xml:"update_on_import"`
+}
+
+// cdata is text written as a CDATA section, as MyAnimeList does for titles and comments
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// malXMLStatuses maps AniList list statuses to the ones in MyAnimeList exports
+var malXMLStatuses = map[string]string{
+	"CURRENT":   "Watching",
+	"PLANNING":  "Plan to Watch",
+	"COMPLETED": "Completed",
+	"REPEATING": "Completed",
+	"PAUSED":    "On-Hold",
+	"DROPPED":   "Dropped",
+}
+
+// malXMLTypes maps AniList media formats to the series types in MyAnimeList exports
+var malXMLTypes = map[string]string{
+	"TV":       "TV",
+	"TV_SHORT": "TV",
+	"MOVIE":    "Movie",
+	"SPECIAL":  "Special",
+	"OVA":      "OVA",
+	"ONA":      "ONA",
+	"MUSIC":    "Music",
+}
+
+// WriteMALXML writes the export in MyAnimeList's format. Scores are converted to 10 points
+// and entries without a MyAnimeList ID are left out, returning how many were skipped.
+func (e ListExport) WriteMALXML(w io.Writer) (int, error) {
+	export := malExport{MyInfo: malMyInfo{UserName: e.User, ExportType: 1}}
+	if e.Tracker == TrackerMyAnimeList {
+		export.MyInfo.UserID = e.UserID
+	}
+
+	skipped := 0
+	for _, entry := range e.Entries {
+		if entry.MalID == 0 {
+			skipped++
+			continue
+		}
+
+		status := malXMLStatuses[entry.Status]
+		switch status {
+		case "Watching":
+			export.MyInfo.TotalWatching++
+		case "Completed":
+			export.MyInfo.TotalCompleted++
+		case "On-Hold":
+			export.MyInfo.TotalOnHold++
+		case "Dropped":
+			export.MyInfo.TotalDropped++
+		case "Plan to Watch":
+			export.MyInfo.TotalPlanned++
+		}
+
+		anime := malXMLAnime{
+			ID:             entry.MalID,
+			Title:          cdata{entry.Title},
+			Type:           malXMLTypes[entry.Format],
+			Episodes:       entry.Episodes,
+			Watched:        entry.Progress,
+			StartDate:      malXMLDate(entry.StartedAt),
+			FinishDate:     malXMLDate(entry.CompletedAt),
+			Score:          int(math.Round(ConvertScore(entry.Score, e.ScoreFormat, ScorePoint10))),
+			Status:         status,
+			Comments:       cdata{entry.Notes},
+			TimesWatched:   entry.Repeat,
+			Priority:       "LOW",
+			UpdateOnImport: 1,
+		}
+		if entry.Status == "REPEATING" {
+			anime.Rewatching = 1
+		}
+		export.Anime = append(export.Anime, anime)
+	}
+	export.MyInfo.TotalAnime = len(export.Anime)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return skipped, fmt.Errorf("failed to write export: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(export); err != nil {
+		return skipped, fmt.Errorf("failed to write export: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return skipped, fmt.Errorf("failed to write export: %w", err)
+	}
+	return skipped, nil
+}
+
+// malXMLDate formats a date the way MyAnimeList exports do, with zeros for unknown parts
+func malXMLDate(date Date) string {
+	return fmt.Sprintf("%04d-%02d-%02d", date.Year, date.Month, date.Day)
+}
+
+// parseMALXMLDate parses a date from a MyAnimeList export
+func parseMALXMLDate(value string) Date {
+	var date Date
+	fields := strings.Split(strings.TrimSpace(value), "-")
+	targets := []*int{&date.Year, &date.Month, &date.Day}
+	for i := 0; i < len(fields) && i < len(targets); i++ {
+		*targets[i], _ = strconv.Atoi(fields[i])
+	}
+	return date
+}
+
+// ReadExport reads an export in either the aniview or the MyAnimeList format. Entries read
+// from a MyAnimeList export only have a MyAnimeList ID, see ResolveMediaIDs.
+func ReadExport(r io.Reader) (ListExport, error) {
+	reader := bufio.NewReader(r)
+
+	// Skip whitespace and a byte order mark to tell JSON from XML
+	var first byte
+	for first == 0 {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return ListExport{}, fmt.Errorf("failed to read export: %w", err)
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n', 0xef, 0xbb, 0xbf:
+		default:
+			first = b
+			reader.UnreadByte()
+		}
+	}
+
+	switch first {
+	case '{':
+		var export ListExport
+		if err := json.NewDecoder(reader).Decode(&export); err != nil {
+			return export, fmt.Errorf("failed to parse export: %w", err)
+		}
+		if export.Version > exportVersion {
+			return export, fmt.Errorf("export was written by a newer aniview (version %d)", export.Version)
+		}
+		return export, nil
+	case '<':
+		return readMALXML(reader)
+	default:
+		return ListExport{}, fmt.Errorf("export is neither JSON nor MyAnimeList XML")
+	}
+}
+
+// readMALXML reads a MyAnimeList export
+func readMALXML(r io.Reader) (ListExport, error) {
+	var file malExport
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return ListExport{}, fmt.Errorf("failed to parse MyAnimeList export: %w", err)
+	}
+
+	export := ListExport{
+		Version:     exportVersion,
+		Tracker:     TrackerMyAnimeList,
+		User:        file.MyInfo.UserName,
+		UserID:      file.MyInfo.UserID,
+		ScoreFormat: ScorePoint10,
+	}
+	for _, anime := range file.Anime {
+		var status string
+		for aniListStatus, malStatus := range malXMLStatuses {
+			if malStatus == anime.Status && aniListStatus != "REPEATING" {
+				status = aniListStatus
+			}
+		}
+		if status == "" {
+			return export, fmt.Errorf("unknown status %q for %s", anime.Status, anime.Title.Value)
+		}
+		if anime.Rewatching == 1 {
+			status = "REPEATING"
+		}
+
+		export.Entries = append(export.Entries, ExportedEntry{
+			MalID:       anime.ID,
+			Title:       anime.Title.Value,
+			Episodes:    anime.Episodes,
+			Status:      status,
+			Score:       float64(anime.Score),
+			Progress:    anime.Watched,
+			Repeat:      anime.TimesWatched,
+			Notes:       anime.Comments.Value,
+			StartedAt:   parseMALXMLDate(anime.StartDate),
+			CompletedAt: parseMALXMLDate(anime.FinishDate),
+		})
+	}
+	return export, nil
+}
+
+// ResolveMediaIDs looks up the AniList IDs of entries that only have a MyAnimeList ID.
+// Entries that aren't on AniList keep a media ID of 0.
+func ResolveMediaIDs(export *ListExport, anilist *AniListClient) error {
+	var malIDs []int
+	for _, entry := range export.Entries {
+		if entry.MediaID == 0 && entry.MalID != 0 {
+			malIDs = append(malIDs, entry.MalID)
+		}
+	}
+	if len(malIDs) == 0 {
+		return nil
+	}
+
+	media, err := anilist.GetMediaByMalIDs(malIDs)
+	if err != nil {
+		return err
+	}
+	for i := range export.Entries {
+		entry := &export.Entries[i]
+		if entry.MediaID != 0 {
+			continue
+		}
+		if m, ok := media[entry.MalID]; ok {
+			entry.MediaID = m.ID
+			if entry.Format == "" {
+				entry.Format = m.Format
+			}
+		}
+	}
+	return nil
+}
+
+// ImportDiff is a field that differs between the current and the imported entry
+type ImportDiff struct {
+	Field    string
+	Current  string
+	Imported string
+}
+
+// ImportChange is a change to the list planned by an import
+type ImportChange struct {
+	Title   string
+	Added   bool // Whether the anime is missing from the list
+	Diffs   []ImportDiff
+	Update  ListEntryUpdate
+	Applied bool
+	Err     error
+}
+
+// PlanImport plans the changes that make the current list match the imported entries.
+// Entries that are already the same are skipped, and entries without an AniList ID are
+// returned as unmatched. AniList only fields are imported only between AniList lists.
+func PlanImport(imported ListExport, current ListExport) ([]ImportChange, []ExportedEntry) {
+	extended := imported.Tracker == TrackerAniList && current.Tracker == TrackerAniList
+
+	currentEntries := make(map[int]ExportedEntry, len(current.Entries))
+	for _, entry := range current.Entries {
+		currentEntries[entry.MediaID] = entry
+	}
+
+	var changes []ImportChange
+	var unmatched []ExportedEntry
+	for _, entry := range imported.Entries {
+		if entry.MediaID == 0 {
+			unmatched = append(unmatched, entry)
+			continue
+		}
+		entry.Score = ConvertScore(entry.Score, imported.ScoreFormat, current.ScoreFormat)
+
+		existing, ok := currentEntries[entry.MediaID]
+		if !ok {
+			changes = append(changes, ImportChange{
+				Title:  entry.Title,
+				Added:  true,
+				Update: importUpdate(entry, nil, extended),
+			})
+			continue
+		}
+
+		diffs := diffImport(existing, entry, current.ScoreFormat, extended)
+		if len(diffs) == 0 {
+			continue
+		}
+		changes = append(changes, ImportChange{
+			Title:  entry.Title,
+			Diffs:  diffs,
+			Update: importUpdate(entry, diffs, extended),
+		})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Title < changes[j].Title
+	})
+	return changes, unmatched
+}
+
+// diffImport returns the imported fields that differ from the current entry
+func diffImport(current, imported ExportedEntry, scoreFormat string, extended bool) []ImportDiff {
+	var diffs []ImportDiff
+	add := func(field, c, i string) {
+		if c != i {
+			diffs = append(diffs, ImportDiff{Field: field, Current: c, Imported: i})
+		}
+	}
+
+	add("status", current.Status, imported.Status)
+	add("progress", strconv.Itoa(current.Progress), strconv.Itoa(imported.Progress))
+	add("score", FormatScore(scoreFormat, current.Score), FormatScore(scoreFormat, imported.Score))
+	add("repeat", strconv.Itoa(current.Repeat), strconv.Itoa(imported.Repeat))
+	add("notes", current.Notes, imported.Notes)
+	add("started", FormatDate(current.StartedAt), FormatDate(imported.StartedAt))
+	add("completed", FormatDate(current.CompletedAt), FormatDate(imported.CompletedAt))
+	if extended {
+		add("priority", strconv.Itoa(current.Priority), strconv.Itoa(imported.Priority))
+		add("private", strconv.FormatBool(current.Private), strconv.FormatBool(imported.Private))
+		add("hidden", strconv.FormatBool(current.HiddenFromStatusLists), strconv.FormatBool(imported.HiddenFromStatusLists))
+		add("lists", strings.Join(customListNames(current.CustomLists), ", "), strings.Join(customListNames(imported.CustomLists), ", "))
+	}
+	return diffs
+}
+
+// importUpdate builds an update setting the differing fields, or every field when diffs is nil.
+// Advanced scores are only exported, AniList takes them as a list in the order of the
+// user's scoring categories, which may differ between accounts.
+func importUpdate(entry ExportedEntry, diffs []ImportDiff, extended bool) ListEntryUpdate {
+	fields := map[string]bool{
+		"status": true, "progress": true, "score": true, "repeat": true, "notes": true, "started": true, "completed": true,
+		"priority": extended, "private": extended, "hidden": extended, "lists": extended,
+	}
+	if diffs != nil {
+		for field := range fields {
+			fields[field] = false
+		}
+		for _, diff := range diffs {
+			fields[diff.Field] = true
+		}
+	}
+
+	update := ListEntryUpdate{MediaID: entry.MediaID}
+	if fields["status"] {
+		update.Status = &entry.Status
+	}
+	if fields["progress"] {
+		update.Progress = &entry.Progress
+	}
+	if fields["score"] {
+		update.Score = &entry.Score
+	}
+	if fields["repeat"] {
+		update.Repeat = &entry.Repeat
+	}
+	if fields["notes"] {
+		update.Notes = &entry.Notes
+	}
+	if fields["started"] {
+		update.StartedAt = &entry.StartedAt
+	}
+	if fields["completed"] {
+		update.CompletedAt = &entry.CompletedAt
+	}
+	if fields["priority"] {
+		update.Priority = &entry.Priority
+	}
+	if fields["private"] {
+		update.Private = &entry.Private
+	}
+	if fields["hidden"] {
+		update.HiddenFromStatusLists = &entry.HiddenFromStatusLists
+	}
+	if fields["lists"] {
+		update.CustomLists = customListNames(entry.CustomLists)
+		if update.CustomLists == nil {
+			update.CustomLists = []string{}
+		}
+	}
+	return update
+}
+
+// customListNames returns the sorted names of the custom lists an entry is on
+func customListNames(lists map[string]bool) []string {
+	var names []string
+	for name, on := range lists {
+		if on {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyImport sends the planned changes in batches, at most perMinute changes a minute,
+// recording the result on each change. Rate limited changes are retried after the limit
+// resets. progress is called after every batch with the number of changes sent so far.
+func ApplyImport(changes []ImportChange, tracker Tracker, perMinute int, batchSize int, progress func(done int)) {
+	if perMinute < 1 {
+		perMinute = 1
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	ticker := time.NewTicker(time.Duration(batchSize) * time.Minute / time.Duration(perMinute))
+	defer ticker.Stop()
+
+	for start := 0; start < len(changes); start += batchSize {
+		if start > 0 {
+			<-ticker.C
+		}
+		batch := changes[start:min(start+batchSize, len(changes))]
+
+		pending := make([]int, len(batch))
+		for i := range batch {
+			pending[i] = i
+		}
+		for attempt := 0; len(pending) > 0; attempt++ {
+			updates := make([]ListEntryUpdate, len(pending))
+			for i, index := range pending {
+				updates[i] = batch[index].Update
+			}
+			_, errs := tracker.SaveEntries(updates)
+
+			var limited []int
+			wait := rateLimitWait
+			for i, index := range pending {
+				batch[index].Err = errs[i]
+				batch[index].Applied = errs[i] == nil
+				if IsRateLimited(errs[i]) {
+					limited = append(limited, index)
+					wait = RetryAfter(errs[i], rateLimitWait)
+				}
+			}
+			if len(limited) == 0 || attempt == importRetries {
+				break
+			}
+			pending = limited
+			time.Sleep(wait)
+		}
+
+		if progress != nil {
+			progress(start + len(batch))
+		}
+	}
+}
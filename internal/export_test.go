@@ -0,0 +1,324 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExportJSONRoundTrip(t *testing.T) {
+	export := ListExport{
+		Version:     exportVersion,
+		ExportedAt:  1700000000,
+		Tracker:     TrackerAniList,
+		User:        "aniview_test",
+		UserID:      42,
+		ScoreFormat: ScorePoint100,
+		Entries: []ExportedEntry{
+			{
+				MediaID:               154587,
+				MalID:                 52991,
+				Title:                 "Frieren",
+				Episodes:              28,
+				Format:                "TV",
+				Status:                "REPEATING",
+				Score:                 95,
+				Progress:              3,
+				Repeat:                1,
+				Priority:              2,
+				Notes:                 "Stark & Fern <3",
+				Private:               true,
+				HiddenFromStatusLists: true,
+				CustomLists:           map[string]bool{"Favourites": true},
+				AdvancedScores:        map[string]float64{"Story": 90, "Music": 100},
+				StartedAt:             Date{Year: 2023, Month: 9, Day: 29},
+				CompletedAt:           Date{Year: 2024, Month: 3, Day: 22},
+				CreatedAt:             1695945600,
+				UpdatedAt:             1711065600,
+			},
+			{MediaID: 21, MalID: 21, Title: "One Piece", Status: "CURRENT", Progress: 1071},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() = %v", err)
+	}
+	read, err := ReadExport(&buf)
+	if err != nil {
+		t.Fatalf("ReadExport() = %v", err)
+	}
+	if !reflect.DeepEqual(read, export) {
+		t.Errorf("ReadExport() = %+v, want %+v", read, export)
+	}
+}
+
+func TestReadExportRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", "", "failed to read export"},
+		{"neither format", "media_id,title\n", "neither JSON nor MyAnimeList XML"},
+		{"newer version", `{"version": 99}`, "newer aniview"},
+		{"unknown status", `<myanimelist><anime><series_title><![CDATA[A]]></series_title><my_status>Rewatching</my_status></anime></myanimelist>`, `unknown status "Rewatching"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadExport(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadExport() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadMALXMLExport(t *testing.T) {
+	// An export as MyAnimeList writes it, starting with a byte order mark
+	file, err := os.Open("testdata/animelist.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	export, err := ReadExport(file)
+	if err != nil {
+		t.Fatalf("ReadExport() = %v", err)
+	}
+	if export.Tracker != TrackerMyAnimeList || export.User != "aniview_test" || export.UserID != 8675309 || export.ScoreFormat != ScorePoint10 {
+		t.Errorf("ReadExport() = %s user %s (%d) with %s scores, want MyAnimeList user aniview_test (8675309) with %s scores",
+			export.Tracker, export.User, export.UserID, export.ScoreFormat, ScorePoint10)
+	}
+
+	want := []ExportedEntry{
+		{
+			MalID:       52991,
+			Title:       "Sousou no Frieren",
+			Episodes:    28,
+			Status:      "REPEATING",
+			Score:       10,
+			Progress:    28,
+			Repeat:      1,
+			Notes:       "Stark & Fern <3",
+			StartedAt:   Date{Year: 2023, Month: 9, Day: 29},
+			CompletedAt: Date{Year: 2024, Month: 3, Day: 22},
+		},
+		{
+			MalID:       457,
+			Title:       "Mushishi",
+			Episodes:    26,
+			Status:      "COMPLETED",
+			Score:       9,
+			Progress:    26,
+			CompletedAt: Date{Year: 2019, Month: 7},
+		},
+		{
+			MalID:     21,
+			Title:     "One Piece",
+			Status:    "CURRENT",
+			Progress:  1071,
+			StartedAt: Date{Year: 2020, Month: 4, Day: 1},
+		},
+	}
+	if !reflect.DeepEqual(export.Entries, want) {
+		t.Errorf("ReadExport() entries = %+v, want %+v", export.Entries, want)
+	}
+
+	// Writing the list back keeps every field MyAnimeList exports
+	var buf bytes.Buffer
+	if _, err := export.WriteMALXML(&buf); err != nil {
+		t.Fatalf("WriteMALXML() = %v", err)
+	}
+	rewritten, err := ReadExport(&buf)
+	if err != nil {
+		t.Fatalf("ReadExport() of the written export = %v", err)
+	}
+	if !reflect.DeepEqual(rewritten, export) {
+		t.Errorf("ReadExport() of the written export = %+v, want %+v", rewritten, export)
+	}
+}
+
+func TestWriteMALXML(t *testing.T) {
+	export := ListExport{
+		Tracker:     TrackerAniList,
+		User:        "aniview_test",
+		UserID:      42,
+		ScoreFormat: ScorePoint100,
+		Entries: []ExportedEntry{
+			{MediaID: 154587, MalID: 52991, Title: "Frieren", Format: "TV", Status: "REPEATING", Score: 95, Progress: 3, Repeat: 1},
+			{MediaID: 5114, MalID: 5114, Title: "Fullmetal Alchemist: Brotherhood", Format: "TV", Status: "PAUSED", Score: 74, Progress: 20},
+			{MediaID: 999999, Title: "Only on AniList", Status: "PLANNING"},
+		},
+	}
+
+	var buf bytes.Buffer
+	skipped, err := export.WriteMALXML(&buf)
+	if err != nil {
+		t.Fatalf("WriteMALXML() = %v", err)
+	}
+	if skipped != 1 {
+		t.Errorf("WriteMALXML() skipped %d entries, want 1", skipped)
+	}
+	for _, want := range []string{"<my_rewatching>1</my_rewatching>", "<series_title><![CDATA[Frieren]]></series_title>", "<user_total_anime>2</user_total_anime>", "<user_id>0</user_id>"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteMALXML() wrote no %s:\n%s", want, buf.String())
+		}
+	}
+
+	read, err := ReadExport(&buf)
+	if err != nil {
+		t.Fatalf("ReadExport() = %v", err)
+	}
+	want := []ExportedEntry{
+		{MalID: 52991, Title: "Frieren", Status: "REPEATING", Score: 10, Progress: 3, Repeat: 1},
+		{MalID: 5114, Title: "Fullmetal Alchemist: Brotherhood", Status: "PAUSED", Score: 7, Progress: 20},
+	}
+	if !reflect.DeepEqual(read.Entries, want) {
+		t.Errorf("ReadExport() entries = %+v, want %+v", read.Entries, want)
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	current := ExportedEntry{
+		MediaID:     154587,
+		Title:       "Frieren",
+		Status:      "CURRENT",
+		Score:       90,
+		Progress:    10,
+		Priority:    1,
+		CustomLists: map[string]bool{"Favourites": true},
+	}
+	with := func(entry ExportedEntry, change func(*ExportedEntry)) ExportedEntry {
+		change(&entry)
+		return entry
+	}
+
+	type planned struct {
+		title  string
+		added  bool
+		diffs  []string // Fields that differ
+		update string   // The update as JSON
+	}
+
+	tests := []struct {
+		name          string
+		from          string // Tracker the import was exported from
+		scoreFormat   string // Score format of the import
+		imported      []ExportedEntry
+		want          []planned
+		wantUnmatched []string
+	}{
+		{
+			name:        "same entry",
+			from:        TrackerAniList,
+			scoreFormat: ScorePoint100,
+			imported:    []ExportedEntry{current},
+		},
+		{
+			name:        "extended fields between anilist lists",
+			from:        TrackerAniList,
+			scoreFormat: ScorePoint100,
+			imported: []ExportedEntry{with(current, func(e *ExportedEntry) {
+				e.Progress = 12
+				e.Priority = 3
+				e.Private = true
+				e.CustomLists = map[string]bool{"Favourites": true, "Rewatch": true}
+			})},
+			want: []planned{{
+				title:  "Frieren",
+				diffs:  []string{"progress", "priority", "private", "lists"},
+				update: `{"mediaId":154587,"progress":12,"private":true,"priority":3,"customLists":["Favourites","Rewatch"]}`,
+			}},
+		},
+		{
+			name:        "basic fields from mal",
+			from:        TrackerMyAnimeList,
+			scoreFormat: ScorePoint10,
+			imported: []ExportedEntry{with(current, func(e *ExportedEntry) {
+				e.Progress = 12
+				e.Score = 9
+				e.Priority = 0
+				e.CustomLists = nil
+			})},
+			want: []planned{{title: "Frieren", diffs: []string{"progress"}, update: `{"mediaId":154587,"progress":12}`}},
+		},
+		{
+			name:        "score converted to the current format",
+			from:        TrackerMyAnimeList,
+			scoreFormat: ScorePoint10,
+			imported:    []ExportedEntry{with(current, func(e *ExportedEntry) { e.Score = 7 })},
+			want:        []planned{{title: "Frieren", diffs: []string{"score"}, update: `{"mediaId":154587,"score":70}`}},
+		},
+		{
+			name:        "added with extended fields",
+			from:        TrackerAniList,
+			scoreFormat: ScorePoint100,
+			imported:    []ExportedEntry{{MediaID: 21, Title: "One Piece", Status: "PLANNING", Private: true}},
+			want: []planned{{
+				title:  "One Piece",
+				added:  true,
+				update: `{"mediaId":21,"status":"PLANNING","score":0,"progress":0,"repeat":0,"notes":"","private":true,"hiddenFromStatusLists":false,"startedAt":{"year":0,"month":0,"day":0},"completedAt":{"year":0,"month":0,"day":0},"priority":0}`,
+			}},
+		},
+		{
+			name:        "added with basic fields",
+			from:        TrackerMyAnimeList,
+			scoreFormat: ScorePoint10,
+			imported:    []ExportedEntry{{MediaID: 21, MalID: 21, Title: "One Piece", Status: "CURRENT", Score: 8, Progress: 1071}},
+			want: []planned{{
+				title:  "One Piece",
+				added:  true,
+				update: `{"mediaId":21,"status":"CURRENT","score":80,"progress":1071,"repeat":0,"notes":"","startedAt":{"year":0,"month":0,"day":0},"completedAt":{"year":0,"month":0,"day":0}}`,
+			}},
+		},
+		{
+			name:        "not on anilist",
+			from:        TrackerMyAnimeList,
+			scoreFormat: ScorePoint10,
+			imported: []ExportedEntry{
+				{MalID: 99999, Title: "Only on MyAnimeList", Status: "COMPLETED"},
+				{Title: "No IDs at all", Status: "PLANNING"},
+			},
+			wantUnmatched: []string{"Only on MyAnimeList", "No IDs at all"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imported := ListExport{Tracker: tt.from, ScoreFormat: tt.scoreFormat, Entries: tt.imported}
+			currentList := ListExport{Tracker: TrackerAniList, ScoreFormat: ScorePoint100, Entries: []ExportedEntry{current}}
+			changes, unmatched := PlanImport(imported, currentList)
+
+			if len(changes) != len(tt.want) {
+				t.Fatalf("PlanImport() planned %d change(s), want %d: %+v", len(changes), len(tt.want), changes)
+			}
+			for i, want := range tt.want {
+				got := changes[i]
+				var diffs []string
+				for _, diff := range got.Diffs {
+					diffs = append(diffs, diff.Field)
+				}
+				if got.Title != want.title || got.Added != want.added || !slices.Equal(diffs, want.diffs) {
+					t.Errorf("change %d = %s, added %v, diffs %v, want %s, added %v, diffs %v",
+						i, got.Title, got.Added, diffs, want.title, want.added, want.diffs)
+				}
+				if update := updateJSON(t, got.Update); update != want.update {
+					t.Errorf("change %d update = %s, want %s", i, update, want.update)
+				}
+			}
+
+			var unmatchedTitles []string
+			for _, entry := range unmatched {
+				unmatchedTitles = append(unmatchedTitles, entry.Title)
+			}
+			if !slices.Equal(unmatchedTitles, tt.wantUnmatched) {
+				t.Errorf("PlanImport() unmatched = %v, want %v", unmatchedTitles, tt.wantUnmatched)
+			}
+		})
+	}
+}
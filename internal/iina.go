@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -62,83 +64,143 @@ func MPVSendCommand(ipcSocketPath string, command []interface{}) (interface{}, e
 	return nil, nil
 }
 
-func getVideoDuration(ipcSocketPath string) (float64, error) {
-	// Send the 'get_property' command to MPV to get the duration of the video
-	command := []interface{}{"get_property", "duration"}
-	data, err := MPVSendCommand(ipcSocketPath, command)
-	if err != nil {
-		return 0, err
+func CleanupSocket(socketPath string) {
+	if _, err := os.Stat(socketPath); err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		fmt.Printf("Error checking socket: %v\n", err)
+		return
 	}
 
-	// Assert the data to float64 (MPV will return a float representing the duration in seconds)
-	if videoDuration, ok := data.(float64); ok {
-		return videoDuration, nil
+	if err := os.Remove(socketPath); err != nil {
+		fmt.Printf("Error removing socket: %v\n", err)
 	}
-
-	return 0, nil
 }
 
-func getCurrentPlaybackTime(ipcSocketPath string) (float64, error) {
-	// Send the 'get_property' command to MPV to get the current time-pos
-	command := []interface{}{"get_property", "time-pos"}
-	data, err := MPVSendCommand(ipcSocketPath, command)
-	if err != nil {
-		return 0, err
-	}
+// PlaybackState is what the player reports about the episode being played
+type PlaybackState struct {
+	Position time.Duration // Position when the state was reported
+	Duration time.Duration // 0 until the player knows it
+	Paused   bool
+}
 
-	// Assert the data to float64 (MPV will return a float representing time in seconds)
-	if timePos, ok := data.(float64); ok {
-		return timePos, nil
-	}
+// PlaybackListener is told about the episode being played, such as the Discord presence
+type PlaybackListener interface {
+	// Playing is called when an episode starts, is paused or resumed, or is seeked
+	Playing(anime AnimeEntry, state PlaybackState)
+	// Stopped is called when the player quits
+	Stopped()
+}
 
-	return 0, nil
+// mpvEvent is an event or command response sent by the mpv IPC server
+type mpvEvent struct {
+	Event     string          `json:"event"`
+	Name      string          `json:"name"`
+	Data      json.RawMessage `json:"data"`
+	RequestID int             `json:"request_id"`
+	Error     string          `json:"error"`
 }
 
-func getPausedState(ipcSocketPath string) (bool, error) {
-	// Send the 'get_property' command to MPV to get the 'pause' property
-	command := []interface{}{"get_property", "pause"}
-	data, err := MPVSendCommand(ipcSocketPath, command)
+// positionRequest is the request ID of time-pos queries, other commands use 0
+const positionRequest = 1
+
+// WatchPlayback waits for the player's IPC socket and calls onChange whenever playback
+// starts, is paused or resumed, is seeked, or the duration becomes known. It returns when
+// the player closes the socket or ctx is done.
+func WatchPlayback(ctx context.Context, ipcSocketPath string, onChange func(PlaybackState)) error {
+	conn, err := waitForSocket(ctx, ipcSocketPath)
 	if err != nil {
-		return false, err
+		return err
 	}
+	defer conn.Close()
+
+	// Unblock the reader when ctx is done
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
 
-	// Assert the data to a boolean (MPV will return true/false for pause state)
-	if paused, ok := data.(bool); ok {
-		return paused, nil
+	send := func(command ...interface{}) error {
+		return mpvWrite(conn, command, 0)
+	}
+	if err := send("observe_property", 1, "pause"); err != nil {
+		return err
+	}
+	if err := send("observe_property", 2, "duration"); err != nil {
+		return err
 	}
 
-	return false, nil
-}
+	// The position is only asked for when something changes, the listeners extrapolate it
+	var state PlaybackState
+	requestPosition := func() error {
+		return mpvWrite(conn, []interface{}{"get_property", "time-pos"}, positionRequest)
+	}
 
-func CleanupSocket(socketPath string) {
-	if _, err := os.Stat(socketPath); err != nil {
-		if os.IsNotExist(err) {
-			return
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var event mpvEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+
+		switch {
+		case event.RequestID == positionRequest:
+			var seconds float64
+			if event.Error != "success" || json.Unmarshal(event.Data, &seconds) != nil {
+				continue
+			}
+			state.Position = time.Duration(seconds * float64(time.Second))
+			onChange(state)
+		case event.Event == "property-change" && event.Name == "pause":
+			json.Unmarshal(event.Data, &state.Paused)
+			err = requestPosition()
+		case event.Event == "property-change" && event.Name == "duration":
+			var seconds float64
+			if json.Unmarshal(event.Data, &seconds) == nil && seconds > 0 {
+				state.Duration = time.Duration(seconds * float64(time.Second))
+				err = requestPosition()
+			}
+		case event.Event == "playback-restart":
+			// Sent once an episode starts and after every seek
+			err = requestPosition()
+		}
+		if err != nil {
+			return err
 		}
-		fmt.Printf("Error checking socket: %v\n", err)
-		return
 	}
 
-	if err := os.Remove(socketPath); err != nil {
-		fmt.Printf("Error removing socket: %v\n", err)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
+
+// mpvWrite sends a command to the mpv IPC server without waiting for the response
+func mpvWrite(conn net.Conn, command []interface{}, requestID int) error {
+	message, err := json.Marshal(map[string]interface{}{
+		"command":    command,
+		"request_id": requestID,
+	})
+	if err != nil {
+		return err
 	}
+	_, err = conn.Write(append(message, '\n'))
+	return err
 }
 
-func waitForSocket(socketPath string) error {
+// waitForSocket connects to the player's IPC socket once the player has created it
+func waitForSocket(ctx context.Context, socketPath string) (net.Conn, error) {
 	for {
-		_, err := os.Stat(socketPath)
+		conn, err := connectToPipe(socketPath)
 		if err == nil {
-			// File exists, proceed
-			return nil
+			return conn, nil
 		}
 
-		if os.IsNotExist(err) {
-			// File does not exist, keep waiting
-			time.Sleep(1 * time.Second)
-			continue
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(500 * time.Millisecond):
 		}
-
-		// An unexpected error occurred (e.g., permission issues)
-		return fmt.Errorf("error checking socket file: %v", err)
 	}
 }
@@ -1,83 +1,329 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-// GetEpisodeData fetches episode data for a given anime ID and episode number
-func GetEpisodeData(animeID int, episodeNo int, anime *AnimeEntry) error {
-	url := fmt.Sprintf("https://api.jikan.moe/v4/anime/%d/episodes/%d", animeID, episodeNo)
+const (
+	jikanAPIURL      = "https://api.jikan.moe/v4"
+	jikanPerSecond   = 3 // Jikan allows 3 requests a second
+	jikanPerMinute   = 60
+	jikanRetries     = 3
+	jikanRetryWait   = time.Second
+	jikanMaxPages    = 50 // Guards against endless pagination
+	jikanHistorySize = jikanPerMinute
+)
 
-	// Use the helper function for making the GET request
-	response, err := makeGetRequest(url, nil)
-	if err != nil {
-		return nil
+// JikanClient reads MyAnimeList data through the Jikan v4 API. It keeps to Jikan's
+// rate limits, so a single client should be shared.
+type JikanClient struct {
+	httpClient *http.Client
+	baseURL    string
+
+	mu   sync.Mutex
+	sent []time.Time // Send times of the most recent requests, oldest first
+}
+
+// NewJikanClient creates a new Jikan client
+func NewJikanClient() *JikanClient {
+	return &JikanClient{
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		baseURL: jikanAPIURL,
 	}
+}
+
+// jikan is the client shared by the package functions, so they keep to one rate limit
+var jikan = NewJikanClient()
+
+// JikanError is an error reported by Jikan
+type JikanError struct {
+	Status  int    `json:"status"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Detail  string `json:"error"`
+}
 
-	data, ok := response["data"].(map[string]interface{})
-	if !ok {
-		return nil
+func (e *JikanError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%s: %s", e.Message, e.Detail)
 	}
-	// getStringValue := func(field string) string {
-	// 	if value, ok := data[field].(string); ok {
-	// 		return value
-	// 	}
-	// 	return ""
-	// }
+	return e.Message
+}
 
-	getIntValue := func(field string) int {
-		if value, ok := data[field].(float64); ok {
-			return int(value)
-		}
-		return 0
+// JikanPagination describes the pages of a paginated Jikan response
+type JikanPagination struct {
+	LastVisiblePage int  `json:"last_visible_page"`
+	HasNextPage     bool `json:"has_next_page"`
+}
+
+// JikanImages holds the image URLs of an anime or character
+type JikanImages struct {
+	JPG struct {
+		ImageURL      string `json:"image_url"`
+		SmallImageURL string `json:"small_image_url"`
+		LargeImageURL string `json:"large_image_url"`
+	} `json:"jpg"`
+}
+
+// JikanResource is a named MyAnimeList resource such as a genre or studio
+type JikanResource struct {
+	MalID int    `json:"mal_id"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+}
+
+// JikanAnime is an anime on MyAnimeList
+type JikanAnime struct {
+	MalID         int         `json:"mal_id"`
+	URL           string      `json:"url"`
+	Images        JikanImages `json:"images"`
+	Title         string      `json:"title"`
+	TitleEnglish  string      `json:"title_english"`
+	TitleJapanese string      `json:"title_japanese"`
+	Type          string      `json:"type"`
+	Source        string      `json:"source"`
+	Episodes      int         `json:"episodes"`
+	Status        string      `json:"status"`
+	Airing        bool        `json:"airing"`
+	Aired         struct {
+		From time.Time `json:"from"` // Zero when unknown
+		To   time.Time `json:"to"`
+	} `json:"aired"`
+	Duration   string  `json:"duration"` // e.g. "24 min per ep"
+	Rating     string  `json:"rating"`
+	Score      float64 `json:"score"`
+	Rank       int     `json:"rank"`
+	Popularity int     `json:"popularity"`
+	Synopsis   string  `json:"synopsis"`
+	Season     string  `json:"season"`
+	Year       int     `json:"year"`
+	Broadcast  struct {
+		Day      string `json:"day"`
+		Time     string `json:"time"`
+		Timezone string `json:"timezone"`
+		String   string `json:"string"`
+	} `json:"broadcast"`
+	Genres  []JikanResource `json:"genres"`
+	Studios []JikanResource `json:"studios"`
+}
+
+// Episode holds the metadata Jikan has for an episode
+type Episode struct {
+	Number       int       `json:"mal_id"`
+	Title        string    `json:"title"`
+	TitleRomanji string    `json:"title_romanji"`
+	Aired        time.Time `json:"aired"` // Zero when unknown
+	Filler       bool      `json:"filler"`
+	Recap        bool      `json:"recap"`
+}
+
+// EpisodeDetail is an episode with the fields only returned when fetching it on its own
+type EpisodeDetail struct {
+	Episode
+	Duration int    `json:"duration"` // Seconds, 0 when unknown
+	Synopsis string `json:"synopsis"`
+}
+
+// JikanCharacter is a character of an anime along with its voice actors
+type JikanCharacter struct {
+	Character struct {
+		MalID  int         `json:"mal_id"`
+		URL    string      `json:"url"`
+		Images JikanImages `json:"images"`
+		Name   string      `json:"name"`
+	} `json:"character"`
+	Role        string `json:"role"` // Main or Supporting
+	VoiceActors []struct {
+		Person struct {
+			MalID int    `json:"mal_id"`
+			URL   string `json:"url"`
+			Name  string `json:"name"`
+		} `json:"person"`
+		Language string `json:"language"`
+	} `json:"voice_actors"`
+}
+
+// GetAnime fetches an anime by its MyAnimeList ID
+func (c *JikanClient) GetAnime(malID int) (JikanAnime, error) {
+	var response struct {
+		Data JikanAnime `json:"data"`
 	}
+	if err := c.get(fmt.Sprintf("/anime/%d", malID), nil, &response); err != nil {
+		return JikanAnime{}, fmt.Errorf("failed to fetch anime %d: %w", malID, err)
+	}
+	return response.Data, nil
+}
 
-	// getBoolValue := func(field string) bool {
-	// 	if value, ok := data[field].(bool); ok {
-	// 		return value
-	// 	}
-	// 	return false
-	// }
+// GetEpisodes fetches every episode of an anime, following the pagination
+func (c *JikanClient) GetEpisodes(malID int) ([]Episode, error) {
+	episodes, err := getAllPages[Episode](c, fmt.Sprintf("/anime/%d/episodes", malID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch episodes of anime %d: %w", malID, err)
+	}
+	return episodes, nil
+}
 
-	anime.EpisodeDuration = getIntValue("duration")
+// GetEpisode fetches a single episode of an anime, including its duration and synopsis
+func (c *JikanClient) GetEpisode(malID int, number int) (EpisodeDetail, error) {
+	var response struct {
+		Data EpisodeDetail `json:"data"`
+	}
+	if err := c.get(fmt.Sprintf("/anime/%d/episodes/%d", malID, number), nil, &response); err != nil {
+		return EpisodeDetail{}, fmt.Errorf("failed to fetch episode %d of anime %d: %w", number, malID, err)
+	}
+	return response.Data, nil
+}
 
-	return nil
+// GetCharacters fetches the characters of an anime and their voice actors
+func (c *JikanClient) GetCharacters(malID int) ([]JikanCharacter, error) {
+	var response struct {
+		Data []JikanCharacter `json:"data"`
+	}
+	if err := c.get(fmt.Sprintf("/anime/%d/characters", malID), nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to fetch characters of anime %d: %w", malID, err)
+	}
+	return response.Data, nil
 }
 
-func makeGetRequest(url string, headers map[string]string) (map[string]interface{}, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// GetSchedule fetches the anime airing on a weekday, e.g. "monday", or every day when it is empty
+func (c *JikanClient) GetSchedule(day string) ([]JikanAnime, error) {
+	query := url.Values{}
+	if day != "" {
+		query.Set("filter", strings.ToLower(day))
+	}
+	anime, err := getAllPages[JikanAnime](c, "/schedules", query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GET request: %w", err)
+		return nil, fmt.Errorf("failed to fetch schedule: %w", err)
+	}
+	return anime, nil
+}
+
+// getAllPages fetches every page of a paginated endpoint
+func getAllPages[T any](c *JikanClient, path string, query url.Values) ([]T, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+
+	var items []T
+	for page := 1; page <= jikanMaxPages; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var response struct {
+			Pagination JikanPagination `json:"pagination"`
+			Data       []T             `json:"data"`
+		}
+		if err := c.get(path, query, &response); err != nil {
+			return nil, err
+		}
+
+		items = append(items, response.Data...)
+		if !response.Pagination.HasNextPage {
+			return items, nil
+		}
 	}
+	return items, nil
+}
 
-	for key, value := range headers {
-		req.Header.Set(key, value)
+// get sends a GET request to Jikan and decodes the response into result. Rate limited
+// requests are retried after the wait Jikan asks for.
+func (c *JikanClient) get(path string, query url.Values, result interface{}) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	var err error
+	for attempt := 0; attempt <= jikanRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(RetryAfter(err, jikanRetryWait))
+		}
+		err = c.send(endpoint, result)
+		if !IsRateLimited(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// send waits for the rate limit and sends a single request
+func (c *JikanClient) send(endpoint string, result interface{}) error {
+	c.wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send GET request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &RequestError{Err: err}
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed with status %d: %s", resp.StatusCode, body)
+		requestErr := &RequestError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: retryAfter(resp.Header)}
+		var jikanErr JikanError
+		if json.Unmarshal(body, &jikanErr) == nil && jikanErr.Message != "" {
+			requestErr.Err = &jikanErr
+		}
+		return requestErr
 	}
 
-	var responseData map[string]interface{}
-	err = json.Unmarshal(body, &responseData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
 	}
+	return nil
+}
+
+// wait blocks until a request can be sent without going over Jikan's rate limits
+func (c *JikanClient) wait() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		now := time.Now()
+		var until time.Time
+		if n := len(c.sent); n >= jikanPerSecond && now.Sub(c.sent[n-jikanPerSecond]) < time.Second {
+			until = c.sent[n-jikanPerSecond].Add(time.Second)
+		}
+		if n := len(c.sent); n >= jikanPerMinute && now.Sub(c.sent[n-jikanPerMinute]) < time.Minute {
+			if next := c.sent[n-jikanPerMinute].Add(time.Minute); next.After(until) {
+				until = next
+			}
+		}
+		if until.IsZero() {
+			break
+		}
+		time.Sleep(until.Sub(now))
+	}
+
+	c.sent = append(c.sent, time.Now())
+	if len(c.sent) > jikanHistorySize {
+		c.sent = c.sent[len(c.sent)-jikanHistorySize:]
+	}
+}
 
-	return responseData, nil
+// GetEpisodes fetches the metadata of every episode of an anime by its MyAnimeList ID
+func GetEpisodes(malID int) ([]Episode, error) {
+	return jikan.GetEpisodes(malID)
 }
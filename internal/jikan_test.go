@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJikanWait(t *testing.T) {
+	// A minute's worth of requests, the oldest sent the given time ago and the newest 2 seconds ago
+	fullMinute := func(oldest time.Duration) []time.Duration {
+		offsets := make([]time.Duration, jikanPerMinute)
+		for i := range offsets {
+			offsets[i] = oldest - time.Duration(i)*(oldest-2*time.Second)/time.Duration(jikanPerMinute-1)
+		}
+		return offsets
+	}
+
+	tests := []struct {
+		name string
+		sent []time.Duration // How long ago the previous requests were sent, oldest first
+		want time.Duration
+	}{
+		{"no requests yet", nil, 0},
+		{"under the per second limit", []time.Duration{500 * time.Millisecond, 100 * time.Millisecond}, 0},
+		{"per second limit", []time.Duration{800 * time.Millisecond, 500 * time.Millisecond, 100 * time.Millisecond}, 200 * time.Millisecond},
+		{"per second limit passed", []time.Duration{1500 * time.Millisecond, 1200 * time.Millisecond, 1100 * time.Millisecond}, 0},
+		{"per minute limit", fullMinute(time.Minute - 300*time.Millisecond), 300 * time.Millisecond},
+		{"per minute limit passed", fullMinute(time.Minute + time.Second), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &JikanClient{}
+			start := time.Now()
+			for _, offset := range tt.sent {
+				client.sent = append(client.sent, start.Add(-offset))
+			}
+
+			client.wait()
+			waited := time.Since(start)
+
+			// Allow for scheduling delays, but not for sending early
+			if waited < tt.want-20*time.Millisecond || waited > tt.want+150*time.Millisecond {
+				t.Errorf("wait() took %v, want about %v", waited, tt.want)
+			}
+			if want := min(len(tt.sent)+1, jikanHistorySize); len(client.sent) != want {
+				t.Errorf("history has %d requests, want %d", len(client.sent), want)
+			}
+		})
+	}
+}
+
+func TestJikanGet(t *testing.T) {
+	type reply struct {
+		status int
+		body   string
+	}
+	ok := reply{http.StatusOK, `{"data": {"mal_id": 21, "title": "One Piece"}}`}
+
+	tests := []struct {
+		name      string
+		replies   []reply
+		wantCalls int
+		wantErr   bool // Whether GetAnime returns the Jikan error
+	}{
+		{
+			name:      "success",
+			replies:   []reply{ok},
+			wantCalls: 1,
+		},
+		{
+			name:      "retried after being rate limited",
+			replies:   []reply{{http.StatusTooManyRequests, `{"status": 429, "type": "RateLimitException", "message": "You are being rate-limited."}`}, ok},
+			wantCalls: 2,
+		},
+		{
+			name:      "not found isn't retried",
+			replies:   []reply{{http.StatusNotFound, `{"status": 404, "type": "BadResponseException", "message": "Resource does not exist"}`}},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				reply := tt.replies[min(calls, len(tt.replies)-1)]
+				calls++
+				mu.Unlock()
+				w.WriteHeader(reply.status)
+				fmt.Fprint(w, reply.body)
+			}))
+			defer server.Close()
+
+			client := NewJikanClient()
+			client.baseURL = server.URL
+			anime, err := client.GetAnime(21)
+
+			if calls != tt.wantCalls {
+				t.Errorf("sent %d request(s), want %d", calls, tt.wantCalls)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("GetAnime() = %v", err)
+				}
+				if anime.MalID != 21 || anime.Title != "One Piece" {
+					t.Errorf("GetAnime() = %+v", anime)
+				}
+				return
+			}
+			var jikanErr *JikanError
+			if !errors.As(err, &jikanErr) || jikanErr.Message != "Resource does not exist" {
+				t.Errorf("GetAnime() = %v, want the Jikan error", err)
+			}
+			if IsTemporary(err) {
+				t.Errorf("IsTemporary(%v) = true, want false", err)
+			}
+		})
+	}
+}
+
+func TestJikanGetAllPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{"pagination": {"has_next_page": true}, "data": [{"mal_id": 1}, {"mal_id": 2, "filler": true}]}`)
+		case "2":
+			fmt.Fprint(w, `{"pagination": {"has_next_page": false}, "data": [{"mal_id": 3}]}`)
+		default:
+			http.Error(w, "unexpected page", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	client := NewJikanClient()
+	client.baseURL = server.URL
+	episodes, err := client.GetEpisodes(21)
+	if err != nil {
+		t.Fatalf("GetEpisodes() = %v", err)
+	}
+	if len(episodes) != 3 || episodes[2].Number != 3 || !episodes[1].Filler {
+		t.Errorf("GetEpisodes() = %+v, want episodes 1 to 3 with 2 as filler", episodes)
+	}
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// MutationJournal persists list changes that could not be sent to AniList yet,
+// so they survive restarts and can be replayed once AniList is reachable again
+type MutationJournal struct {
+	path    string
+	mu      sync.Mutex
+	entries []journalEntry
+}
+
+// journalEntry is a queued update. The revision changes every time more changes are
+// collapsed into it, so a send that raced with a newer change does not drop it.
+type journalEntry struct {
+	Update   ListEntryUpdate `json:"update"`
+	Revision int             `json:"revision"`
+}
+
+var (
+	journalMu sync.Mutex
+	journals  = make(map[string]*MutationJournal) // Opened journals by path, so each file has a single writer
+)
+
+// OpenJournal loads a profile's journal from the config directory, creating an empty one if needed.
+// A journal that is already open is shared, so clients created for the same profile don't overwrite each other's changes.
+func OpenJournal(profile string) (*MutationJournal, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	path := journalPath(configPath, profile)
+
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	if journal, ok := journals[path]; ok {
+		return journal, nil
+	}
+
+	journal := &MutationJournal{
+		path: path,
+	}
+
+	data, err := os.ReadFile(journal.path)
+	if errors.Is(err, os.ErrNotExist) {
+		journals[path] = journal
+		return journal, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &journal.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse journal: %w", err)
+	}
+
+	journals[path] = journal
+	return journal, nil
+}
+
+// Add queues an update, collapsing it into any pending update for the same anime
+func (j *MutationJournal) Add(update ListEntryUpdate) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := range j.entries {
+		if j.entries[i].Update.MediaID == update.MediaID {
+			j.entries[i].Update.Merge(update)
+			j.entries[i].Revision++
+			return j.save()
+		}
+	}
+
+	j.entries = append(j.entries, journalEntry{Update: update, Revision: 1})
+	return j.save()
+}
+
+// Merged returns the pending update for the anime with the given update applied on top of it,
+// along with the revision of the pending update, or 0 if nothing is pending
+func (j *MutationJournal) Merged(update ListEntryUpdate) (ListEntryUpdate, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, entry := range j.entries {
+		if entry.Update.MediaID == update.MediaID {
+			entry.Update.Merge(update)
+			return entry.Update, entry.Revision
+		}
+	}
+	return update, 0
+}
+
+// Resolve drops the pending update for an anime after it was sent,
+// unless more changes were queued since the given revision
+func (j *MutationJournal) Resolve(mediaID int, revision int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := range j.entries {
+		if j.entries[i].Update.MediaID == mediaID && j.entries[i].Revision == revision {
+			j.entries = append(j.entries[:i], j.entries[i+1:]...)
+			return j.save()
+		}
+	}
+	return nil
+}
+
+// Pending returns the queued updates in the order they were first queued
+func (j *MutationJournal) Pending() []ListEntryUpdate {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	updates := make([]ListEntryUpdate, len(j.entries))
+	for i, entry := range j.entries {
+		updates[i] = entry.Update
+	}
+	return updates
+}
+
+// snapshot returns a copy of the queued entries
+func (j *MutationJournal) snapshot() []journalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return append([]journalEntry(nil), j.entries...)
+}
+
+// save writes the journal to disk, replacing the previous file atomically
+func (j *MutationJournal) save() error {
+	data, err := json.MarshalIndent(j.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal: %w", err)
+	}
+
+	return writeFileAtomic(j.path, data, 0o600)
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func newTestJournal(t *testing.T, mediaIDs ...int) *MutationJournal {
+	t.Helper()
+	journal := &MutationJournal{path: filepath.Join(t.TempDir(), "journal.json")}
+	for _, id := range mediaIDs {
+		progress := id
+		if err := journal.Add(ListEntryUpdate{MediaID: id, Progress: &progress}); err != nil {
+			t.Fatalf("Add(%d): %v", id, err)
+		}
+	}
+	return journal
+}
+
+func pendingIDs(journal *MutationJournal) []int {
+	var ids []int
+	for _, update := range journal.Pending() {
+		ids = append(ids, update.MediaID)
+	}
+	return ids
+}
+
+func TestReplay(t *testing.T) {
+	offline := &RequestError{Err: errors.New("connection refused")}
+	expired := &AuthError{RequestError: &RequestError{StatusCode: 401}}
+	invalid := &RequestError{StatusCode: 400, Err: &APIError{Kind: ErrValidation, Message: "invalid progress"}}
+	missing := &RequestError{StatusCode: 404, Err: &APIError{Kind: ErrNotFound, Message: "not found"}}
+	failed := &RequestError{StatusCode: 403, Body: "forbidden"}
+
+	tests := []struct {
+		name      string
+		results   map[int]error // Result of sending each anime's change, nil when missing
+		wantTried []int
+		wantKept  []int
+		wantDrop  []int
+		wantAuth  bool
+		wantOther bool
+	}{
+		{
+			name:      "all sent",
+			wantTried: []int{1, 2, 3},
+		},
+		{
+			name:      "stops when offline",
+			results:   map[int]error{2: offline},
+			wantTried: []int{1, 2},
+			wantKept:  []int{2, 3},
+		},
+		{
+			name:      "stops when the login expired",
+			results:   map[int]error{2: expired},
+			wantTried: []int{1, 2},
+			wantKept:  []int{2, 3},
+			wantAuth:  true,
+		},
+		{
+			name:      "drops refused changes",
+			results:   map[int]error{1: missing, 2: invalid},
+			wantTried: []int{1, 2, 3},
+			wantDrop:  []int{1, 2},
+		},
+		{
+			name:      "keeps changes that failed otherwise",
+			results:   map[int]error{2: failed},
+			wantTried: []int{1, 2, 3},
+			wantKept:  []int{2},
+			wantOther: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := mutationQueue{journal: newTestJournal(t, 1, 2, 3)}
+
+			var attempted []int
+			sent, err := queue.replay(func(update ListEntryUpdate) (*MediaListEntry, error) {
+				attempted = append(attempted, update.MediaID)
+				return nil, tt.results[update.MediaID]
+			})
+
+			if !slices.Equal(attempted, tt.wantTried) {
+				t.Errorf("tried sending changes to %v, want %v", attempted, tt.wantTried)
+			}
+			wantCount := 0
+			for _, id := range tt.wantTried {
+				if tt.results[id] == nil {
+					wantCount++
+				}
+			}
+			if sent != wantCount {
+				t.Errorf("replay() = %d sent, want %d", sent, wantCount)
+			}
+			if kept := pendingIDs(queue.journal); !slices.Equal(kept, tt.wantKept) {
+				t.Errorf("kept %v queued, want %v", kept, tt.wantKept)
+			}
+
+			var dropped []int
+			var other bool
+			if err != nil {
+				for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+					var droppedErr *DroppedError
+					switch {
+					case errors.As(err, &droppedErr):
+						dropped = append(dropped, droppedErr.Update.MediaID)
+					case !IsAuthError(err):
+						other = true
+					}
+				}
+			}
+			if !slices.Equal(dropped, tt.wantDrop) {
+				t.Errorf("dropped %v, want %v", dropped, tt.wantDrop)
+			}
+			if IsAuthError(err) != tt.wantAuth {
+				t.Errorf("IsAuthError(%v) = %v, want %v", err, !tt.wantAuth, tt.wantAuth)
+			}
+			if other != tt.wantOther {
+				t.Errorf("other error in %v = %v, want %v", err, other, tt.wantOther)
+			}
+		})
+	}
+}
+
+func TestJournalResolveKeepsNewerChanges(t *testing.T) {
+	journal := newTestJournal(t, 1)
+
+	// A change queued while the first one was being sent
+	_, revision := journal.Merged(ListEntryUpdate{MediaID: 1})
+	status := "COMPLETED"
+	if err := journal.Add(ListEntryUpdate{MediaID: 1, Status: &status}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := journal.Resolve(1, revision); err != nil {
+		t.Fatal(err)
+	}
+	pending := journal.Pending()
+	if len(pending) != 1 || pending[0].Progress == nil || pending[0].Status == nil {
+		t.Fatalf("Pending() = %+v, want the merged change kept", pending)
+	}
+
+	_, revision = journal.Merged(ListEntryUpdate{MediaID: 1})
+	if err := journal.Resolve(1, revision); err != nil {
+		t.Fatal(err)
+	}
+	if pending := journal.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %+v after resolving the latest revision, want none", pending)
+	}
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Score formats supported by AniList
+const (
+	ScorePoint100       = "POINT_100"
+	ScorePoint10Decimal = "POINT_10_DECIMAL"
+	ScorePoint10        = "POINT_10"
+	ScorePoint5         = "POINT_5"
+	ScorePoint3         = "POINT_3"
+)
+
+// ListStatuses contains every status a list entry can have, in display order
+var ListStatuses = []string{"CURRENT", "PLANNING", "COMPLETED", "REPEATING", "PAUSED", "DROPPED"}
+
+// ScoreRange returns the highest score allowed by the given score format
+func ScoreRange(format string) float64 {
+	switch format {
+	case ScorePoint100:
+		return 100
+	case ScorePoint5:
+		return 5
+	case ScorePoint3:
+		return 3
+	default:
+		return 10
+	}
+}
+
+// ParseScore parses a score typed by the user and validates it against the score format
+func ParseScore(format string, value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("score must be a number")
+	}
+
+	max := ScoreRange(format)
+	if score < 0 || score > max {
+		return 0, fmt.Errorf("score must be between 0 and %g", max)
+	}
+
+	if format != ScorePoint10Decimal && score != float64(int(score)) {
+		return 0, fmt.Errorf("score must be a whole number")
+	}
+	if format == ScorePoint10Decimal && score*10 != float64(int(score*10)) {
+		return 0, fmt.Errorf("score can only have one decimal place")
+	}
+
+	return score, nil
+}
+
+// FormatScore formats a score for display in the given score format
+func FormatScore(format string, score float64) string {
+	if score == 0 {
+		return ""
+	}
+	if format == ScorePoint10Decimal {
+		return strconv.FormatFloat(score, 'f', 1, 64)
+	}
+	return strconv.Itoa(int(score))
+}
+
+// ConvertScore converts a score between score formats through the 100 point scale
+func ConvertScore(score float64, from string, to string) float64 {
+	if score == 0 || from == to {
+		return score
+	}
+
+	// Smiley scores follow the values AniList uses for them
+	var point100 float64
+	switch from {
+	case ScorePoint3:
+		point100 = []float64{0, 35, 60, 85}[int(math.Min(math.Max(score, 1), 3))]
+	default:
+		point100 = score * 100 / ScoreRange(from)
+	}
+
+	var converted float64
+	switch to {
+	case ScorePoint3:
+		switch {
+		case point100 <= 40:
+			converted = 1
+		case point100 <= 70:
+			converted = 2
+		default:
+			converted = 3
+		}
+	case ScorePoint10Decimal:
+		converted = math.Round(point100) / 10
+	default:
+		converted = math.Round(point100 * ScoreRange(to) / 100)
+	}
+
+	// Keep a rated entry rated
+	if converted == 0 {
+		converted = 1
+		if to == ScorePoint10Decimal {
+			converted = 0.1
+		}
+	}
+	return converted
+}
+
+// ParseDate parses a date in YYYY, YYYY-MM or YYYY-MM-DD form
+func ParseDate(value string) (Date, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Date{}, nil
+	}
+
+	parts := strings.Split(value, "-")
+	if len(parts) > 3 {
+		return Date{}, fmt.Errorf("date must be in YYYY-MM-DD form")
+	}
+
+	fields := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return Date{}, fmt.Errorf("date must be in YYYY-MM-DD form")
+		}
+		fields[i] = n
+	}
+
+	date := Date{Year: fields[0], Month: fields[1], Day: fields[2]}
+	if date.Year < 1900 || date.Year > 9999 {
+		return Date{}, fmt.Errorf("year must be between 1900 and 9999")
+	}
+	if len(parts) > 1 && (date.Month < 1 || date.Month > 12) {
+		return Date{}, fmt.Errorf("month must be between 1 and 12")
+	}
+	if len(parts) > 2 && (date.Day < 1 || date.Day > 31) {
+		return Date{}, fmt.Errorf("day must be between 1 and 31")
+	}
+	// time.Date normalizes days past the end of the month, e.g. Feb 31 to Mar 3
+	if len(parts) > 2 && time.Date(date.Year, time.Month(date.Month), date.Day, 0, 0, 0, 0, time.UTC).Day() != date.Day {
+		return Date{}, fmt.Errorf("%s has no day %d", time.Month(date.Month), date.Day)
+	}
+
+	return date, nil
+}
+
+// FormatDate formats a date as YYYY-MM-DD, leaving out the parts that are not set
+func FormatDate(date Date) string {
+	if date.IsEmpty() {
+		return ""
+	}
+	if date.Month == 0 {
+		return fmt.Sprintf("%04d", date.Year)
+	}
+	if date.Day == 0 {
+		return fmt.Sprintf("%04d-%02d", date.Year, date.Month)
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", date.Year, date.Month, date.Day)
+}
+
+// DateOf returns the calendar date of a time
+func DateOf(t time.Time) Date {
+	return Date{Year: t.Year(), Month: int(t.Month()), Day: t.Day()}
+}
@@ -0,0 +1,575 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	malAPIURL      = "https://api.myanimelist.net/v2"
+	malAuthURL     = "https://myanimelist.net/v1/oauth2/authorize"
+	malTokenURL    = "https://myanimelist.net/v1/oauth2/token"
+	malClientIDEnv = "ANIVIEW_MAL_CLIENT_ID"
+	malListFields  = "list_status{status,score,num_episodes_watched,is_rewatching,num_times_rewatched,comments,start_date,finish_date,updated_at}"
+)
+
+// malStatuses maps AniList list statuses to MyAnimeList ones. REPEATING is a completed
+// entry that is being rewatched on MyAnimeList.
+var malStatuses = map[string]string{
+	"CURRENT":   "watching",
+	"PLANNING":  "plan_to_watch",
+	"COMPLETED": "completed",
+	"REPEATING": "completed",
+	"PAUSED":    "on_hold",
+	"DROPPED":   "dropped",
+}
+
+// MALClient keeps the user's list on MyAnimeList. Entries are matched to AniList media
+// through idMal so the rest of aniview can keep using AniList IDs.
+type MALClient struct {
+	httpClient *http.Client
+	anilist    *AniListClient // Used to look up media by idMal
+
+	tokenMu   sync.Mutex
+	profile   Config                                        // Copy of the profile with the tokens the client uses
+	onRefresh func(profile string, token Credentials) error // Saves refreshed tokens
+
+	idMu   sync.Mutex
+	malIDs map[int]int // AniList media ID to MyAnimeList ID
+	mutationQueue
+}
+
+// malToken represents the response from the MyAnimeList token endpoint
+type malToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// malListEntry represents an entry of the user's MyAnimeList anime list
+type malListEntry struct {
+	Node struct {
+		ID          int    `json:"id"`
+		Title       string `json:"title"`
+		NumEpisodes int    `json:"num_episodes"`
+	} `json:"node"`
+	ListStatus malListStatus `json:"list_status"`
+}
+
+// malListStatus represents the user's list status for an anime on MyAnimeList
+type malListStatus struct {
+	Status             string `json:"status"`
+	Score              int    `json:"score"`
+	NumEpisodesWatched int    `json:"num_episodes_watched"`
+	IsRewatching       bool   `json:"is_rewatching"`
+	NumTimesRewatched  int    `json:"num_times_rewatched"`
+	Comments           string `json:"comments"`
+	StartDate          string `json:"start_date"`
+	FinishDate         string `json:"finish_date"`
+	UpdatedAt          string `json:"updated_at"`
+}
+
+// NewMALClient creates a MyAnimeList client for a profile. The AniList client is used to
+// look up media and may be unauthenticated.
+func NewMALClient(config *Config, anilist *AniListClient) *MALClient {
+	if anilist == nil {
+		anilist = NewAniListClient("")
+	}
+	return &MALClient{
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		anilist:   anilist,
+		profile:   *config,
+		onRefresh: saveRefreshedToken,
+		malIDs:    make(map[int]int),
+	}
+}
+
+// OnTokenRefresh sets what saves the tokens the client refreshes, instead of writing them to
+// the config file. The client keeps using the new tokens either way.
+func (c *MALClient) OnTokenRefresh(save func(profile string, token Credentials) error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.onRefresh = save
+}
+
+// Name returns the name of the service shown to the user
+func (c *MALClient) Name() string {
+	return "MyAnimeList"
+}
+
+// UpdateUserInfo fetches user information from MyAnimeList and updates the config
+func (c *MALClient) UpdateUserInfo(config *Config) error {
+	if err := c.loadViewer(config); err != nil {
+		return err
+	}
+
+	// Save the updated config
+	if err := SaveConfig(config); err != nil {
+		return fmt.Errorf("failed to save updated config: %w", err)
+	}
+	return nil
+}
+
+// loadViewer fills in the config with the user the client is logged in as
+func (c *MALClient) loadViewer(config *Config) error {
+	var user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := c.doRequest("GET", malAPIURL+"/users/@me", nil, &user); err != nil {
+		return fmt.Errorf("failed to fetch user info: %w", err)
+	}
+
+	config.Username = user.Name
+	config.UserID = user.ID
+	return nil
+}
+
+// GetPlanned fetches the user's planned anime list
+func (c *MALClient) GetPlanned(userID int) ([]AnimeEntry, error) {
+	return c.getAnimeList("PLANNING")
+}
+
+// GetCurrentlyWatching fetches the user's currently watching anime list, including rewatches
+func (c *MALClient) GetCurrentlyWatching(userID int) ([]AnimeEntry, error) {
+	return c.getAnimeList("CURRENT", "REPEATING")
+}
+
+// GetCompleted fetches the user's completed anime list
+func (c *MALClient) GetCompleted(userID int) ([]AnimeEntry, error) {
+	return c.getAnimeList("COMPLETED")
+}
+
+// GetList fetches the user's anime list with any of the specified statuses
+func (c *MALClient) GetList(userID int, statuses ...string) ([]AnimeEntry, error) {
+	return c.getAnimeList(statuses...)
+}
+
+// GetScoreFormat returns the score format of MyAnimeList, which only has whole scores out of 10
+func (c *MALClient) GetScoreFormat() (string, error) {
+	return ScorePoint10, nil
+}
+
+// getAnimeList fetches the user's anime list with any of the specified statuses and
+// matches the entries to AniList media. Entries missing from AniList are left out.
+func (c *MALClient) getAnimeList(statuses ...string) ([]AnimeEntry, error) {
+	// COMPLETED and REPEATING share a status on MyAnimeList
+	malStatusSet := make(map[string]bool)
+	for _, status := range statuses {
+		malStatusSet[malStatuses[status]] = true
+	}
+
+	var listEntries []malListEntry
+	for malStatus := range malStatusSet {
+		entries, err := c.fetchList(malStatus)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch anime list with status %s: %w", strings.Join(statuses, "/"), err)
+		}
+		for _, entry := range entries {
+			for _, status := range statuses {
+				if entry.ListStatus.aniListStatus() == status {
+					listEntries = append(listEntries, entry)
+					break
+				}
+			}
+		}
+	}
+
+	malIDs := make([]int, len(listEntries))
+	for i, entry := range listEntries {
+		malIDs[i] = entry.Node.ID
+	}
+	media, err := c.anilist.GetMediaByMalIDs(malIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var animeList []AnimeEntry
+	c.idMu.Lock()
+	defer c.idMu.Unlock()
+	for _, entry := range listEntries {
+		m, ok := media[entry.Node.ID]
+		if !ok {
+			continue
+		}
+		c.malIDs[m.ID] = entry.Node.ID
+
+		anime := newAnimeEntry(m)
+		anime.ApplyListEntry(entry.ListStatus.mediaListEntry())
+		animeList = append(animeList, anime)
+	}
+
+	return animeList, nil
+}
+
+// fetchList fetches every entry of the user's list with the given MyAnimeList status
+func (c *MALClient) fetchList(status string) ([]malListEntry, error) {
+	params := url.Values{
+		"status": {status},
+		"fields": {malListFields},
+		"limit":  {"1000"},
+		"nsfw":   {"true"},
+	}
+	next := malAPIURL + "/users/@me/animelist?" + params.Encode()
+
+	var entries []malListEntry
+	for next != "" {
+		var page struct {
+			Data   []malListEntry `json:"data"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if err := c.doRequest("GET", next, nil, &page); err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Data...)
+		next = page.Paging.Next
+	}
+
+	return entries, nil
+}
+
+// SaveEntry saves a list entry and returns it as stored by MyAnimeList. If MyAnimeList cannot be
+// reached and a journal is set, the change is queued and an error wrapping ErrQueued is returned.
+func (c *MALClient) SaveEntry(update ListEntryUpdate) (*MediaListEntry, error) {
+	return c.save(update, c.saveEntry)
+}
+
+// SaveEntries saves many list entries. MyAnimeList has no batch requests, so each is sent on its own.
+func (c *MALClient) SaveEntries(updates []ListEntryUpdate) ([]*MediaListEntry, []error) {
+	return c.saveAll(updates, saveEach(c.saveEntry))
+}
+
+// ReplayPending sends the queued list changes to MyAnimeList in order
+func (c *MALClient) ReplayPending() (int, error) {
+	return c.replay(c.saveEntry)
+}
+
+// saveEntry updates the list status on MyAnimeList. Private and hidden entries have no
+// equivalent on MyAnimeList and are ignored.
+func (c *MALClient) saveEntry(update ListEntryUpdate) (*MediaListEntry, error) {
+	malID, err := c.malID(update.MediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	if update.Status != nil {
+		form.Set("status", malStatuses[*update.Status])
+		form.Set("is_rewatching", strconv.FormatBool(*update.Status == "REPEATING"))
+	}
+	if update.Score != nil {
+		form.Set("score", strconv.Itoa(int(math.Round(*update.Score))))
+	}
+	if update.Progress != nil {
+		form.Set("num_watched_episodes", strconv.Itoa(*update.Progress))
+	}
+	if update.Repeat != nil {
+		form.Set("num_times_rewatched", strconv.Itoa(*update.Repeat))
+	}
+	if update.Notes != nil {
+		form.Set("comments", *update.Notes)
+	}
+	if update.StartedAt != nil {
+		form.Set("start_date", FormatDate(*update.StartedAt))
+	}
+	if update.CompletedAt != nil {
+		form.Set("finish_date", FormatDate(*update.CompletedAt))
+	}
+
+	var status malListStatus
+	endpoint := fmt.Sprintf("%s/anime/%d/my_list_status", malAPIURL, malID)
+	if err := c.doRequest("PATCH", endpoint, form, &status); err != nil {
+		return nil, fmt.Errorf("failed to update anime (mediaID: %d): %w", update.MediaID, err)
+	}
+
+	entry := status.mediaListEntry()
+	return &entry, nil
+}
+
+// malID returns the MyAnimeList ID of an AniList media
+func (c *MALClient) malID(mediaID int) (int, error) {
+	c.idMu.Lock()
+	malID, ok := c.malIDs[mediaID]
+	c.idMu.Unlock()
+	if ok {
+		return malID, nil
+	}
+
+	malID, err := c.anilist.GetMalID(mediaID)
+	if err != nil {
+		return 0, err
+	}
+	if malID == 0 {
+		return 0, &APIError{Kind: ErrNotFound, Message: fmt.Sprintf("anime %d is not on MyAnimeList", mediaID)}
+	}
+
+	c.idMu.Lock()
+	c.malIDs[mediaID] = malID
+	c.idMu.Unlock()
+	return malID, nil
+}
+
+// doRequest sends an authenticated request, refreshing the token once if MyAnimeList rejects it
+func (c *MALClient) doRequest(method, endpoint string, form url.Values, result interface{}) error {
+	err := c.sendRequest(method, endpoint, form, result)
+	if !IsAuthError(err) {
+		return err
+	}
+
+	if refreshErr := c.refreshToken(); refreshErr != nil {
+		// A refresh that could not reach MyAnimeList may work later, so the change is kept
+		if IsTemporary(refreshErr) {
+			return refreshErr
+		}
+		return err
+	}
+	return c.sendRequest(method, endpoint, form, result)
+}
+
+// sendRequest sends a single request to the MyAnimeList API
+func (c *MALClient) sendRequest(method, endpoint string, form url.Values, result interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	c.tokenMu.Lock()
+	req.Header.Set("Authorization", "Bearer "+c.profile.Token)
+	c.tokenMu.Unlock()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &RequestError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return &AuthError{&RequestError{StatusCode: resp.StatusCode, Body: string(respBody)}}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &RequestError{StatusCode: resp.StatusCode, Body: string(respBody), Err: malAPIError(resp.StatusCode, respBody), RetryAfter: retryAfter(resp.Header)}
+	}
+
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// malAPIError reads the error MyAnimeList explains a refused request with, marking it as not
+// found or invalid so queued changes it refuses are dropped. Other statuses return nil.
+func malAPIError(status int, body []byte) error {
+	var kind error
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		kind = ErrValidation
+	case http.StatusNotFound:
+		kind = ErrNotFound
+	default:
+		return nil
+	}
+
+	var response struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	json.Unmarshal(body, &response)
+	message := response.Message
+	if message == "" {
+		message = response.Error
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &APIError{Kind: kind, Message: "MyAnimeList: " + message, Status: status}
+}
+
+// refreshToken gets a new access token with the refresh token and saves it in the profile
+func (c *MALClient) refreshToken() error {
+	c.tokenMu.Lock()
+	err := c.renewToken()
+	profile, credentials := c.profile.Profile, c.profile.Credentials()
+	c.tokenMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// The callback may wait on the UI, which may be waiting on the token lock
+	return c.onRefresh(profile, credentials)
+}
+
+// renewToken requests a new access token and sets it on the client. The caller holds tokenMu.
+func (c *MALClient) renewToken() error {
+	if c.profile.RefreshToken == "" {
+		return fmt.Errorf("no refresh token")
+	}
+	clientID, err := malClientID(&c.profile)
+	if err != nil {
+		return err
+	}
+
+	token, err := requestMALToken(url.Values{
+		"client_id":     {clientID},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {c.profile.RefreshToken},
+	})
+	if err != nil {
+		return err
+	}
+
+	c.profile.setMALToken(token)
+	return nil
+}
+
+// saveRefreshedToken stores refreshed tokens in a profile in the config file, leaving its other settings alone
+func saveRefreshedToken(profile string, token Credentials) error {
+	profiles, err := LoadProfiles()
+	if err != nil {
+		return err
+	}
+	config, ok := profiles.Profiles[profile]
+	if !ok {
+		return fmt.Errorf("profile %s no longer exists", profile)
+	}
+	config.SetCredentials(token)
+	return profiles.Save()
+}
+
+// aniListStatus returns the AniList status of a MyAnimeList list status
+func (s malListStatus) aniListStatus() string {
+	if s.IsRewatching {
+		return "REPEATING"
+	}
+	for status, malStatus := range malStatuses {
+		if malStatus == s.Status && status != "REPEATING" {
+			return status
+		}
+	}
+	return ""
+}
+
+// mediaListEntry converts a MyAnimeList list status to an AniList list entry
+func (s malListStatus) mediaListEntry() MediaListEntry {
+	startedAt, _ := ParseDate(s.StartDate)
+	completedAt, _ := ParseDate(s.FinishDate)
+
+	entry := MediaListEntry{
+		Status:      s.aniListStatus(),
+		Score:       float64(s.Score),
+		Progress:    s.NumEpisodesWatched,
+		Repeat:      s.NumTimesRewatched,
+		Notes:       s.Comments,
+		StartedAt:   startedAt,
+		CompletedAt: completedAt,
+	}
+	if updatedAt, err := time.Parse(time.RFC3339, s.UpdatedAt); err == nil {
+		entry.UpdatedAt = int(updatedAt.Unix())
+	}
+	return entry
+}
+
+// setMALToken stores MyAnimeList tokens in the config
+func (c *Config) setMALToken(token malToken) {
+	c.Token = token.AccessToken
+	c.RefreshToken = token.RefreshToken
+	c.TokenExpiresAt = time.Now().Unix() + token.ExpiresIn
+}
+
+// malClientID returns the client ID of the MyAnimeList API app from the config or the environment
+func malClientID(config *Config) (string, error) {
+	if config.MALClientID != "" {
+		return config.MALClientID, nil
+	}
+	if clientID := os.Getenv(malClientIDEnv); clientID != "" {
+		return clientID, nil
+	}
+	return "", fmt.Errorf("set %s or mal_client_id to the client ID of your MyAnimeList API app", malClientIDEnv)
+}
+
+// malAuthorizeURL returns the MyAnimeList URL that asks the user to authorize aniview.
+// MyAnimeList only supports the plain PKCE challenge, so the verifier is sent as is.
+func malAuthorizeURL(clientID, verifier, state string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"code_challenge":        {verifier},
+		"code_challenge_method": {"plain"},
+		"state":                 {state},
+		"redirect_uri":          {"http://" + callbackAddr + callbackPath},
+	}
+	return malAuthURL + "?" + params.Encode()
+}
+
+// exchangeMALCode exchanges an authorization code for tokens
+func exchangeMALCode(clientID, code, verifier string) (malToken, error) {
+	return requestMALToken(url.Values{
+		"client_id":     {clientID},
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"code_verifier": {verifier},
+		"redirect_uri":  {"http://" + callbackAddr + callbackPath},
+	})
+}
+
+// requestMALToken sends a request to the MyAnimeList token endpoint
+func requestMALToken(form url.Values) (malToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", malTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return malToken{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return malToken{}, &RequestError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return malToken{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return malToken{}, &RequestError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var token malToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return malToken{}, fmt.Errorf("failed to parse token: %w", err)
+	}
+	return token, nil
+}
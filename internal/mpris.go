@@ -0,0 +1,365 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	mprisName          = "org.mpris.MediaPlayer2.aniview"
+	mprisPath          = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	mprisRootIface     = "org.mpris.MediaPlayer2"
+	mprisPlayerIface   = "org.mpris.MediaPlayer2.Player"
+	propertiesIface    = "org.freedesktop.DBus.Properties"
+	mprisNoTrack       = dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")
+	mprisSeekTolerance = time.Second // Position jumps larger than this are reported as seeks
+)
+
+// MPRIS publishes the episode being played as an MPRIS2 media player on D-Bus, so desktop
+// widgets and media keys can show and control it. Controls are sent to the player's IPC socket.
+type MPRIS struct {
+	conn   *dbus.Conn
+	socket string // The player's IPC socket
+
+	mu       sync.Mutex
+	playing  bool // Whether an episode is open in the player
+	anime    AnimeEntry
+	state    PlaybackState
+	reported time.Time // When state was reported
+}
+
+// StartMPRIS publishes the now playing service on the session bus. It is only available on Linux.
+func StartMPRIS() (*MPRIS, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("MPRIS is only available on Linux")
+	}
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+	m, err := NewMPRIS(conn, socketPath)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewMPRIS publishes the now playing service on a bus connection, such as a private session bus,
+// controlling the player through the given IPC socket
+func NewMPRIS(conn *dbus.Conn, ipcSocketPath string) (*MPRIS, error) {
+	m := &MPRIS{conn: conn, socket: ipcSocketPath}
+
+	root := mprisRoot{m}
+	player := mprisPlayer{m}
+	properties := mprisProperties{m}
+	playerMethods := introspect.Methods(player)
+	for i := range playerMethods {
+		if newName, ok := mprisPlayerMethods[playerMethods[i].Name]; ok {
+			playerMethods[i].Name = newName
+		}
+	}
+	node := &introspect.Node{
+		Name: string(mprisPath),
+		Interfaces: []introspect.Interface{
+			{Name: mprisRootIface, Methods: introspect.Methods(root), Properties: mprisRootProperties},
+			{
+				Name:       mprisPlayerIface,
+				Methods:    playerMethods,
+				Properties: mprisPlayerProperties,
+				Signals:    []introspect.Signal{{Name: "Seeked", Args: []introspect.Arg{{Name: "Position", Type: "x"}}}},
+			},
+			{Name: propertiesIface, Methods: introspect.Methods(properties)},
+		},
+	}
+
+	exports := []struct {
+		v       interface{}
+		mapping map[string]string
+		iface   string
+	}{
+		{root, nil, mprisRootIface},
+		{player, mprisPlayerMethods, mprisPlayerIface},
+		{properties, nil, propertiesIface},
+		{introspect.NewIntrospectable(node), nil, "org.freedesktop.DBus.Introspectable"},
+	}
+	for _, export := range exports {
+		if err := conn.ExportWithMap(export.v, export.mapping, mprisPath, export.iface); err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", export.iface, err)
+		}
+	}
+
+	reply, err := conn.RequestName(mprisName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request %s: %w", mprisName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("%s is taken, is another aniview running?", mprisName)
+	}
+	return m, nil
+}
+
+// Playing publishes the episode being played
+func (m *MPRIS) Playing(anime AnimeEntry, state PlaybackState) {
+	m.mu.Lock()
+	changed := !m.playing || m.anime.ID != anime.ID || m.anime.CurrentEpisode != anime.CurrentEpisode || m.state.Duration != state.Duration
+	paused := !m.playing || m.state.Paused != state.Paused
+	seeked := !changed && !paused && absDuration(m.position()-state.Position) > mprisSeekTolerance
+
+	m.playing = true
+	m.anime = anime
+	m.state = state
+	m.reported = time.Now()
+	properties := m.properties()
+	m.mu.Unlock()
+
+	changes := make(map[string]dbus.Variant)
+	if changed {
+		changes["Metadata"] = properties["Metadata"]
+	}
+	if changed || paused {
+		changes["PlaybackStatus"] = properties["PlaybackStatus"]
+	}
+	m.emitChanged(changes)
+	if seeked {
+		m.conn.Emit(mprisPath, mprisPlayerIface+".Seeked", state.Position.Microseconds())
+	}
+}
+
+// Stopped publishes that nothing is playing
+func (m *MPRIS) Stopped() {
+	m.mu.Lock()
+	m.playing = false
+	m.state = PlaybackState{}
+	properties := m.properties()
+	m.mu.Unlock()
+
+	m.emitChanged(map[string]dbus.Variant{
+		"PlaybackStatus": properties["PlaybackStatus"],
+		"Metadata":       properties["Metadata"],
+	})
+}
+
+// Close removes the service from the bus
+func (m *MPRIS) Close() error {
+	return m.conn.Close()
+}
+
+// emitChanged signals changed player properties
+func (m *MPRIS) emitChanged(changes map[string]dbus.Variant) {
+	if len(changes) > 0 {
+		m.conn.Emit(mprisPath, propertiesIface+".PropertiesChanged", mprisPlayerIface, changes, []string{})
+	}
+}
+
+// position extrapolates the playback position from the last reported state. The caller holds mu.
+func (m *MPRIS) position() time.Duration {
+	if !m.playing {
+		return 0
+	}
+	if m.state.Paused {
+		return m.state.Position
+	}
+	return m.state.Position + time.Since(m.reported)
+}
+
+// trackID identifies the episode being played. The caller holds mu.
+func (m *MPRIS) trackID() dbus.ObjectPath {
+	if !m.playing {
+		return mprisNoTrack
+	}
+	return dbus.ObjectPath(fmt.Sprintf("/org/aniview/episode/%d_%d", m.anime.ID, m.anime.CurrentEpisode))
+}
+
+// properties returns the player interface's properties. The caller holds mu.
+func (m *MPRIS) properties() map[string]dbus.Variant {
+	status := "Stopped"
+	metadata := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(m.trackID()),
+	}
+	if m.playing {
+		status = "Playing"
+		if m.state.Paused {
+			status = "Paused"
+		}
+		metadata["xesam:title"] = dbus.MakeVariant(fmt.Sprintf("Episode %d", m.anime.CurrentEpisode))
+		metadata["xesam:album"] = dbus.MakeVariant(m.anime.Title)
+		metadata["xesam:artist"] = dbus.MakeVariant([]string{m.anime.Title})
+		metadata["xesam:trackNumber"] = dbus.MakeVariant(int32(m.anime.CurrentEpisode))
+		if m.anime.CoverImage != "" {
+			metadata["mpris:artUrl"] = dbus.MakeVariant(m.anime.CoverImage)
+		}
+		if m.state.Duration > 0 {
+			metadata["mpris:length"] = dbus.MakeVariant(m.state.Duration.Microseconds())
+		}
+	}
+
+	return map[string]dbus.Variant{
+		"PlaybackStatus": dbus.MakeVariant(status),
+		"Rate":           dbus.MakeVariant(1.0),
+		"MinimumRate":    dbus.MakeVariant(1.0),
+		"MaximumRate":    dbus.MakeVariant(1.0),
+		"Volume":         dbus.MakeVariant(1.0),
+		"Metadata":       dbus.MakeVariant(metadata),
+		"Position":       dbus.MakeVariant(m.position().Microseconds()),
+		"CanGoNext":      dbus.MakeVariant(false),
+		"CanGoPrevious":  dbus.MakeVariant(false),
+		"CanPlay":        dbus.MakeVariant(m.playing),
+		"CanPause":       dbus.MakeVariant(m.playing),
+		"CanSeek":        dbus.MakeVariant(m.playing),
+		"CanControl":     dbus.MakeVariant(true),
+	}
+}
+
+// send sends a command to the player, doing nothing when nothing is playing
+func (m *MPRIS) send(command ...interface{}) *dbus.Error {
+	m.mu.Lock()
+	playing := m.playing
+	m.mu.Unlock()
+	if !playing {
+		return nil
+	}
+
+	if _, err := MPVSendCommand(m.socket, command); err != nil {
+		return dbus.MakeFailedError(fmt.Errorf("failed to control the player: %w", err))
+	}
+	return nil
+}
+
+// mprisRoot implements the org.mpris.MediaPlayer2 interface
+type mprisRoot struct{ m *MPRIS }
+
+// Raise does nothing, the player window belongs to the player
+func (r mprisRoot) Raise() *dbus.Error { return nil }
+
+// Quit does nothing, aniview is quit from the terminal
+func (r mprisRoot) Quit() *dbus.Error { return nil }
+
+var mprisRootProperties = []introspect.Property{
+	{Name: "CanQuit", Type: "b", Access: "read"},
+	{Name: "CanRaise", Type: "b", Access: "read"},
+	{Name: "HasTrackList", Type: "b", Access: "read"},
+	{Name: "Identity", Type: "s", Access: "read"},
+	{Name: "SupportedUriSchemes", Type: "as", Access: "read"},
+	{Name: "SupportedMimeTypes", Type: "as", Access: "read"},
+}
+
+func (r mprisRoot) properties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"CanQuit":             dbus.MakeVariant(false),
+		"CanRaise":            dbus.MakeVariant(false),
+		"HasTrackList":        dbus.MakeVariant(false),
+		"Identity":            dbus.MakeVariant("aniview"),
+		"SupportedUriSchemes": dbus.MakeVariant([]string{}),
+		"SupportedMimeTypes":  dbus.MakeVariant([]string{}),
+	}
+}
+
+// mprisPlayer implements the org.mpris.MediaPlayer2.Player interface
+type mprisPlayer struct{ m *MPRIS }
+
+// mprisPlayerMethods maps the player's Go method names to D-Bus names where they differ
+var mprisPlayerMethods = map[string]string{"SeekBy": "Seek"}
+
+func (p mprisPlayer) Next() *dbus.Error     { return nil }
+func (p mprisPlayer) Previous() *dbus.Error { return nil }
+func (p mprisPlayer) Pause() *dbus.Error    { return p.m.send("set_property", "pause", true) }
+func (p mprisPlayer) Play() *dbus.Error     { return p.m.send("set_property", "pause", false) }
+func (p mprisPlayer) PlayPause() *dbus.Error {
+	return p.m.send("cycle", "pause")
+}
+func (p mprisPlayer) Stop() *dbus.Error { return p.m.send("stop") }
+
+// SeekBy moves the position by an offset in microseconds. It is exported as Seek, which
+// go vet reserves for io.Seeker.
+func (p mprisPlayer) SeekBy(offset int64) *dbus.Error {
+	return p.m.send("seek", float64(offset)/1e6, "relative")
+}
+
+// SetPosition moves to a position in microseconds, if the track is still playing
+func (p mprisPlayer) SetPosition(trackID dbus.ObjectPath, position int64) *dbus.Error {
+	p.m.mu.Lock()
+	current := p.m.trackID()
+	p.m.mu.Unlock()
+	if trackID != current || position < 0 {
+		return nil
+	}
+	return p.m.send("seek", float64(position)/1e6, "absolute")
+}
+
+// OpenUri isn't supported, episodes are picked in aniview
+func (p mprisPlayer) OpenUri(uri string) *dbus.Error {
+	return dbus.MakeFailedError(errors.New("opening URIs isn't supported"))
+}
+
+var mprisPlayerProperties = []introspect.Property{
+	{Name: "PlaybackStatus", Type: "s", Access: "read"},
+	{Name: "Rate", Type: "d", Access: "read"},
+	{Name: "MinimumRate", Type: "d", Access: "read"},
+	{Name: "MaximumRate", Type: "d", Access: "read"},
+	{Name: "Volume", Type: "d", Access: "read"},
+	{Name: "Metadata", Type: "a{sv}", Access: "read"},
+	{Name: "Position", Type: "x", Access: "read"},
+	{Name: "CanGoNext", Type: "b", Access: "read"},
+	{Name: "CanGoPrevious", Type: "b", Access: "read"},
+	{Name: "CanPlay", Type: "b", Access: "read"},
+	{Name: "CanPause", Type: "b", Access: "read"},
+	{Name: "CanSeek", Type: "b", Access: "read"},
+	{Name: "CanControl", Type: "b", Access: "read"},
+}
+
+// mprisProperties implements org.freedesktop.DBus.Properties. The position is read from the
+// player so it is exact, the other properties come from the last reported state.
+type mprisProperties struct{ m *MPRIS }
+
+func (p mprisProperties) Get(iface string, name string) (dbus.Variant, *dbus.Error) {
+	all, err := p.GetAll(iface)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	value, ok := all[name]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{name})
+	}
+	return value, nil
+}
+
+func (p mprisProperties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	switch iface {
+	case mprisRootIface:
+		return mprisRoot{p.m}.properties(), nil
+	case mprisPlayerIface:
+		p.m.mu.Lock()
+		properties := p.m.properties()
+		playing := p.m.playing
+		p.m.mu.Unlock()
+
+		if playing {
+			if seconds, err := MPVSendCommand(p.m.socket, []interface{}{"get_property", "time-pos"}); err == nil {
+				if seconds, ok := seconds.(float64); ok {
+					properties["Position"] = dbus.MakeVariant(int64(seconds * 1e6))
+				}
+			}
+		}
+		return properties, nil
+	}
+	return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []interface{}{iface})
+}
+
+func (p mprisProperties) Set(iface string, name string, value dbus.Variant) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{name})
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
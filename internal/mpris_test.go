@@ -0,0 +1,359 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const testBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-BUS Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=%s</listen>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startTestBus starts a private bus, so the tests don't touch the desktop's session bus
+func startTestBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon isn't installed")
+	}
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(config, []byte(fmt.Sprintf(testBusConfig, dir)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+config, "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read the bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// connectTestBus opens a connection to the private bus
+func connectTestBus(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("failed to connect to the bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// fakePlayer answers commands on an IPC socket like mpv, recording them
+type fakePlayer struct {
+	socket string
+
+	mu       sync.Mutex
+	commands [][]interface{}
+}
+
+func startFakePlayer(t *testing.T) *fakePlayer {
+	t.Helper()
+	p := &fakePlayer{socket: filepath.Join(t.TempDir(), "mpv.sock")}
+	listener, err := net.Listen("unix", p.socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+func (p *fakePlayer) serve(conn net.Conn) {
+	defer conn.Close()
+	var request struct {
+		Command []interface{} `json:"command"`
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil || json.Unmarshal(line, &request) != nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.commands = append(p.commands, request.Command)
+	p.mu.Unlock()
+
+	var data interface{}
+	if reflect.DeepEqual(request.Command, []interface{}{"get_property", "time-pos"}) {
+		data = 42.5
+	}
+	response, _ := json.Marshal(map[string]interface{}{"data": data, "error": "success"})
+	conn.Write(append(response, '\n'))
+}
+
+// takeCommands returns the commands received since the last call
+func (p *fakePlayer) takeCommands() [][]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	commands := p.commands
+	p.commands = nil
+	return commands
+}
+
+func TestMPRISProperties(t *testing.T) {
+	address := startTestBus(t)
+	player := startFakePlayer(t)
+	m, err := NewMPRIS(connectTestBus(t, address), player.socket)
+	if err != nil {
+		t.Fatalf("NewMPRIS() = %v", err)
+	}
+	object := connectTestBus(t, address).Object(mprisName, mprisPath)
+
+	anime := AnimeEntry{ID: 21, Title: "One Piece", CurrentEpisode: 3, CoverImage: "https://example.com/cover.jpg"}
+	tests := []struct {
+		name         string
+		update       func()
+		wantStatus   string
+		wantTrack    dbus.ObjectPath
+		wantTitle    string
+		wantPosition int64 // Microseconds, 0 to skip checking
+	}{
+		{
+			name:       "nothing played yet",
+			update:     func() {},
+			wantStatus: "Stopped",
+			wantTrack:  mprisNoTrack,
+		},
+		{
+			name:         "playing",
+			update:       func() { m.Playing(anime, PlaybackState{Position: 40 * time.Second, Duration: 24 * time.Minute}) },
+			wantStatus:   "Playing",
+			wantTrack:    "/org/aniview/episode/21_3",
+			wantTitle:    "Episode 3",
+			wantPosition: 42500000, // Read from the player
+		},
+		{
+			name: "paused",
+			update: func() {
+				m.Playing(anime, PlaybackState{Position: 41 * time.Second, Duration: 24 * time.Minute, Paused: true})
+			},
+			wantStatus: "Paused",
+			wantTrack:  "/org/aniview/episode/21_3",
+			wantTitle:  "Episode 3",
+		},
+		{
+			name:       "stopped",
+			update:     m.Stopped,
+			wantStatus: "Stopped",
+			wantTrack:  mprisNoTrack,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.update()
+
+			var properties map[string]dbus.Variant
+			if err := object.Call(propertiesIface+".GetAll", 0, mprisPlayerIface).Store(&properties); err != nil {
+				t.Fatalf("GetAll() = %v", err)
+			}
+			if status := properties["PlaybackStatus"].Value(); status != tt.wantStatus {
+				t.Errorf("PlaybackStatus = %v, want %s", status, tt.wantStatus)
+			}
+
+			metadata, ok := properties["Metadata"].Value().(map[string]dbus.Variant)
+			if !ok {
+				t.Fatalf("Metadata = %v, want a dictionary", properties["Metadata"])
+			}
+			if track := metadata["mpris:trackid"].Value(); track != tt.wantTrack {
+				t.Errorf("mpris:trackid = %v, want %s", track, tt.wantTrack)
+			}
+			if tt.wantTitle != "" {
+				if title := metadata["xesam:title"].Value(); title != tt.wantTitle {
+					t.Errorf("xesam:title = %v, want %s", title, tt.wantTitle)
+				}
+				if album := metadata["xesam:album"].Value(); album != anime.Title {
+					t.Errorf("xesam:album = %v, want %s", album, anime.Title)
+				}
+				if length := metadata["mpris:length"].Value(); length != (24 * time.Minute).Microseconds() {
+					t.Errorf("mpris:length = %v, want %d", length, (24 * time.Minute).Microseconds())
+				}
+			} else if _, ok := metadata["xesam:title"]; ok {
+				t.Errorf("Metadata = %v while stopped, want only the track ID", metadata)
+			}
+			if tt.wantPosition != 0 {
+				if position := properties["Position"].Value(); position != tt.wantPosition {
+					t.Errorf("Position = %v, want %d", position, tt.wantPosition)
+				}
+			}
+		})
+	}
+
+	identity, err := object.GetProperty(mprisRootIface + ".Identity")
+	if err != nil || identity.Value() != "aniview" {
+		t.Errorf("Identity = %v, %v, want aniview", identity, err)
+	}
+}
+
+func TestMPRISControls(t *testing.T) {
+	address := startTestBus(t)
+	player := startFakePlayer(t)
+	m, err := NewMPRIS(connectTestBus(t, address), player.socket)
+	if err != nil {
+		t.Fatalf("NewMPRIS() = %v", err)
+	}
+	object := connectTestBus(t, address).Object(mprisName, mprisPath)
+
+	tests := []struct {
+		name    string
+		playing bool
+		method  string
+		args    []interface{}
+		want    [][]interface{}
+	}{
+		{"play", true, "Play", nil, [][]interface{}{{"set_property", "pause", false}}},
+		{"pause", true, "Pause", nil, [][]interface{}{{"set_property", "pause", true}}},
+		{"play or pause", true, "PlayPause", nil, [][]interface{}{{"cycle", "pause"}}},
+		{"stop", true, "Stop", nil, [][]interface{}{{"stop"}}},
+		{"seek", true, "Seek", []interface{}{int64(-5000000)}, [][]interface{}{{"seek", -5.0, "relative"}}},
+		{"set position", true, "SetPosition", []interface{}{dbus.ObjectPath("/org/aniview/episode/21_3"), int64(90000000)}, [][]interface{}{{"seek", 90.0, "absolute"}}},
+		{"set position of another episode", true, "SetPosition", []interface{}{dbus.ObjectPath("/org/aniview/episode/21_2"), int64(90000000)}, nil},
+		{"nothing playing", false, "PlayPause", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.playing {
+				m.Playing(AnimeEntry{ID: 21, CurrentEpisode: 3}, PlaybackState{})
+			} else {
+				m.Stopped()
+			}
+
+			if err := object.Call(mprisPlayerIface+"."+tt.method, 0, tt.args...).Err; err != nil {
+				t.Fatalf("%s() = %v", tt.method, err)
+			}
+			if got := player.takeCommands(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("player received %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMPRISSignals(t *testing.T) {
+	address := startTestBus(t)
+	m, err := NewMPRIS(connectTestBus(t, address), startFakePlayer(t).socket)
+	if err != nil {
+		t.Fatalf("NewMPRIS() = %v", err)
+	}
+
+	client := connectTestBus(t, address)
+	if err := client.AddMatchSignal(dbus.WithMatchObjectPath(mprisPath)); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 10)
+	client.Signal(signals)
+
+	anime := AnimeEntry{ID: 21, CurrentEpisode: 3}
+	tests := []struct {
+		name        string
+		update      func()
+		wantSignal  string
+		wantChanged []string // Properties reported as changed
+	}{
+		{"starts playing", func() { m.Playing(anime, PlaybackState{Position: time.Second}) }, "PropertiesChanged", []string{"Metadata", "PlaybackStatus"}},
+		{"pauses", func() { m.Playing(anime, PlaybackState{Position: time.Second, Paused: true}) }, "PropertiesChanged", []string{"PlaybackStatus"}},
+		{"seeks", func() { m.Playing(anime, PlaybackState{Position: time.Minute, Paused: true}) }, "Seeked", nil},
+		{"next episode", func() { m.Playing(AnimeEntry{ID: 21, CurrentEpisode: 4}, PlaybackState{}) }, "PropertiesChanged", []string{"Metadata", "PlaybackStatus"}},
+		{"stops", m.Stopped, "PropertiesChanged", []string{"Metadata", "PlaybackStatus"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.update()
+
+			var signal *dbus.Signal
+			select {
+			case signal = <-signals:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("no signal, want %s", tt.wantSignal)
+			}
+			if !strings.HasSuffix(signal.Name, "."+tt.wantSignal) {
+				t.Fatalf("got signal %s, want %s", signal.Name, tt.wantSignal)
+			}
+			if tt.wantChanged == nil {
+				return
+			}
+
+			changes, _ := signal.Body[1].(map[string]dbus.Variant)
+			var changed []string
+			for name := range changes {
+				changed = append(changed, name)
+			}
+			if !sameStrings(changed, tt.wantChanged) {
+				t.Errorf("changed %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestMPRISNameTaken(t *testing.T) {
+	address := startTestBus(t)
+	if _, err := NewMPRIS(connectTestBus(t, address), ""); err != nil {
+		t.Fatalf("NewMPRIS() = %v", err)
+	}
+	if _, err := NewMPRIS(connectTestBus(t, address), ""); err == nil || !strings.Contains(err.Error(), "another aniview") {
+		t.Errorf("second NewMPRIS() = %v, want the name to be taken", err)
+	}
+}
+
+// sameStrings reports whether a and b hold the same strings in any order
+func sameStrings(a, b []string) bool {
+	counts := make(map[string]int)
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		counts[s]--
+	}
+	for _, n := range counts {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
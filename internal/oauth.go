@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/cancelreader"
+	"github.com/pkg/browser"
+)
+
+const (
+	// callbackAddr must match the redirect URL registered for the AniList and MyAnimeList clients
+	callbackAddr    = "127.0.0.1:47615"
+	callbackPath    = "/callback"
+	callbackTimeout = 5 * time.Minute
+)
+
+// errPasteInstead is returned when the user stops waiting for the redirect to paste the token
+var errPasteInstead = errors.New("switching to pasting the token")
+
+// callbackPage reads the token from the URL fragment, which the browser never sends to
+// the server, and posts it back to the listener along with the page's state. %s is
+// replaced with the state, which other sites cannot read from the page.
+const callbackPage = `<!DOCTYPE html>
+<html>
+<head><title>aniview</title></head>
+<body>
+<p id="status">Logging in...</p>
+<script>
+const params = new URLSearchParams(location.hash.slice(1));
+const status = document.getElementById("status");
+fetch("/token", {
+	method: "POST",
+	headers: { "Content-Type": "application/json" },
+	body: JSON.stringify({ state: "%s", token: params.get("access_token") || "" }),
+})
+	.then((res) => {
+		status.textContent = res.ok
+			? "Logged in. You can close this tab and return to aniview."
+			: "Login failed. Return to aniview to try again.";
+	})
+	.catch(() => { status.textContent = "Login failed. Return to aniview to try again."; });
+</script>
+</body>
+</html>
+`
+
+// TokenCapture listens on the loopback address for the redirect carrying the AniList
+// token or the MyAnimeList authorization code
+type TokenCapture struct {
+	server    *http.Server
+	state     string // Sent with the MyAnimeList request and checked on the redirect
+	pageState string // Embedded in the AniList callback page and checked when it posts the token
+	tokens    chan string
+	errs      chan error
+}
+
+// StartTokenCapture starts the loopback listener for the redirect
+func StartTokenCapture() (*TokenCapture, error) {
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	pageState, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", callbackAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start callback listener: %w", err)
+	}
+
+	capture := &TokenCapture{
+		state:     state,
+		pageState: pageState,
+		tokens:    make(chan string, 1),
+		errs:      make(chan error, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		// A denied request is reported in the query instead of the fragment
+		if reason := query.Get("error"); reason != "" {
+			http.Error(w, "Login was cancelled. Return to aniview.", http.StatusOK)
+			select {
+			case capture.errs <- fmt.Errorf("login failed: %s", reason):
+			default:
+			}
+			return
+		}
+		// MyAnimeList sends an authorization code in the query
+		if code := query.Get("code"); code != "" {
+			if query.Get("state") != capture.state {
+				http.Error(w, "Login state doesn't match, try again.", http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, "Logged in. You can close this tab and return to aniview.")
+			select {
+			case capture.tokens <- code:
+			default:
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, callbackPage, capture.pageState)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		// Only the callback page may post a token, so other sites open in the browser
+		// cannot log aniview in to their own account
+		if r.Header.Get("Origin") != "http://"+callbackAddr {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var posted struct {
+			State string `json:"state"`
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 8192)).Decode(&posted); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(posted.State), []byte(capture.pageState)) != 1 {
+			http.Error(w, "login state doesn't match", http.StatusForbidden)
+			return
+		}
+		token := strings.TrimSpace(posted.Token)
+		if token == "" {
+			http.Error(w, "missing token", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		select {
+		case capture.tokens <- token:
+		default:
+		}
+	})
+
+	capture.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go capture.server.Serve(listener)
+
+	return capture, nil
+}
+
+// AuthURL returns the AniList URL that redirects back to the listener
+func (t *TokenCapture) AuthURL() string {
+	redirectURI := "http://" + callbackAddr + callbackPath
+	return fmt.Sprintf("https://anilist.co/api/v2/oauth/authorize?client_id=%s&response_type=token&redirect_uri=%s", clientID, url.QueryEscape(redirectURI))
+}
+
+// Wait blocks until the token arrives, the login is cancelled or the timeout passes
+func (t *TokenCapture) Wait(timeout time.Duration) (string, error) {
+	select {
+	case token := <-t.tokens:
+		return token, nil
+	case err := <-t.errs:
+		return "", err
+	case <-time.After(timeout):
+		return "", fmt.Errorf("timed out waiting for AniList to redirect back")
+	}
+}
+
+// Skip stops waiting for the redirect so the token can be pasted instead
+func (t *TokenCapture) Skip() {
+	select {
+	case t.errs <- errPasteInstead:
+	default:
+	}
+}
+
+// Close stops the listener, giving the browser a moment to receive the last response
+func (t *TokenCapture) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	t.server.Shutdown(ctx)
+	select {
+	case t.errs <- fmt.Errorf("login was cancelled"):
+	default:
+	}
+}
+
+// ManualAuthURL returns the AniList URL that shows the token for pasting
+func ManualAuthURL() string {
+	return fmt.Sprintf("https://anilist.co/api/v2/oauth/authorize?client_id=%s&response_type=token", clientID)
+}
+
+// randomString returns a URL safe random string made from n random bytes
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// LoginSession is a login through the browser that is waiting for the redirect
+type LoginSession struct {
+	Tracker  string
+	AuthURL  string
+	capture  *TokenCapture
+	clientID string
+	verifier string // PKCE code verifier for MyAnimeList
+}
+
+// StartLogin starts the loopback listener and builds the URL to log in to the profile's tracker
+func StartLogin(config *Config) (*LoginSession, error) {
+	capture, err := StartTokenCapture()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &LoginSession{Tracker: config.Tracker, capture: capture}
+	if config.Tracker != TrackerMyAnimeList {
+		session.AuthURL = capture.AuthURL()
+		return session, nil
+	}
+
+	session.clientID, err = malClientID(config)
+	if err == nil {
+		session.verifier, err = randomString(64)
+	}
+	if err != nil {
+		capture.Close()
+		return nil, err
+	}
+	session.AuthURL = malAuthorizeURL(session.clientID, session.verifier, capture.state)
+	return session, nil
+}
+
+// Wait blocks until the redirect arrives, the login is cancelled or the timeout passes
+func (s *LoginSession) Wait(timeout time.Duration) (string, error) {
+	return s.capture.Wait(timeout)
+}
+
+// Close stops the loopback listener
+func (s *LoginSession) Close() {
+	s.capture.Close()
+}
+
+// Complete logs in with what the redirect carried, checking the credentials before they
+// are stored in the config. A profile that already has a user must log in as the same user.
+func (s *LoginSession) Complete(config *Config, secret string) error {
+	if s.Tracker != TrackerMyAnimeList {
+		return setAniListToken(config, secret)
+	}
+
+	token, err := exchangeMALCode(s.clientID, secret, s.verifier)
+	if err != nil {
+		return err
+	}
+
+	viewer := *config
+	viewer.MALClientID = s.clientID
+	viewer.setMALToken(token)
+	if err := NewMALClient(&viewer, nil).loadViewer(&viewer); err != nil {
+		return fmt.Errorf("MyAnimeList rejected the login: %w", err)
+	}
+	if config.UserID != 0 && viewer.UserID != config.UserID {
+		return fmt.Errorf("logged in as %s, not %s", viewer.Username, config.Username)
+	}
+
+	*config = viewer
+	return nil
+}
+
+// captureToken opens the browser for authentication and captures the token from the
+// redirect with a loopback HTTP listener
+func captureToken() (string, error) {
+	capture, err := StartTokenCapture()
+	if err != nil {
+		return "", err
+	}
+	defer capture.Close()
+
+	authURL := capture.AuthURL()
+	fmt.Println("Opening browser to authenticate with AniList...")
+	if err := browser.OpenURL(authURL); err != nil {
+		fmt.Printf("Failed to open browser automatically. Please open the following URL manually:\n%s\n", authURL)
+	}
+	waiting := "Waiting for AniList to redirect back..."
+	if stop := skipOnEnter(capture); stop != nil {
+		defer stop()
+		waiting = "Waiting for AniList to redirect back, press Enter to paste the token instead..."
+	}
+	fmt.Println(waiting)
+
+	return capture.Wait(callbackTimeout)
+}
+
+// skipOnEnter stops the capture when Enter is pressed in the terminal, for when the
+// redirect URL isn't registered for the AniList client. The returned function stops
+// reading so the next reader of the terminal gets every key. It returns nil when stdin
+// isn't a terminal.
+func skipOnEnter(capture *TokenCapture) func() {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return nil
+	}
+	input, err := cancelreader.NewReader(os.Stdin)
+	if err != nil {
+		return nil
+	}
+
+	go func() {
+		defer input.Close()
+		if _, err := bufio.NewReader(input).ReadString('\n'); err == nil {
+			capture.Skip()
+		}
+	}()
+	return func() { input.Cancel() }
+}
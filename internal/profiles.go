@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// profileNamePattern matches the names a profile may have. Names end up in file names and
+// credential keys, so they are kept to letters, digits, dashes and underscores.
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Profiles holds the configuration of every named profile
+type Profiles struct {
+	Version         int                `json:"version"` // ConfigVersion once loaded
+	Default         string             `json:"default_profile"`
+	CredentialStore string             `json:"credential_store,omitempty"` // One of the CredentialStore constants, empty to pick automatically
+	Settings                           // Shared by every profile, with environment overrides applied
+	Profiles        map[string]*Config `json:"profiles"`
+	saved           Settings           // Settings as in the file, which Save writes back
+	credentials     CredentialStore
+}
+
+// Names returns the profile names in alphabetical order
+func (p *Profiles) Names() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateProfileName returns an error when a profile name has characters that are not allowed
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use only letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// DefaultName returns the profile to use when none was chosen
+func (p *Profiles) DefaultName() string {
+	if _, ok := p.Profiles[p.Default]; ok {
+		return p.Default
+	}
+	if names := p.Names(); len(names) > 0 {
+		return names[0]
+	}
+	return defaultProfile
+}
+
+// NewProfileClients creates the tracker for a profile, with the profile's journal for queued changes,
+// and the AniList client used for the catalog. The AniList client only has a token for AniList profiles.
+func NewProfileClients(config *Config) (Tracker, *AniListClient, error) {
+	journal, err := OpenJournal(config.Profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracker, anilist := NewProfileTracker(config)
+	tracker.SetJournal(journal)
+
+	return tracker, anilist, nil
+}
+
+// NewProfileTracker creates the tracker and AniList client for a profile without a journal,
+// so list changes fail instead of being queued
+func NewProfileTracker(config *Config) (Tracker, *AniListClient) {
+	if config.Tracker == TrackerMyAnimeList {
+		anilist := NewAniListClient("")
+		return NewMALClient(config, anilist), anilist
+	}
+
+	anilist := NewAniListClient(config.Token)
+	return anilist, anilist
+}
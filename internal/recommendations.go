@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// forYouSources is the number of top scored completed anime used for the "for you" list
+const forYouSources = 10
+
+// GetRecommendations fetches the recommendations of an anime, ranked by rating
+func (c *AniListClient) GetRecommendations(mediaID int) ([]Recommendation, error) {
+	query := `
+	query ($id: Int) {
+		Media(id: $id) {
+			recommendations(sort: RATING_DESC, perPage: 25) {
+				nodes {
+					rating
+					mediaRecommendation {
+						id
+						type
+						title {
+							romaji
+							english
+							native
+						}
+						episodes
+						format
+						status
+						averageScore
+						mediaListEntry {
+							id
+							status
+						}
+					}
+				}
+			}
+		}
+	}
+	`
+	variables := map[string]interface{}{
+		"id": mediaID,
+	}
+
+	var response RecommendationsResponse
+	if err := c.executeQuery(query, variables, &response); err != nil {
+		return nil, fmt.Errorf("failed to fetch recommendations (mediaID: %d): %w", mediaID, err)
+	}
+
+	var recommendations []Recommendation
+	for _, node := range response.Data.Media.Recommendations.Nodes {
+		// Recommendations of deleted media come back without a media
+		if node.MediaRecommendation == nil || node.Rating <= 0 {
+			continue
+		}
+		recommendations = append(recommendations, Recommendation{
+			Rating: node.Rating,
+			Media:  *node.MediaRecommendation,
+		})
+	}
+
+	return recommendations, nil
+}
+
+// GetForYou merges the recommendations of the user's highest scored completed anime,
+// leaving out anything that is already on one of the user's lists. The lists come from
+// the tracker, since AniList doesn't know the lists of MyAnimeList profiles.
+func (c *AniListClient) GetForYou(completed []AnimeEntry, listed map[int]string) ([]Recommendation, error) {
+	completed = slices.Clone(completed)
+	sort.SliceStable(completed, func(i, j int) bool {
+		return completed[i].Score > completed[j].Score
+	})
+
+	merged := make(map[int]*Recommendation)
+	for i, anime := range completed {
+		if i == forYouSources || anime.Score == 0 {
+			break
+		}
+
+		recommendations, err := c.GetRecommendations(anime.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, recommendation := range recommendations {
+			if _, ok := listed[recommendation.Media.ID]; ok {
+				continue
+			}
+			if existing, ok := merged[recommendation.Media.ID]; ok {
+				existing.Rating += recommendation.Rating
+				continue
+			}
+			recommendation := recommendation
+			merged[recommendation.Media.ID] = &recommendation
+		}
+	}
+
+	forYou := make([]Recommendation, 0, len(merged))
+	for _, recommendation := range merged {
+		forYou = append(forYou, *recommendation)
+	}
+	sort.Slice(forYou, func(i, j int) bool {
+		if forYou[i].Rating != forYou[j].Rating {
+			return forYou[i].Rating > forYou[j].Rating
+		}
+		return forYou[i].Media.ID < forYou[j].Media.ID
+	})
+
+	return forYou, nil
+}
@@ -0,0 +1,44 @@
+package internal
+
+import "time"
+
+// CurrentSeason returns the anime season and year for the given time.
+// December belongs to the winter season of the following year.
+func CurrentSeason(t time.Time) (string, int) {
+	year := t.Year()
+	switch t.Month() {
+	case time.December:
+		return "WINTER", year + 1
+	case time.January, time.February:
+		return "WINTER", year
+	case time.March, time.April, time.May:
+		return "SPRING", year
+	case time.June, time.July, time.August:
+		return "SUMMER", year
+	default:
+		return "FALL", year
+	}
+}
+
+// ShiftSeason moves a season forwards or backwards by delta seasons
+func ShiftSeason(season string, year int, delta int) (string, int) {
+	index := 0
+	for i, s := range MediaSeasons {
+		if s == season {
+			index = i
+			break
+		}
+	}
+
+	index += delta
+	for index < 0 {
+		index += len(MediaSeasons)
+		year--
+	}
+	for index >= len(MediaSeasons) {
+		index -= len(MediaSeasons)
+		year++
+	}
+
+	return MediaSeasons[index], year
+}
@@ -0,0 +1,417 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ConfigVersion is the version of the config file schema written by this build
+const ConfigVersion = 2
+
+// Players that can play episodes
+const (
+	PlayerIINA = "iina"
+	PlayerMPV  = "mpv"
+)
+
+// Translations a source can stream
+const (
+	TranslationSub = "sub"
+	TranslationDub = "dub"
+)
+
+// Stream qualities besides a resolution such as "1080"
+const (
+	QualityBest  = "best"
+	QualityWorst = "worst"
+)
+
+// SourceAllAnime is the only source episodes are streamed from for now
+const SourceAllAnime = "allanime"
+
+// Tabs the lists can open on
+var UITabs = []string{"watching", "planned", "completed"}
+
+// Settings are the preferences shared by every profile
+type Settings struct {
+	Player  PlayerConfig  `json:"player"`
+	Source  SourceConfig  `json:"source"`
+	Discord DiscordConfig `json:"discord"`
+	UI      UIConfig      `json:"ui"`
+}
+
+// PlayerConfig controls the video player
+type PlayerConfig struct {
+	Name string   `json:"name"` // One of the Player constants
+	Path string   `json:"path"` // Executable, empty for the player's default location
+	Args []string `json:"args"` // Extra arguments passed to the player
+}
+
+// SourceConfig controls where episodes are streamed from
+type SourceConfig struct {
+	Provider    string `json:"provider"`    // Only SourceAllAnime for now
+	Translation string `json:"translation"` // One of the Translation constants
+	Quality     string `json:"quality"`     // One of the Quality constants or a resolution such as "1080", preferred within the chosen provider
+}
+
+// UIConfig controls the interface
+type UIConfig struct {
+	DefaultTab string `json:"default_tab"` // One of UITabs
+}
+
+// Buttons the Discord presence can show
+const (
+	ButtonAniList     = "anilist"
+	ButtonMyAnimeList = "mal"
+)
+
+// DiscordConfig controls the Discord presence. The templates may use {title}, {episode},
+// {episodes} and {status}.
+type DiscordConfig struct {
+	Enabled         bool     `json:"enabled"`
+	ClientID        string   `json:"client_id"`        // Discord application the presence shows as
+	Details         string   `json:"details"`          // Template of the first line
+	State           string   `json:"state"`            // Template of the second line
+	PausedState     string   `json:"paused_state"`     // Template of the second line while paused
+	ImageText       string   `json:"image_text"`       // Template of the cover's tooltip
+	Buttons         []string `json:"buttons"`          // Button constants, Discord shows at most two
+	ExcludePrivate  bool     `json:"exclude_private"`  // Show nothing while watching private entries
+	ExcludeStatuses []string `json:"exclude_statuses"` // List statuses to show nothing for, e.g. PLANNING
+	ExcludeShows    []int    `json:"exclude_shows"`    // AniList IDs of shows to show nothing for
+}
+
+// DefaultSettings returns the settings used when the config file doesn't set them
+func DefaultSettings() Settings {
+	player := PlayerMPV
+	if runtime.GOOS == "darwin" {
+		player = PlayerIINA
+	}
+
+	return Settings{
+		Player: PlayerConfig{
+			Name: player,
+			Args: []string{},
+		},
+		Source: SourceConfig{
+			Provider:    SourceAllAnime,
+			Translation: TranslationSub,
+			Quality:     QualityBest,
+		},
+		Discord: DiscordConfig{
+			Enabled:         true,
+			ClientID:        defaultDiscordClientID,
+			Details:         "{title}",
+			State:           "Episode {episode}",
+			PausedState:     "Episode {episode} (Paused)",
+			ImageText:       "{title}",
+			Buttons:         []string{ButtonAniList, ButtonMyAnimeList},
+			ExcludePrivate:  true,
+			ExcludeStatuses: []string{},
+			ExcludeShows:    []int{},
+		},
+		UI: UIConfig{
+			DefaultTab: UITabs[0],
+		},
+	}
+}
+
+// Validate checks every setting, returning all the problems at once
+func (s Settings) Validate() error {
+	var errs []error
+	invalid := func(key string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if s.Player.Name != PlayerIINA && s.Player.Name != PlayerMPV {
+		invalid("player.name", "unknown player %q, use %s or %s", s.Player.Name, PlayerIINA, PlayerMPV)
+	}
+
+	if s.Source.Provider != SourceAllAnime {
+		invalid("source.provider", "unknown provider %q, only %s is supported", s.Source.Provider, SourceAllAnime)
+	}
+	if s.Source.Translation != TranslationSub && s.Source.Translation != TranslationDub {
+		invalid("source.translation", "unknown translation %q, use %s or %s", s.Source.Translation, TranslationSub, TranslationDub)
+	}
+	if s.Source.Quality != QualityBest && s.Source.Quality != QualityWorst {
+		if height, err := strconv.Atoi(strings.TrimSuffix(s.Source.Quality, "p")); err != nil || height <= 0 {
+			invalid("source.quality", "unknown quality %q, use %s, %s or a resolution such as 1080", s.Source.Quality, QualityBest, QualityWorst)
+		}
+	}
+
+	if s.Discord.ClientID != "" && !discordClientIDPattern.MatchString(s.Discord.ClientID) {
+		invalid("discord.client_id", "%q isn't a Discord application ID, which is a number", s.Discord.ClientID)
+	}
+	for _, button := range s.Discord.Buttons {
+		if button != ButtonAniList && button != ButtonMyAnimeList {
+			invalid("discord.buttons", "unknown button %q, use %s or %s", button, ButtonAniList, ButtonMyAnimeList)
+		}
+	}
+	if len(s.Discord.Buttons) > 2 {
+		invalid("discord.buttons", "Discord shows at most 2 buttons, got %d", len(s.Discord.Buttons))
+	}
+	for _, status := range s.Discord.ExcludeStatuses {
+		if !containsFold(ListStatuses, status) {
+			invalid("discord.exclude_statuses", "unknown list status %q, use one of %s", status, strings.Join(ListStatuses, ", "))
+		}
+	}
+
+	if !slices.Contains(UITabs, s.UI.DefaultTab) {
+		invalid("ui.default_tab", "unknown tab %q, use one of %s", s.UI.DefaultTab, strings.Join(UITabs, ", "))
+	}
+
+	return errors.Join(errs...)
+}
+
+var discordClientIDPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// containsFold tells whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateProfile checks the settings of a profile
+func validateProfile(name string, config Config) error {
+	var errs []error
+	if config.Tracker != "" && config.Tracker != TrackerAniList && config.Tracker != TrackerMyAnimeList {
+		errs = append(errs, fmt.Errorf("profiles.%s.tracker: unknown tracker %q, use %s or %s", name, config.Tracker, TrackerAniList, TrackerMyAnimeList))
+	}
+	if !slices.Contains(FillerModes, config.Episodes.Filler) {
+		errs = append(errs, fmt.Errorf("profiles.%s.episodes.filler: unknown mode %q, use one of %s", name, config.Episodes.Filler, strings.Join(FillerModes, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// envPrefix starts the environment variables that override settings, e.g. ANIVIEW_PLAYER_NAME
+const envPrefix = "ANIVIEW_"
+
+// applyEnv overrides settings with environment variables named after their section and key,
+// such as ANIVIEW_SOURCE_QUALITY=720. Lists are separated by commas.
+func (s *Settings) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	sections := reflect.ValueOf(s).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		sectionName := jsonName(sections.Type().Field(i))
+
+		for j := 0; j < section.NumField(); j++ {
+			name := envPrefix + strings.ToUpper(sectionName+"_"+jsonName(section.Type().Field(j)))
+			value, ok := lookup(name)
+			if !ok {
+				continue
+			}
+			if err := setFromEnv(section.Field(j), value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// jsonName returns the name a struct field has in the config file
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// setFromEnv parses an environment variable into a setting
+func setFromEnv(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't true or false", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		list := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setFromEnv(list.Index(i), item); err != nil {
+				return err
+			}
+		}
+		field.Set(list)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't a number", value)
+		}
+		field.SetInt(int64(n))
+	default:
+		return fmt.Errorf("can't be set from the environment")
+	}
+	return nil
+}
+
+// configHeader starts the config file
+const configHeader = `// aniview config. Lines starting with // are comments.
+// Shared settings can be overridden with environment variables named after their
+// section and key, e.g. ANIVIEW_PLAYER_NAME=mpv or ANIVIEW_DISCORD_ENABLED=false.
+`
+
+// configComments explains the settings in the config file. Profile settings are keyed
+// without the profile's name.
+var configComments = map[string]string{
+	"version":                  "Schema version of this file, older files are migrated on start",
+	"default_profile":          "Profile used when --profile isn't given",
+	"credential_store":         "Where tokens are kept: keyring, file or encrypted",
+	"player":                   "Video player episodes are watched in",
+	"player.name":              "iina or mpv",
+	"player.path":              "Player executable, empty for the default location",
+	"player.args":              "Extra arguments passed to the player",
+	"source":                   "Where episodes are streamed from",
+	"source.provider":          "Only allanime is supported for now",
+	"source.translation":       "sub or dub",
+	"source.quality":           "best, worst or a resolution such as 1080, falling back to the closest one the preferred provider has",
+	"discord":                  "Discord Rich Presence",
+	"discord.client_id":        "Discord application the presence shows as",
+	"discord.details":          "Templates of the presence lines, which may use {title}, {episode}, {episodes} and {status}",
+	"discord.buttons":          "Links to show, any two of anilist and mal",
+	"discord.exclude_private":  "Show nothing while watching private list entries",
+	"discord.exclude_statuses": "List statuses to show nothing for, e.g. PLANNING",
+	"discord.exclude_shows":    "AniList IDs of shows to show nothing for",
+	"ui":                       "Interface",
+	"ui.default_tab":           "Tab the lists open on: watching, planned or completed",
+	"profiles":                 "Accounts, each keeping a list on AniList or MyAnimeList",
+	"profile.tracker":          "anilist or mal, set when the profile is created",
+	"profile.tracking":         "How watching episodes updates the list entry",
+	"profile.episodes.filler":  "show, skip or hide filler episodes",
+}
+
+// commentConfig adds the header and the comments explaining each setting to an encoded config file
+func commentConfig(data []byte) []byte {
+	var out bytes.Buffer
+	out.WriteString(configHeader)
+
+	var path []string
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if (strings.HasPrefix(trimmed, "}") || strings.HasPrefix(trimmed, "]")) && len(path) > 0 {
+			path = path[:len(path)-1]
+		}
+
+		key, isKey := lineKey(trimmed)
+		if isKey && len(path) > 0 {
+			if comment, ok := configComments[commentKey(append(path, key))]; ok {
+				indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+				out.WriteString(indent + "// " + comment + "\n")
+			}
+		}
+		out.WriteString(line + "\n")
+
+		if strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, "[") {
+			path = append(path, key)
+		}
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+}
+
+// lineKey returns the object key a line of indented JSON starts with
+func lineKey(line string) (string, bool) {
+	if !strings.HasPrefix(line, `"`) {
+		return "", false
+	}
+	escaped := false
+	for i := 1; i < len(line); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case line[i] == '\\':
+			escaped = true
+		case line[i] == '"':
+			if !strings.HasPrefix(line[i+1:], ":") {
+				return "", false
+			}
+			var key string
+			if json.Unmarshal([]byte(line[:i+1]), &key) != nil {
+				return "", false
+			}
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// commentKey returns the key of a setting in configComments from its path in the file,
+// which starts at the unnamed top-level object
+func commentKey(path []string) string {
+	path = path[1:]
+	if len(path) > 2 && path[0] == "profiles" {
+		return "profile." + strings.Join(path[2:], ".")
+	}
+	return strings.Join(path, ".")
+}
+
+// stripComments removes // comments from a config file, keeping the line breaks so
+// errors point at the right line
+func stripComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			out = append(out, c)
+			continue
+		}
+
+		if c == '/' && i+1 < len(data) && data[i+1] == '/' {
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// parseError explains where a config file is malformed
+func parseError(data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line := bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+		return fmt.Errorf("line %d: %w", line, err)
+	}
+	return err
+}
+
+// decodeStrict decodes a config section, rejecting keys the schema doesn't have
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
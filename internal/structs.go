@@ -2,9 +2,52 @@ package internal
 
 // Config represents the application configuration
 type Config struct {
-	Token    string `json:"token"`
-	Username string `json:"username"`
-	UserID   int    `json:"user_id"`
+	Profile        string         `json:"-"`                       // Name of the profile the config belongs to
+	Token          string         `json:"-"`                       // Kept in the credential store
+	TokenExpiresAt int64          `json:"-"`                       // Unix time the token expires at, 0 when unknown
+	RefreshToken   string         `json:"-"`                       // MyAnimeList only, kept in the credential store
+	Tracker        string         `json:"tracker,omitempty"`       // One of the Tracker constants, empty for AniList
+	MALClientID    string         `json:"mal_client_id,omitempty"` // Client ID of the MyAnimeList API app
+	Username       string         `json:"username"`
+	UserID         int            `json:"user_id"`
+	Tracking       TrackingConfig `json:"tracking"`
+	Episodes       EpisodesConfig `json:"episodes"`
+}
+
+// TrackingConfig controls how watching episodes updates the list entry
+type TrackingConfig struct {
+	SetStartDate bool `json:"set_start_date"` // Set startedAt when the first episode is watched
+	AutoComplete bool `json:"auto_complete"`  // Complete finished shows after their final episode
+	PromptScore  bool `json:"prompt_score"`   // Ask for a score when a show is completed
+}
+
+// How filler episodes are treated in the episode list
+const (
+	FillerShow = "show" // List filler like any other episode
+	FillerSkip = "skip" // List filler, but select the next episode that isn't filler
+	FillerHide = "hide" // Leave filler out of the list
+)
+
+// FillerModes contains every filler mode, in the order the episode list cycles through them
+var FillerModes = []string{FillerShow, FillerSkip, FillerHide}
+
+// EpisodesConfig controls the episode list
+type EpisodesConfig struct {
+	Filler string `json:"filler"` // One of the Filler constants
+}
+
+// DefaultConfig returns the configuration used for settings missing from the config file
+func DefaultConfig() Config {
+	return Config{
+		Tracking: TrackingConfig{
+			SetStartDate: true,
+			AutoComplete: true,
+			PromptScore:  true,
+		},
+		Episodes: EpisodesConfig{
+			Filler: FillerShow,
+		},
+	}
 }
 
 // AniListUserResponse represents the response from the AniList API for user info
@@ -25,24 +68,132 @@ type MediaListCollection struct {
 	Data struct {
 		MediaListCollection struct {
 			Lists []struct {
-				Name    string `json:"name"`
-				Entries []struct {
-					ID          int    `json:"id"`
-					Status      string `json:"status"`
-					Progress    int    `json:"progress"`
-					Media       Media  `json:"media"`
-					UpdatedAt   int    `json:"updatedAt"`
-					StartedAt   Date   `json:"startedAt"`
-					CompletedAt Date   `json:"completedAt"`
+				Name    string           `json:"name"`
+				Entries []MediaListEntry `json:"entries"`
+			} `json:"lists"`
+		} `json:"MediaListCollection"`
+	} `json:"data"`
+}
+
+// MediaListEntry represents a single entry on a user's AniList list
+type MediaListEntry struct {
+	ID                    int     `json:"id"`
+	Status                string  `json:"status"`
+	Score                 float64 `json:"score"`
+	Progress              int     `json:"progress"`
+	Repeat                int     `json:"repeat"`
+	Notes                 string  `json:"notes"`
+	Private               bool    `json:"private"`
+	HiddenFromStatusLists bool    `json:"hiddenFromStatusLists"`
+	Media                 Media   `json:"media"`
+	UpdatedAt             int     `json:"updatedAt"`
+	StartedAt             Date    `json:"startedAt"`
+	CompletedAt           Date    `json:"completedAt"`
+}
+
+// FullMediaListCollectionResponse represents every list of a user from AniList, including
+// the fields only needed for exports
+type FullMediaListCollectionResponse struct {
+	Data struct {
+		MediaListCollection struct {
+			User struct {
+				ID               int    `json:"id"`
+				Name             string `json:"name"`
+				MediaListOptions struct {
+					ScoreFormat string `json:"scoreFormat"`
+				} `json:"mediaListOptions"`
+			} `json:"user"`
+			Lists []struct {
+				Name         string `json:"name"`
+				IsCustomList bool   `json:"isCustomList"`
+				Entries      []struct {
+					MediaListEntry
+					Priority       int                `json:"priority"`
+					CustomLists    map[string]bool    `json:"customLists"`
+					AdvancedScores map[string]float64 `json:"advancedScores"`
+					CreatedAt      int64              `json:"createdAt"`
 				} `json:"entries"`
 			} `json:"lists"`
 		} `json:"MediaListCollection"`
 	} `json:"data"`
 }
 
+// SaveMediaListEntryResponse represents the response from the SaveMediaListEntry mutation
+type SaveMediaListEntryResponse struct {
+	Data struct {
+		SaveMediaListEntry MediaListEntry `json:"SaveMediaListEntry"`
+	} `json:"data"`
+}
+
+// ViewerOptionsResponse represents the list options of the authenticated user
+type ViewerOptionsResponse struct {
+	Data struct {
+		Viewer struct {
+			MediaListOptions struct {
+				ScoreFormat string `json:"scoreFormat"`
+			} `json:"mediaListOptions"`
+		} `json:"Viewer"`
+	} `json:"data"`
+}
+
+// ListEntryUpdate describes the fields sent with a SaveMediaListEntry mutation.
+// Nil fields are left untouched on AniList.
+type ListEntryUpdate struct {
+	MediaID               int      `json:"mediaId"`
+	Status                *string  `json:"status,omitempty"`
+	Score                 *float64 `json:"score,omitempty"`
+	Progress              *int     `json:"progress,omitempty"`
+	Repeat                *int     `json:"repeat,omitempty"`
+	Notes                 *string  `json:"notes,omitempty"`
+	Private               *bool    `json:"private,omitempty"`
+	HiddenFromStatusLists *bool    `json:"hiddenFromStatusLists,omitempty"`
+	StartedAt             *Date    `json:"startedAt,omitempty"`
+	CompletedAt           *Date    `json:"completedAt,omitempty"`
+	Priority              *int     `json:"priority,omitempty"`
+	CustomLists           []string `json:"customLists,omitempty"` // Names of the custom lists the entry is on, nil leaves them untouched
+}
+
+// Merge overlays the fields set in a later update onto this one
+func (u *ListEntryUpdate) Merge(later ListEntryUpdate) {
+	if later.Status != nil {
+		u.Status = later.Status
+	}
+	if later.Score != nil {
+		u.Score = later.Score
+	}
+	if later.Progress != nil {
+		u.Progress = later.Progress
+	}
+	if later.Repeat != nil {
+		u.Repeat = later.Repeat
+	}
+	if later.Notes != nil {
+		u.Notes = later.Notes
+	}
+	if later.Private != nil {
+		u.Private = later.Private
+	}
+	if later.HiddenFromStatusLists != nil {
+		u.HiddenFromStatusLists = later.HiddenFromStatusLists
+	}
+	if later.StartedAt != nil {
+		u.StartedAt = later.StartedAt
+	}
+	if later.CompletedAt != nil {
+		u.CompletedAt = later.CompletedAt
+	}
+	if later.Priority != nil {
+		u.Priority = later.Priority
+	}
+	if later.CustomLists != nil {
+		u.CustomLists = later.CustomLists
+	}
+}
+
 // Media represents an anime media entry from AniList
 type Media struct {
 	ID                int    `json:"id"`
+	Type              string `json:"type"`
 	Title             Title  `json:"title"`
 	Episodes          int    `json:"episodes"`
 	Format            string `json:"format"`
@@ -54,7 +205,129 @@ type Media struct {
 	AverageScore      int               `json:"averageScore"`
 	SeasonYear        int               `json:"seasonYear"`
 	Season            string            `json:"season"`
+	Genres            []string          `json:"genres"`
+	Popularity        int               `json:"popularity"`
+	StartDate         Date              `json:"startDate"`
 	NextAiringEpisode NextAiringEpisode `json:"nextAiringEpisode"`
+	MediaListEntry    *MediaListStatus  `json:"mediaListEntry"`
+}
+
+// MediaListStatus represents the authenticated user's list entry for a media, if any
+type MediaListStatus struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+// MediaPageResponse represents a page of media returned by an AniList search
+type MediaPageResponse struct {
+	Data struct {
+		Page struct {
+			PageInfo PageInfo `json:"pageInfo"`
+			Media    []Media  `json:"media"`
+		} `json:"Page"`
+	} `json:"data"`
+}
+
+// PageInfo represents the pagination info of an AniList page
+type PageInfo struct {
+	CurrentPage int  `json:"currentPage"`
+	HasNextPage bool `json:"hasNextPage"`
+}
+
+// AiringSchedulePageResponse represents a page of airing schedules from AniList
+type AiringSchedulePageResponse struct {
+	Data struct {
+		Page struct {
+			PageInfo        PageInfo         `json:"pageInfo"`
+			AiringSchedules []AiringSchedule `json:"airingSchedules"`
+		} `json:"Page"`
+	} `json:"data"`
+}
+
+// AiringSchedule represents a single episode airing
+type AiringSchedule struct {
+	ID       int   `json:"id"`
+	Episode  int   `json:"episode"`
+	AiringAt int64 `json:"airingAt"`
+	MediaID  int   `json:"mediaId"`
+}
+
+// MediaRelationsResponse represents the relations of a media from AniList
+type MediaRelationsResponse struct {
+	Data struct {
+		Media struct {
+			Relations struct {
+				Edges []struct {
+					RelationType string `json:"relationType"`
+					Node         Media  `json:"node"`
+				} `json:"edges"`
+			} `json:"relations"`
+		} `json:"Media"`
+	} `json:"data"`
+}
+
+// MediaRelation represents an anime related to another one, such as a sequel
+type MediaRelation struct {
+	RelationType string
+	Media        Media
+}
+
+// RecommendationsResponse represents the recommendations of a media from AniList
+type RecommendationsResponse struct {
+	Data struct {
+		Media struct {
+			Recommendations struct {
+				Nodes []struct {
+					Rating              int    `json:"rating"`
+					MediaRecommendation *Media `json:"mediaRecommendation"`
+				} `json:"nodes"`
+			} `json:"recommendations"`
+		} `json:"Media"`
+	} `json:"data"`
+}
+
+// Recommendation represents an anime recommended by AniList users, with its combined rating
+type Recommendation struct {
+	Rating int
+	Media  Media
+}
+
+// NotificationPageResponse represents a page of notifications for the authenticated user
+type NotificationPageResponse struct {
+	Data struct {
+		Viewer struct {
+			UnreadNotificationCount int `json:"unreadNotificationCount"`
+		} `json:"Viewer"`
+		Page struct {
+			PageInfo      PageInfo       `json:"pageInfo"`
+			Notifications []Notification `json:"notifications"`
+		} `json:"Page"`
+	} `json:"data"`
+}
+
+// Notification represents an AniList notification. Fields that do not apply to the notification type are left empty.
+type Notification struct {
+	ID        int      `json:"id"`
+	Type      string   `json:"type"`
+	CreatedAt int64    `json:"createdAt"`
+	Episode   int      `json:"episode"`
+	Contexts  []string `json:"contexts"`
+	Context   string   `json:"context"`
+	Reason    string   `json:"reason"`
+	AnimeID   int      `json:"animeId"`
+	Media     *Media   `json:"media"`
+	User      *struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+// MediaSearchFilter holds the filters for an AniList media search. Empty fields are ignored.
+type MediaSearchFilter struct {
+	Search     string
+	Format     string
+	Season     string
+	SeasonYear int
+	Genre      string
 }
 
 type NextAiringEpisode struct {
@@ -73,6 +346,14 @@ type Title struct {
 	Native  string `json:"native"`
 }
 
+// Preferred returns the English title, falling back to Romaji
+func (t Title) Preferred() string {
+	if t.English != "" {
+		return t.English
+	}
+	return t.Romaji
+}
+
 // Image represents an image from AniList
 type Image struct {
 	Medium string `json:"medium"`
@@ -86,6 +367,11 @@ type Date struct {
 	Day   int `json:"day"`
 }
 
+// IsEmpty reports whether no part of the date is set
+func (d Date) IsEmpty() bool {
+	return d.Year == 0 && d.Month == 0 && d.Day == 0
+}
+
 // AnimeEntry represents a single anime entry for display in the UI
 type AnimeEntry struct {
 	Title             string
@@ -97,12 +383,76 @@ type AnimeEntry struct {
 	Description       string
 	NextEpisode       int
 	CurrentEpisode    int
-	EpisodeDuration   int
 	NextAiringEpisode NextAiringEpisode
 	IsAiring          bool
+	MediaStatus       string
+
+	// List entry fields
+	Status                string
+	Score                 float64
+	Repeat                int
+	Notes                 string
+	Private               bool
+	HiddenFromStatusLists bool
+	StartedAt             Date
+	CompletedAt           Date
+	UpdatedAt             int64 // Unix time the list entry was last changed
+}
+
+// ApplyListEntry copies the list entry fields from a saved AniList entry
+func (a *AnimeEntry) ApplyListEntry(entry MediaListEntry) {
+	a.Status = entry.Status
+	a.Score = entry.Score
+	a.Progress = entry.Progress
+	a.NextEpisode = entry.Progress + 1
+	a.Repeat = entry.Repeat
+	a.Notes = entry.Notes
+	a.Private = entry.Private
+	a.HiddenFromStatusLists = entry.HiddenFromStatusLists
+	a.StartedAt = entry.StartedAt
+	a.CompletedAt = entry.CompletedAt
+	a.UpdatedAt = int64(entry.UpdatedAt)
+}
+
+// ApplyUpdate copies the fields set in an update, for changes that have not reached AniList yet
+func (a *AnimeEntry) ApplyUpdate(update ListEntryUpdate) {
+	if update.Status != nil {
+		a.Status = *update.Status
+	}
+	if update.Score != nil {
+		a.Score = *update.Score
+	}
+	if update.Progress != nil {
+		a.Progress = *update.Progress
+		a.NextEpisode = a.Progress + 1
+	}
+	if update.Repeat != nil {
+		a.Repeat = *update.Repeat
+	}
+	if update.Notes != nil {
+		a.Notes = *update.Notes
+	}
+	if update.Private != nil {
+		a.Private = *update.Private
+	}
+	if update.HiddenFromStatusLists != nil {
+		a.HiddenFromStatusLists = *update.HiddenFromStatusLists
+	}
+	if update.StartedAt != nil {
+		a.StartedAt = *update.StartedAt
+	}
+	if update.CompletedAt != nil {
+		a.CompletedAt = *update.CompletedAt
+	}
 }
 
 // Constants for the application
 var (
 	LinkPriorities = []string{"filemoon", "sharepoint", "doodstream", "mp4upload"}
+	MediaFormats   = []string{"TV", "TV_SHORT", "MOVIE", "SPECIAL", "OVA", "ONA", "MUSIC"}
+	MediaSeasons   = []string{"WINTER", "SPRING", "SUMMER", "FALL"}
+	MediaGenres    = []string{
+		"Action", "Adventure", "Comedy", "Drama", "Ecchi", "Fantasy", "Horror", "Mahou Shoujo", "Mecha", "Music",
+		"Mystery", "Psychological", "Romance", "Sci-Fi", "Slice of Life", "Sports", "Supernatural", "Thriller",
+	}
 )
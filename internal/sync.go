@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Policies deciding which list wins when AniList and MyAnimeList disagree
+const (
+	SyncNewest       = "newest"   // The entry changed most recently wins
+	SyncAniList      = "anilist"  // AniList always wins
+	SyncMyAnimeList  = "mal"      // MyAnimeList always wins
+	SyncMostProgress = "progress" // The entry with the most episodes watched wins, the newest on a tie
+)
+
+// SyncPolicies contains every sync policy
+var SyncPolicies = []string{SyncNewest, SyncAniList, SyncMyAnimeList, SyncMostProgress}
+
+// SyncDiff is a field that differs between the AniList and MyAnimeList entry
+type SyncDiff struct {
+	Field   string
+	AniList string
+	MAL     string
+}
+
+// SyncChange is a change to one list planned by a sync
+type SyncChange struct {
+	Title   string
+	Target  string // Tracker the change is sent to, one of the Tracker constants
+	Added   bool   // Whether the anime is missing from the target list
+	Diffs   []SyncDiff
+	Update  ListEntryUpdate
+	Applied bool
+	Err     error
+}
+
+// SyncLists holds both lists and the score formats their scores are in
+type SyncLists struct {
+	AniList            []AnimeEntry
+	AniListScoreFormat string
+	MAL                []AnimeEntry
+}
+
+// FetchSyncLists fetches every entry of both lists
+func FetchSyncLists(anilist Tracker, anilistUserID int, mal Tracker) (SyncLists, error) {
+	var lists SyncLists
+	var err error
+
+	if lists.AniList, err = anilist.GetList(anilistUserID, ListStatuses...); err != nil {
+		return lists, err
+	}
+	if lists.AniListScoreFormat, err = anilist.GetScoreFormat(); err != nil {
+		return lists, err
+	}
+	if lists.MAL, err = mal.GetList(0, ListStatuses...); err != nil {
+		return lists, err
+	}
+
+	return lists, nil
+}
+
+// PlanSync joins both lists on idMal and plans the changes that make them match. Entries
+// on one list only are added to the other, conflicts are settled with the policy. Private
+// AniList entries are left alone unless includePrivate is set, their titles are returned.
+func PlanSync(lists SyncLists, policy string, includePrivate bool) ([]SyncChange, []string, error) {
+	validPolicy := false
+	for _, p := range SyncPolicies {
+		validPolicy = validPolicy || p == policy
+	}
+	if !validPolicy {
+		return nil, nil, fmt.Errorf("unknown sync policy %q", policy)
+	}
+
+	malEntries := make(map[int]AnimeEntry, len(lists.MAL))
+	for _, entry := range lists.MAL {
+		malEntries[entry.MalId] = entry
+	}
+
+	var changes []SyncChange
+	var private []string
+	seen := make(map[int]bool)
+	for _, anilistEntry := range lists.AniList {
+		// Anime that aren't on MyAnimeList can't be synced
+		if anilistEntry.MalId == 0 {
+			continue
+		}
+		seen[anilistEntry.MalId] = true
+
+		// MyAnimeList has no private entries, so syncing would make them public there
+		if anilistEntry.Private && !includePrivate {
+			private = append(private, anilistEntry.Title)
+			continue
+		}
+
+		// Scores are compared on the MyAnimeList scale
+		anilistEntry.Score = ConvertScore(anilistEntry.Score, lists.AniListScoreFormat, ScorePoint10)
+
+		malEntry, ok := malEntries[anilistEntry.MalId]
+		if !ok {
+			changes = append(changes, SyncChange{
+				Title:  anilistEntry.Title,
+				Target: TrackerMyAnimeList,
+				Added:  true,
+				Update: fullUpdate(anilistEntry),
+			})
+			continue
+		}
+
+		diffs := diffEntries(anilistEntry, malEntry)
+		if len(diffs) == 0 {
+			continue
+		}
+
+		change := SyncChange{Title: anilistEntry.Title, Diffs: diffs}
+		if syncWinner(anilistEntry, malEntry, policy) == TrackerAniList {
+			change.Target = TrackerMyAnimeList
+			change.Update = diffUpdate(anilistEntry, diffs)
+		} else {
+			change.Target = TrackerAniList
+			change.Update = diffUpdate(malEntry, diffs)
+		}
+		changes = append(changes, change)
+	}
+
+	for _, malEntry := range lists.MAL {
+		if seen[malEntry.MalId] {
+			continue
+		}
+		changes = append(changes, SyncChange{
+			Title:  malEntry.Title,
+			Target: TrackerAniList,
+			Added:  true,
+			Update: fullUpdate(malEntry),
+		})
+	}
+
+	// Scores sent to AniList must be in the user's score format
+	for i := range changes {
+		if changes[i].Target == TrackerAniList && changes[i].Update.Score != nil {
+			score := ConvertScore(*changes[i].Update.Score, ScorePoint10, lists.AniListScoreFormat)
+			changes[i].Update.Score = &score
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Title < changes[j].Title
+	})
+	sort.Strings(private)
+	return changes, private, nil
+}
+
+// ApplySync sends the planned changes, batched per tracker, recording the result on each change
+func ApplySync(changes []SyncChange, anilist Tracker, mal Tracker) {
+	for _, target := range []Tracker{anilist, mal} {
+		var indexes []int
+		var updates []ListEntryUpdate
+		for i, change := range changes {
+			if (change.Target == TrackerMyAnimeList) == (target == mal) {
+				indexes = append(indexes, i)
+				updates = append(updates, change.Update)
+			}
+		}
+		if len(updates) == 0 {
+			continue
+		}
+
+		_, errs := target.SaveEntries(updates)
+		for i, index := range indexes {
+			changes[index].Err = errs[i]
+			changes[index].Applied = errs[i] == nil
+		}
+	}
+}
+
+// diffEntries returns the synced fields that differ between the entries
+func diffEntries(anilist, mal AnimeEntry) []SyncDiff {
+	var diffs []SyncDiff
+	add := func(field, a, m string) {
+		if a != m {
+			diffs = append(diffs, SyncDiff{Field: field, AniList: a, MAL: m})
+		}
+	}
+
+	add("status", anilist.Status, mal.Status)
+	add("progress", strconv.Itoa(anilist.Progress), strconv.Itoa(mal.Progress))
+	add("score", FormatScore(ScorePoint10, anilist.Score), FormatScore(ScorePoint10, mal.Score))
+	add("started", FormatDate(anilist.StartedAt), FormatDate(mal.StartedAt))
+	add("completed", FormatDate(anilist.CompletedAt), FormatDate(mal.CompletedAt))
+	return diffs
+}
+
+// syncWinner returns the tracker whose entry wins under the policy
+func syncWinner(anilist, mal AnimeEntry, policy string) string {
+	switch policy {
+	case SyncAniList:
+		return TrackerAniList
+	case SyncMyAnimeList:
+		return TrackerMyAnimeList
+	case SyncMostProgress:
+		if anilist.Progress != mal.Progress {
+			if anilist.Progress > mal.Progress {
+				return TrackerAniList
+			}
+			return TrackerMyAnimeList
+		}
+	}
+
+	if mal.UpdatedAt > anilist.UpdatedAt {
+		return TrackerMyAnimeList
+	}
+	return TrackerAniList
+}
+
+// diffUpdate builds an update setting the differing fields to the winning entry's values
+func diffUpdate(winner AnimeEntry, diffs []SyncDiff) ListEntryUpdate {
+	full := fullUpdate(winner)
+	update := ListEntryUpdate{MediaID: winner.ID}
+	for _, diff := range diffs {
+		switch diff.Field {
+		case "status":
+			update.Status = full.Status
+		case "progress":
+			update.Progress = full.Progress
+		case "score":
+			update.Score = full.Score
+		case "started":
+			update.StartedAt = full.StartedAt
+		case "completed":
+			update.CompletedAt = full.CompletedAt
+		}
+	}
+	return update
+}
+
+// fullUpdate builds an update copying every synced field of an entry
+func fullUpdate(entry AnimeEntry) ListEntryUpdate {
+	return ListEntryUpdate{
+		MediaID:     entry.ID,
+		Status:      &entry.Status,
+		Score:       &entry.Score,
+		Progress:    &entry.Progress,
+		Repeat:      &entry.Repeat,
+		StartedAt:   &entry.StartedAt,
+		CompletedAt: &entry.CompletedAt,
+	}
+}
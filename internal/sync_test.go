@@ -0,0 +1,326 @@
+package internal
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSyncWinner(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		// Progress and last change of the AniList and MyAnimeList entry
+		anilistProgress, malProgress int
+		anilistUpdated, malUpdated   int64
+		want                         string
+	}{
+		{"anilist wins even when older", SyncAniList, 1, 1, 100, 200, TrackerAniList},
+		{"mal wins even when older", SyncMyAnimeList, 1, 1, 200, 100, TrackerMyAnimeList},
+		{"newest anilist", SyncNewest, 1, 5, 200, 100, TrackerAniList},
+		{"newest mal", SyncNewest, 5, 1, 100, 200, TrackerMyAnimeList},
+		{"newest tie goes to anilist", SyncNewest, 1, 5, 100, 100, TrackerAniList},
+		{"most progress anilist", SyncMostProgress, 5, 1, 100, 200, TrackerAniList},
+		{"most progress mal", SyncMostProgress, 1, 5, 200, 100, TrackerMyAnimeList},
+		{"progress tie goes to the newest", SyncMostProgress, 3, 3, 100, 200, TrackerMyAnimeList},
+		{"progress and time tie goes to anilist", SyncMostProgress, 3, 3, 100, 100, TrackerAniList},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anilist := AnimeEntry{Progress: tt.anilistProgress, UpdatedAt: tt.anilistUpdated}
+			mal := AnimeEntry{Progress: tt.malProgress, UpdatedAt: tt.malUpdated}
+			if got := syncWinner(anilist, mal, tt.policy); got != tt.want {
+				t.Errorf("syncWinner() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffUpdate(t *testing.T) {
+	winner := AnimeEntry{
+		ID:          21,
+		Status:      "COMPLETED",
+		Progress:    12,
+		Score:       8,
+		Repeat:      1,
+		StartedAt:   Date{Year: 2024, Month: 1, Day: 2},
+		CompletedAt: Date{Year: 2024, Month: 3, Day: 4},
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   string // The update as JSON, which leaves out the fields that aren't set
+	}{
+		{"nothing differs", nil, `{"mediaId":21}`},
+		{"progress only", []string{"progress"}, `{"mediaId":21,"progress":12}`},
+		{
+			name:   "every field",
+			fields: []string{"status", "progress", "score", "started", "completed"},
+			want:   `{"mediaId":21,"status":"COMPLETED","score":8,"progress":12,"startedAt":{"year":2024,"month":1,"day":2},"completedAt":{"year":2024,"month":3,"day":4}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diffs []SyncDiff
+			for _, field := range tt.fields {
+				diffs = append(diffs, SyncDiff{Field: field})
+			}
+			if got := updateJSON(t, diffUpdate(winner, diffs)); got != tt.want {
+				t.Errorf("diffUpdate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanSync(t *testing.T) {
+	// A pair of entries for the same anime, AniList scores out of 100
+	anilist := AnimeEntry{ID: 1, MalId: 101, Title: "Frieren", Status: "CURRENT", Progress: 10, Score: 90, UpdatedAt: 200}
+	mal := AnimeEntry{ID: 1, MalId: 101, Title: "Frieren", Status: "CURRENT", Progress: 10, Score: 9, UpdatedAt: 100}
+	with := func(entry AnimeEntry, change func(*AnimeEntry)) AnimeEntry {
+		change(&entry)
+		return entry
+	}
+
+	type planned struct {
+		title  string
+		target string
+		added  bool
+		diffs  []string // Fields that differ
+		update string   // The update as JSON
+	}
+
+	tests := []struct {
+		name           string
+		anilist        []AnimeEntry
+		mal            []AnimeEntry
+		scoreFormat    string
+		policy         string
+		includePrivate bool
+		want           []planned
+		wantPrivate    []string
+	}{
+		{
+			name:        "lists match",
+			anilist:     []AnimeEntry{anilist},
+			mal:         []AnimeEntry{mal},
+			scoreFormat: ScorePoint100,
+			policy:      SyncNewest,
+		},
+		{
+			name:        "only on anilist",
+			anilist:     []AnimeEntry{anilist},
+			scoreFormat: ScorePoint100,
+			policy:      SyncNewest,
+			want: []planned{{
+				title:  "Frieren",
+				target: TrackerMyAnimeList,
+				added:  true,
+				update: `{"mediaId":1,"status":"CURRENT","score":9,"progress":10,"repeat":0,"startedAt":{"year":0,"month":0,"day":0},"completedAt":{"year":0,"month":0,"day":0}}`,
+			}},
+		},
+		{
+			name:        "only on mal",
+			mal:         []AnimeEntry{mal},
+			scoreFormat: ScorePoint100,
+			policy:      SyncNewest,
+			want: []planned{{
+				title:  "Frieren",
+				target: TrackerAniList,
+				added:  true,
+				update: `{"mediaId":1,"status":"CURRENT","score":90,"progress":10,"repeat":0,"startedAt":{"year":0,"month":0,"day":0},"completedAt":{"year":0,"month":0,"day":0}}`,
+			}},
+		},
+		{
+			name:        "not on mal at all",
+			anilist:     []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.MalId = 0 })},
+			scoreFormat: ScorePoint100,
+			policy:      SyncNewest,
+		},
+		{
+			name:        "newer anilist progress",
+			anilist:     []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.Progress = 12 })},
+			mal:         []AnimeEntry{mal},
+			scoreFormat: ScorePoint100,
+			policy:      SyncNewest,
+			want:        []planned{{title: "Frieren", target: TrackerMyAnimeList, diffs: []string{"progress"}, update: `{"mediaId":1,"progress":12}`}},
+		},
+		{
+			name:        "mal policy",
+			anilist:     []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.Progress = 12 })},
+			mal:         []AnimeEntry{mal},
+			scoreFormat: ScorePoint100,
+			policy:      SyncMyAnimeList,
+			want:        []planned{{title: "Frieren", target: TrackerAniList, diffs: []string{"progress"}, update: `{"mediaId":1,"progress":10}`}},
+		},
+		{
+			name:        "most progress beats newer",
+			anilist:     []AnimeEntry{anilist},
+			mal:         []AnimeEntry{with(mal, func(e *AnimeEntry) { e.Progress = 11 })},
+			scoreFormat: ScorePoint100,
+			policy:      SyncMostProgress,
+			want:        []planned{{title: "Frieren", target: TrackerAniList, diffs: []string{"progress"}, update: `{"mediaId":1,"progress":11}`}},
+		},
+		{
+			name:        "anilist policy",
+			anilist:     []AnimeEntry{anilist},
+			mal:         []AnimeEntry{with(mal, func(e *AnimeEntry) { e.Progress = 11; e.UpdatedAt = 300 })},
+			scoreFormat: ScorePoint100,
+			policy:      SyncAniList,
+			want:        []planned{{title: "Frieren", target: TrackerMyAnimeList, diffs: []string{"progress"}, update: `{"mediaId":1,"progress":10}`}},
+		},
+		{
+			name:        "repeating on anilist, completed on mal",
+			anilist:     []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.Status = "REPEATING" })},
+			mal:         []AnimeEntry{with(mal, func(e *AnimeEntry) { e.Status = "COMPLETED" })},
+			scoreFormat: ScorePoint100,
+			policy:      SyncNewest,
+			want:        []planned{{title: "Frieren", target: TrackerMyAnimeList, diffs: []string{"status"}, update: `{"mediaId":1,"status":"REPEATING"}`}},
+		},
+		{
+			name:        "completed on anilist, repeating on mal",
+			anilist:     []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.Status = "COMPLETED"; e.UpdatedAt = 50 })},
+			mal:         []AnimeEntry{with(mal, func(e *AnimeEntry) { e.Status = "REPEATING" })},
+			scoreFormat: ScorePoint100,
+			policy:      SyncNewest,
+			want:        []planned{{title: "Frieren", target: TrackerAniList, diffs: []string{"status"}, update: `{"mediaId":1,"status":"REPEATING"}`}},
+		},
+		{
+			name:        "scores equal across formats",
+			anilist:     []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.Score = 4 })},
+			mal:         []AnimeEntry{with(mal, func(e *AnimeEntry) { e.Score = 8 })},
+			scoreFormat: ScorePoint5,
+			policy:      SyncNewest,
+		},
+		{
+			name:        "anilist score sent to mal out of 10",
+			anilist:     []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.Score = 75 })},
+			mal:         []AnimeEntry{mal},
+			scoreFormat: ScorePoint100,
+			policy:      SyncAniList,
+			want:        []planned{{title: "Frieren", target: TrackerMyAnimeList, diffs: []string{"score"}, update: `{"mediaId":1,"score":8}`}},
+		},
+		{
+			name:        "mal score sent to anilist in the user's format",
+			anilist:     []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.Score = 2 })},
+			mal:         []AnimeEntry{with(mal, func(e *AnimeEntry) { e.Score = 8 })},
+			scoreFormat: ScorePoint5,
+			policy:      SyncMyAnimeList,
+			want:        []planned{{title: "Frieren", target: TrackerAniList, diffs: []string{"score"}, update: `{"mediaId":1,"score":4}`}},
+		},
+		{
+			name:        "mal score sent to anilist as decimal",
+			anilist:     []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.Score = 6.5 })},
+			mal:         []AnimeEntry{mal},
+			scoreFormat: ScorePoint10Decimal,
+			policy:      SyncMyAnimeList,
+			want:        []planned{{title: "Frieren", target: TrackerAniList, diffs: []string{"score"}, update: `{"mediaId":1,"score":9}`}},
+		},
+		{
+			name: "private entries left out",
+			anilist: []AnimeEntry{
+				with(anilist, func(e *AnimeEntry) { e.Private = true; e.Progress = 12 }),
+				{ID: 2, MalId: 102, Title: "Bocchi", Status: "PLANNING", Private: true},
+			},
+			mal:         []AnimeEntry{mal},
+			scoreFormat: ScorePoint100,
+			policy:      SyncNewest,
+			wantPrivate: []string{"Bocchi", "Frieren"},
+		},
+		{
+			name:           "private entries included",
+			anilist:        []AnimeEntry{with(anilist, func(e *AnimeEntry) { e.Private = true; e.Progress = 12 })},
+			mal:            []AnimeEntry{mal},
+			scoreFormat:    ScorePoint100,
+			policy:         SyncNewest,
+			includePrivate: true,
+			want:           []planned{{title: "Frieren", target: TrackerMyAnimeList, diffs: []string{"progress"}, update: `{"mediaId":1,"progress":12}`}},
+		},
+		{
+			name: "sorted by title",
+			anilist: []AnimeEntry{
+				{ID: 3, MalId: 103, Title: "Mushishi", Status: "COMPLETED"},
+				{ID: 2, MalId: 102, Title: "Bocchi", Status: "PLANNING"},
+			},
+			scoreFormat: ScorePoint100,
+			policy:      SyncNewest,
+			want: []planned{
+				{title: "Bocchi", target: TrackerMyAnimeList, added: true, update: `{"mediaId":2,"status":"PLANNING","score":0,"progress":0,"repeat":0,"startedAt":{"year":0,"month":0,"day":0},"completedAt":{"year":0,"month":0,"day":0}}`},
+				{title: "Mushishi", target: TrackerMyAnimeList, added: true, update: `{"mediaId":3,"status":"COMPLETED","score":0,"progress":0,"repeat":0,"startedAt":{"year":0,"month":0,"day":0},"completedAt":{"year":0,"month":0,"day":0}}`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lists := SyncLists{AniList: tt.anilist, AniListScoreFormat: tt.scoreFormat, MAL: tt.mal}
+			changes, private, err := PlanSync(lists, tt.policy, tt.includePrivate)
+			if err != nil {
+				t.Fatalf("PlanSync() = %v", err)
+			}
+
+			if len(changes) != len(tt.want) {
+				t.Fatalf("PlanSync() planned %d change(s), want %d: %+v", len(changes), len(tt.want), changes)
+			}
+			for i, want := range tt.want {
+				got := changes[i]
+				var diffs []string
+				for _, diff := range got.Diffs {
+					diffs = append(diffs, diff.Field)
+				}
+				if got.Title != want.title || got.Target != want.target || got.Added != want.added || !slices.Equal(diffs, want.diffs) {
+					t.Errorf("change %d = %s to %s, added %v, diffs %v, want %s to %s, added %v, diffs %v",
+						i, got.Title, got.Target, got.Added, diffs, want.title, want.target, want.added, want.diffs)
+				}
+				if update := updateJSON(t, got.Update); update != want.update {
+					t.Errorf("change %d update = %s, want %s", i, update, want.update)
+				}
+			}
+			if !slices.Equal(private, tt.wantPrivate) {
+				t.Errorf("PlanSync() left out %v, want %v", private, tt.wantPrivate)
+			}
+		})
+	}
+}
+
+func TestPlanSyncRejectsUnknownPolicy(t *testing.T) {
+	if _, _, err := PlanSync(SyncLists{}, "oldest", false); err == nil {
+		t.Error("PlanSync() with an unknown policy = nil, want an error")
+	}
+}
+
+func TestMALRepeatingStatus(t *testing.T) {
+	// MyAnimeList keeps a rewatch as a completed entry with a flag
+	tests := []struct {
+		status    malListStatus
+		want      string
+		wantSaved string // The MyAnimeList status sent for want
+	}{
+		{malListStatus{Status: "completed"}, "COMPLETED", "completed"},
+		{malListStatus{Status: "completed", IsRewatching: true}, "REPEATING", "completed"},
+		{malListStatus{Status: "watching"}, "CURRENT", "watching"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.status.aniListStatus(); got != tt.want {
+				t.Errorf("aniListStatus() = %s, want %s", got, tt.want)
+			}
+			if saved := malStatuses[tt.want]; saved != tt.wantSaved {
+				t.Errorf("malStatuses[%s] = %s, want %s", tt.want, saved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+// updateJSON returns an update as JSON, which leaves out the fields that aren't set
+func updateJSON(t *testing.T, update ListEntryUpdate) string {
+	t.Helper()
+	data, err := json.Marshal(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenExpiry decodes the expiry time from an AniList access token, which is a JWT
+func TokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode token payload: %w", err)
+	}
+
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse token payload: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("token has no expiry")
+	}
+
+	return time.Unix(int64(claims.Exp), 0), nil
+}
+
+// SetToken stores the token in the config along with its expiry
+func (c *Config) SetToken(token string) {
+	c.Token = token
+	c.TokenExpiresAt = 0
+	if expiry, err := TokenExpiry(token); err == nil {
+		c.TokenExpiresAt = expiry.Unix()
+	}
+}
+
+// TokenExpiresWithin reports whether the token expires within d, false when the expiry is unknown
+func (c *Config) TokenExpiresWithin(d time.Duration) bool {
+	if c.TokenExpiresAt == 0 {
+		return false
+	}
+	return time.Until(time.Unix(c.TokenExpiresAt, 0)) < d
+}
+
+// Relogin replaces the token of an AniList profile with a pasted one after checking it
+// belongs to the same user, then saves the profile
+func Relogin(config *Config, token string) error {
+	if err := setAniListToken(config, parsePastedToken(token)); err != nil {
+		return err
+	}
+	return SaveConfig(config)
+}
+
+// setAniListToken stores an AniList token in the config after checking it works.
+// A profile that already has a user must log in as the same user.
+func setAniListToken(config *Config, token string) error {
+	if token == "" {
+		return fmt.Errorf("no access token was provided")
+	}
+
+	viewer := *config
+	if err := NewAniListClient(token).loadViewer(&viewer); err != nil {
+		return fmt.Errorf("AniList rejected the token: %w", err)
+	}
+	if config.UserID != 0 && viewer.UserID != config.UserID {
+		return fmt.Errorf("token belongs to %s, not %s", viewer.Username, config.Username)
+	}
+
+	config.Username = viewer.Username
+	config.UserID = viewer.UserID
+	config.SetToken(token)
+	return nil
+}
@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Trackers a profile can keep its list on
+const (
+	TrackerAniList     = "anilist"
+	TrackerMyAnimeList = "mal"
+)
+
+// Tracker keeps the user's anime list on a tracking service. Entries are always keyed
+// by AniList media ID, trackers with their own IDs map them through idMal.
+type Tracker interface {
+	// Name returns the name of the service shown to the user
+	Name() string
+	// UpdateUserInfo fetches the user the tracker is logged in as and saves it in the config
+	UpdateUserInfo(config *Config) error
+	GetCurrentlyWatching(userID int) ([]AnimeEntry, error)
+	GetPlanned(userID int) ([]AnimeEntry, error)
+	GetCompleted(userID int) ([]AnimeEntry, error)
+	// GetList fetches the entries with any of the given statuses
+	GetList(userID int, statuses ...string) ([]AnimeEntry, error)
+	// GetScoreFormat returns the score format the user's scores are in
+	GetScoreFormat() (string, error)
+	// SaveEntry saves a list entry and returns it as stored by the tracker
+	SaveEntry(update ListEntryUpdate) (*MediaListEntry, error)
+	// SaveEntries saves many list entries at once. The returned entries and errors line up with the updates.
+	SaveEntries(updates []ListEntryUpdate) ([]*MediaListEntry, []error)
+	SetJournal(journal *MutationJournal)
+	PendingChanges() int
+	ReplayPending() (int, error)
+}
+
+var (
+	_ Tracker = (*AniListClient)(nil)
+	_ Tracker = (*MALClient)(nil)
+)
+
+// TrackerName returns the name of a tracker shown to the user
+func TrackerName(tracker string) string {
+	if tracker == TrackerMyAnimeList {
+		return "MyAnimeList"
+	}
+	return "AniList"
+}
+
+// UsesAniList reports whether the profile keeps its list on AniList
+func (c *Config) UsesAniList() bool {
+	return c.Tracker != TrackerMyAnimeList
+}
+
+// AddToList adds an anime to the user's list with the given status
+func AddToList(t Tracker, mediaID int, status string) (*MediaListEntry, error) {
+	return t.SaveEntry(ListEntryUpdate{
+		MediaID: mediaID,
+		Status:  &status,
+	})
+}
+
+// StartRewatch sets an anime to REPEATING and resets its progress
+func StartRewatch(t Tracker, mediaID int) error {
+	status := "REPEATING"
+	progress := 0
+	_, err := t.SaveEntry(ListEntryUpdate{
+		MediaID:  mediaID,
+		Status:   &status,
+		Progress: &progress,
+	})
+	return err
+}
+
+// UpdateProgress updates the progress of an anime
+func UpdateProgress(t Tracker, mediaID int, progress int) error {
+	return UpdateAnime(t, mediaID, progress, "")
+}
+
+// UpdateAnime updates both progress and status of an anime
+func UpdateAnime(t Tracker, mediaID int, progress int, status string) error {
+	update := ListEntryUpdate{
+		MediaID:  mediaID,
+		Progress: &progress,
+	}
+
+	if status != "" {
+		update.Status = &status
+	}
+
+	_, err := t.SaveEntry(update)
+	return err
+}
+
+// SetStatuses changes the status of many anime at once, returning an error per anime
+func SetStatuses(t Tracker, mediaIDs []int, status string) []error {
+	updates := make([]ListEntryUpdate, len(mediaIDs))
+	for i, mediaID := range mediaIDs {
+		updates[i] = ListEntryUpdate{
+			MediaID: mediaID,
+			Status:  &status,
+		}
+	}
+
+	_, errs := t.SaveEntries(updates)
+	return errs
+}
+
+// saveEach sends list changes one request at a time, for trackers without batch requests
+func saveEach(send func(ListEntryUpdate) (*MediaListEntry, error)) func([]ListEntryUpdate) ([]*MediaListEntry, []error) {
+	return func(updates []ListEntryUpdate) ([]*MediaListEntry, []error) {
+		entries := make([]*MediaListEntry, len(updates))
+		errs := make([]error, len(updates))
+		for i, update := range updates {
+			entries[i], errs[i] = send(update)
+		}
+		return entries, errs
+	}
+}
+
+// mutationQueue queues list changes in a journal while the tracker is unreachable
+type mutationQueue struct {
+	journal *MutationJournal
+}
+
+// SetJournal enables queueing list changes in the journal while the tracker is unreachable
+func (q *mutationQueue) SetJournal(journal *MutationJournal) {
+	q.journal = journal
+}
+
+// PendingChanges returns the number of list changes waiting to be sent
+func (q *mutationQueue) PendingChanges() int {
+	if q.journal == nil {
+		return 0
+	}
+	return len(q.journal.Pending())
+}
+
+// save sends a list change. If the tracker cannot be reached and a journal is set, the
+// change is queued and an error wrapping ErrQueued is returned.
+func (q *mutationQueue) save(update ListEntryUpdate, send func(ListEntryUpdate) (*MediaListEntry, error)) (*MediaListEntry, error) {
+	if q.journal == nil {
+		return send(update)
+	}
+
+	// Send queued changes for the anime along with the new ones so a later replay cannot overwrite them
+	update, revision := q.journal.Merged(update)
+
+	entry, err := send(update)
+	if err != nil {
+		if !IsTemporary(err) {
+			return nil, err
+		}
+		if err := q.journal.Add(update); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrQueued, err)
+	}
+
+	if err := q.journal.Resolve(update.MediaID, revision); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// saveAll sends many list changes at once. Like save, changes that fail because the tracker
+// cannot be reached are queued when a journal is set.
+func (q *mutationQueue) saveAll(updates []ListEntryUpdate, send func([]ListEntryUpdate) ([]*MediaListEntry, []error)) ([]*MediaListEntry, []error) {
+	if q.journal == nil {
+		return send(updates)
+	}
+
+	merged := make([]ListEntryUpdate, len(updates))
+	revisions := make([]int, len(updates))
+	for i, update := range updates {
+		merged[i], revisions[i] = q.journal.Merged(update)
+	}
+
+	entries, errs := send(merged)
+	for i, err := range errs {
+		if err == nil {
+			if err := q.journal.Resolve(merged[i].MediaID, revisions[i]); err != nil {
+				entries[i], errs[i] = nil, err
+			}
+			continue
+		}
+		if !IsTemporary(err) {
+			continue
+		}
+		if err := q.journal.Add(merged[i]); err != nil {
+			errs[i] = err
+			continue
+		}
+		errs[i] = fmt.Errorf("%w: %v", ErrQueued, err)
+	}
+	return entries, errs
+}
+
+// replay sends the queued list changes in order. It stops at the first change that fails
+// temporarily or because the login expired, keeping it and the rest queued. Changes the
+// tracker refuses outright are dropped and returned as DroppedErrors, other failed changes
+// stay queued.
+func (q *mutationQueue) replay(send func(ListEntryUpdate) (*MediaListEntry, error)) (int, error) {
+	if q.journal == nil {
+		return 0, nil
+	}
+
+	sent := 0
+	var errs []error
+	for _, entry := range q.journal.snapshot() {
+		_, err := send(entry.Update)
+		switch {
+		case err == nil:
+			sent++
+		case IsTemporary(err):
+			return sent, errors.Join(errs...)
+		case IsAuthError(err):
+			return sent, errors.Join(append(errs, err)...)
+		case IsRejected(err):
+			errs = append(errs, &DroppedError{Update: entry.Update, Err: err})
+		default:
+			errs = append(errs, err)
+			continue
+		}
+		if err := q.journal.Resolve(entry.Update.MediaID, entry.Revision); err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, errors.Join(errs...)
+}
@@ -10,18 +10,24 @@ import (
 // AnimeItem represents an item in the list UI
 type AnimeItem struct {
 	AnimeEntry internal.AnimeEntry
-	Index      int // Store the original index in the list
+	Index      int  // Store the original index in the list
+	Selected   bool // Selected for a bulk status change
 }
 
 func (i AnimeItem) Title() string {
+	if i.Selected {
+		return "✓ " + i.AnimeEntry.Title
+	}
 	return i.AnimeEntry.Title
 }
 
 func (i AnimeItem) Description() string {
 	var status string
 
-	// Check if the anime is currently airing
-	if i.AnimeEntry.IsAiring {
+	// Check if the anime is being rewatched or currently airing
+	if i.AnimeEntry.Status == "REPEATING" {
+		status = fmt.Sprintf("%d/%d episodes (Rewatch #%d)", i.AnimeEntry.Progress, i.AnimeEntry.Episodes, i.AnimeEntry.Repeat+1)
+	} else if i.AnimeEntry.IsAiring {
 		status = fmt.Sprintf("%d/%d episodes (Currently airing)", i.AnimeEntry.Progress, i.AnimeEntry.Episodes)
 	} else if i.AnimeEntry.Episodes > 0 {
 		status = fmt.Sprintf("%d/%d episodes", i.AnimeEntry.Progress, i.AnimeEntry.Episodes)
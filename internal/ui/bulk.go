@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/daannte/aniview/internal"
+)
+
+// BulkEdit holds the state of the multi-select mode used to change many statuses at once.
+// The selection itself is kept on the list items.
+type BulkEdit struct {
+	Status int // Index into internal.ListStatuses of the status to set
+	Saving bool
+	Err    string
+}
+
+// enterBulk starts selecting entries on the active tab
+func (m *Model) enterBulk() {
+	m.Bulk = BulkEdit{}
+	m.State = StateBulk
+}
+
+// leaveBulk clears the selection and returns to the lists
+func (m *Model) leaveBulk() tea.Cmd {
+	activeList := m.tabList(m.ActiveTab)
+	var cmds []tea.Cmd
+	for i, item := range activeList.Items() {
+		if animeItem, ok := item.(AnimeItem); ok && animeItem.Selected {
+			animeItem.Selected = false
+			cmds = append(cmds, activeList.SetItem(i, animeItem))
+		}
+	}
+	m.State = StateSelecting
+	return tea.Batch(cmds...)
+}
+
+// setSelected selects or deselects the anime with the given IDs on the active tab
+func (m *Model) setSelected(mediaIDs map[int]bool, selected bool) tea.Cmd {
+	activeList := m.tabList(m.ActiveTab)
+	var cmds []tea.Cmd
+	for i, item := range activeList.Items() {
+		if animeItem, ok := item.(AnimeItem); ok && mediaIDs[animeItem.AnimeEntry.ID] && animeItem.Selected != selected {
+			animeItem.Selected = selected
+			cmds = append(cmds, activeList.SetItem(i, animeItem))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// selectedEntries returns the selected entries of the active tab
+func (m *Model) selectedEntries() []internal.AnimeEntry {
+	var entries []internal.AnimeEntry
+	for _, item := range m.tabList(m.ActiveTab).Items() {
+		if animeItem, ok := item.(AnimeItem); ok && animeItem.Selected {
+			entries = append(entries, animeItem.AnimeEntry)
+		}
+	}
+	return entries
+}
+
+// SaveStatuses sets the status of many anime in as few requests as the tracker allows
+func (m *Model) SaveStatuses(entries []internal.AnimeEntry, status string) tea.Cmd {
+	return func() tea.Msg {
+		mediaIDs := make([]int, len(entries))
+		titles := make([]string, len(entries))
+		for i, entry := range entries {
+			mediaIDs[i] = entry.ID
+			titles[i] = entry.Title
+		}
+		errs := internal.SetStatuses(m.Tracker, mediaIDs, status)
+		return StatusesSavedMsg{Titles: titles, Errs: errs}
+	}
+}
+
+// handleBulkKey handles keyboard input while selecting entries
+func (m *Model) handleBulkKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	activeList := m.tabList(m.ActiveTab)
+	if m.Bulk.Saving {
+		return m, nil
+	}
+	if m.isFiltering() {
+		var cmd tea.Cmd
+		*activeList, cmd = activeList.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "esc", "ctrl+c", "v":
+		// Esc first clears an applied filter, like on the lists
+		if msg.String() == "esc" && activeList.FilterState() == list.FilterApplied {
+			activeList.ResetFilter()
+			return m, nil
+		}
+		return m, m.leaveBulk()
+	case " ":
+		if animeItem, ok := activeList.SelectedItem().(AnimeItem); ok {
+			cmd := m.setSelected(map[int]bool{animeItem.AnimeEntry.ID: true}, !animeItem.Selected)
+			activeList.CursorDown()
+			return m, cmd
+		}
+		return m, nil
+	case "a":
+		// Select every shown entry, or deselect them when all are selected already
+		visible := make(map[int]bool)
+		allSelected := true
+		for _, item := range activeList.VisibleItems() {
+			if animeItem, ok := item.(AnimeItem); ok {
+				visible[animeItem.AnimeEntry.ID] = true
+				allSelected = allSelected && animeItem.Selected
+			}
+		}
+		return m, m.setSelected(visible, !allSelected)
+	case "left", "h":
+		m.Bulk.Status = cycle(m.Bulk.Status, -1, len(internal.ListStatuses))
+		return m, nil
+	case "right", "l":
+		m.Bulk.Status = cycle(m.Bulk.Status, 1, len(internal.ListStatuses))
+		return m, nil
+	case "enter":
+		entries := m.selectedEntries()
+		if len(entries) == 0 {
+			m.Bulk.Err = "Select entries with Space first"
+			return m, nil
+		}
+		m.Bulk.Err = ""
+		m.Bulk.Saving = true
+		return m, m.SaveStatuses(entries, internal.ListStatuses[m.Bulk.Status])
+	}
+
+	var cmd tea.Cmd
+	*activeList, cmd = activeList.Update(msg)
+	return m, cmd
+}
+
+// handleStatusesSaved reloads the lists after a bulk status change, reporting the entries that failed
+func (m *Model) handleStatusesSaved(msg StatusesSavedMsg) (tea.Model, tea.Cmd) {
+	m.Bulk.Saving = false
+
+	var failed []string
+	var firstErr error
+	for i, err := range msg.Errs {
+		if err != nil && !errors.Is(err, internal.ErrQueued) {
+			failed = append(failed, msg.Titles[i])
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if len(failed) == len(msg.Titles) {
+		// Nothing changed, stay in the mode so the user can retry
+		m.Bulk.Err = fmt.Sprintf("Failed to update %d entries: %s", len(failed), errorText(firstErr))
+		return m, nil
+	}
+	if len(failed) > 0 {
+		m.SyncErr = fmt.Sprintf("Failed to update %s: %s", strings.Join(failed, ", "), errorText(firstErr))
+	}
+	// The reloaded lists come without a selection
+	return m.leaveScreen(true)
+}
+
+// viewBulk renders the active tab with the selection and the status to apply
+func (m *Model) viewBulk() string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("\n   %s\n", RenderTabs(m.Tabs, m.ActiveTab)))
+	selected := len(m.selectedEntries())
+	status := listName(internal.ListStatuses[m.Bulk.Status])
+	b.WriteString(fmt.Sprintf("   %s\n", InfoStyle.Render(fmt.Sprintf("%d selected, set status to: ‹ %s ›", selected, status))))
+	b.WriteString(m.tabList(m.ActiveTab).View())
+
+	if m.Bulk.Err != "" {
+		b.WriteString("\n   " + ErrorStyle.Render(m.Bulk.Err) + "\n")
+	}
+	if m.Bulk.Saving {
+		b.WriteString(fmt.Sprintf("\n   %s Saving...\n", m.Spinner.View()))
+	} else {
+		b.WriteString("\n   Space to select, [a] to select all, ←/→ to pick a status, Enter to apply, Esc to cancel\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/daannte/aniview/internal"
+)
+
+var chartSorts = []string{"Popularity", "Score", "Start date"}
+
+// SeasonChart holds the state of the seasonal chart screen
+type SeasonChart struct {
+	Season       string
+	Year         int
+	Media        []internal.Media
+	Loaded       bool     // Whether Media holds the season's anime, which may be none
+	Formats      []string // Formats present in the chart, in display order
+	ActiveFormat int
+	SortIndex    int
+	List         list.Model
+	Status       string
+	Err          string
+	Added        bool // Whether anything was added to the user's lists
+}
+
+// NewSeasonChart creates a chart for the given season
+func NewSeasonChart(season string, year int, chartList list.Model) SeasonChart {
+	return SeasonChart{
+		Season: season,
+		Year:   year,
+		List:   chartList,
+	}
+}
+
+// SetMedia replaces the chart contents and groups them by format
+func (c *SeasonChart) SetMedia(media []internal.Media) {
+	c.Media = media
+	c.Formats = nil
+	c.ActiveFormat = 0
+
+	for _, format := range internal.MediaFormats {
+		for _, m := range media {
+			if m.Format == format {
+				c.Formats = append(c.Formats, format)
+				break
+			}
+		}
+	}
+
+	c.refresh()
+}
+
+// refresh rebuilds the list for the active format using the current sort
+func (c *SeasonChart) refresh() {
+	if len(c.Formats) == 0 {
+		c.List.SetItems(nil)
+		return
+	}
+
+	format := c.Formats[c.ActiveFormat]
+	var group []internal.Media
+	for _, m := range c.Media {
+		if m.Format == format {
+			group = append(group, m)
+		}
+	}
+
+	sort.SliceStable(group, func(i, j int) bool {
+		switch c.SortIndex {
+		case 1:
+			return group[i].AverageScore > group[j].AverageScore
+		case 2:
+			return dateBefore(group[i].StartDate, group[j].StartDate)
+		default:
+			return group[i].Popularity > group[j].Popularity
+		}
+	})
+
+	items := make([]list.Item, len(group))
+	for i, m := range group {
+		items[i] = MediaItem{Media: m}
+	}
+	c.List.SetItems(items)
+	c.List.ResetSelected()
+}
+
+// SwitchFormat moves to another format group
+func (c *SeasonChart) SwitchFormat(delta int) {
+	if len(c.Formats) == 0 {
+		return
+	}
+	c.ActiveFormat = cycle(c.ActiveFormat, delta, len(c.Formats))
+	c.refresh()
+}
+
+// NextSort cycles to the next sort order
+func (c *SeasonChart) NextSort() {
+	c.SortIndex = cycle(c.SortIndex, 1, len(chartSorts))
+	c.refresh()
+}
+
+// MarkListed records that a media was added to the user's list with the given status
+func (c *SeasonChart) MarkListed(mediaID int, status string) {
+	for i := range c.Media {
+		if c.Media[i].ID == mediaID {
+			c.Media[i].MediaListEntry = &internal.MediaListStatus{Status: status}
+		}
+	}
+
+	index := c.List.Index()
+	c.refresh()
+	c.List.Select(index)
+}
+
+// View renders the chart
+func (c SeasonChart) View() string {
+	var b strings.Builder
+
+	title := fmt.Sprintf("%s %d", seasonName(c.Season), c.Year)
+	b.WriteString(fmt.Sprintf("\n   %s   Sort: %s\n\n", TitleStyle.Render(title), chartSorts[c.SortIndex]))
+
+	if len(c.Formats) > 0 {
+		tabs := make([]string, len(c.Formats))
+		for i, format := range c.Formats {
+			count := 0
+			for _, m := range c.Media {
+				if m.Format == format {
+					count++
+				}
+			}
+			tabs[i] = fmt.Sprintf("%s (%d)", format, count)
+		}
+		b.WriteString(fmt.Sprintf("   %s\n\n", RenderTabs(tabs, c.ActiveFormat)))
+		b.WriteString(c.List.View())
+	} else if c.Err == "" {
+		b.WriteString("   Nothing found for this season.\n")
+	}
+
+	if c.Err != "" {
+		b.WriteString("\n   " + ErrorStyle.Render(c.Err) + "\n")
+	} else if c.Status != "" {
+		b.WriteString("\n   " + InfoStyle.Render(c.Status) + "\n")
+	}
+
+	b.WriteString("\n\n   Tab to switch format, [ and ] to change season, [o] to sort, [p] to add to Planning, Esc to go back\n")
+	return b.String()
+}
+
+// dateBefore reports whether a is earlier than b, placing unknown dates last
+func dateBefore(a, b internal.Date) bool {
+	if a.IsEmpty() != b.IsEmpty() {
+		return b.IsEmpty()
+	}
+	if a.Year != b.Year {
+		return a.Year < b.Year
+	}
+	if a.Month != b.Month {
+		return a.Month < b.Month
+	}
+	return a.Day < b.Day
+}
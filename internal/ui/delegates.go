@@ -33,10 +33,11 @@ func (d CompactDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
 
 // Render renders the item
 func (d CompactDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
-	var title string
+	var title, details string
 
 	if i, ok := item.(EpisodeItem); ok {
 		title = i.Title()
+		details = i.Description()
 	} else {
 		title = "Unknown item"
 	}
@@ -46,6 +47,10 @@ func (d CompactDelegate) Render(w io.Writer, m list.Model, index int, item list.
 	} else {
 		fmt.Fprint(w, "  "+title)
 	}
+	// Show filler and recap markers and the air date after the title
+	if details != "" {
+		fmt.Fprint(w, "  "+MutedStyle.Render(details))
+	}
 }
 
 // NewCompactDelegate creates a new compact delegate for episode items
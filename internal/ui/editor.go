@@ -0,0 +1,234 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/daannte/aniview/internal"
+)
+
+// Editor fields in the order they are shown
+const (
+	fieldStatus = iota
+	fieldScore
+	fieldStarted
+	fieldCompleted
+	fieldRepeat
+	fieldNotes
+	fieldPrivate
+	fieldHidden
+	fieldCount
+)
+
+var fieldLabels = []string{"Status", "Score", "Started", "Completed", "Rewatches", "Notes", "Private", "Hide from status lists"}
+
+// EntryEditor holds the state of the list entry editor screen
+type EntryEditor struct {
+	Anime       AnimeItem
+	Tab         int
+	ScoreFormat string
+	Focus       int
+	StatusIndex int
+	Private     bool
+	Hidden      bool
+	Inputs      []textinput.Model // Indexed by field, only text fields are used
+	Err         string
+}
+
+// NewEntryEditor creates an editor prefilled with the entry's current values
+func NewEntryEditor(item AnimeItem, tab int, scoreFormat string) EntryEditor {
+	entry := item.AnimeEntry
+
+	statusIndex := 0
+	for i, status := range internal.ListStatuses {
+		if status == entry.Status {
+			statusIndex = i
+			break
+		}
+	}
+
+	inputs := make([]textinput.Model, fieldCount)
+	values := map[int]string{
+		fieldScore:     internal.FormatScore(scoreFormat, entry.Score),
+		fieldStarted:   internal.FormatDate(entry.StartedAt),
+		fieldCompleted: internal.FormatDate(entry.CompletedAt),
+		fieldRepeat:    strconv.Itoa(entry.Repeat),
+		fieldNotes:     entry.Notes,
+	}
+	placeholders := map[int]string{
+		fieldScore:     fmt.Sprintf("0-%g", internal.ScoreRange(scoreFormat)),
+		fieldStarted:   "YYYY-MM-DD",
+		fieldCompleted: "YYYY-MM-DD",
+		fieldRepeat:    "0",
+		fieldNotes:     "Notes",
+	}
+	for field, value := range values {
+		input := textinput.New()
+		input.Prompt = ""
+		input.Placeholder = placeholders[field]
+		input.SetValue(value)
+		inputs[field] = input
+	}
+	inputs[fieldNotes].CharLimit = 0
+	inputs[fieldNotes].Width = 60
+
+	return EntryEditor{
+		Anime:       item,
+		Tab:         tab,
+		ScoreFormat: scoreFormat,
+		Focus:       fieldStatus,
+		StatusIndex: statusIndex,
+		Private:     entry.Private,
+		Hidden:      entry.HiddenFromStatusLists,
+		Inputs:      inputs,
+	}
+}
+
+// isTextField reports whether the field is edited through a text input
+func isTextField(field int) bool {
+	return field != fieldStatus && field != fieldPrivate && field != fieldHidden
+}
+
+// setFocus moves focus to the given field
+func (e *EntryEditor) setFocus(field int) tea.Cmd {
+	if isTextField(e.Focus) {
+		e.Inputs[e.Focus].Blur()
+	}
+	e.Focus = cycle(field, 0, fieldCount)
+	if isTextField(e.Focus) {
+		return e.Inputs[e.Focus].Focus()
+	}
+	return nil
+}
+
+// Update handles input for the editor
+func (e EntryEditor) Update(msg tea.Msg) (EntryEditor, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if isTextField(e.Focus) {
+			var cmd tea.Cmd
+			e.Inputs[e.Focus], cmd = e.Inputs[e.Focus].Update(msg)
+			return e, cmd
+		}
+		return e, nil
+	}
+
+	switch keyMsg.String() {
+	case "tab", "down":
+		return e, e.setFocus(e.Focus + 1)
+	case "shift+tab", "up":
+		return e, e.setFocus(e.Focus - 1)
+	case "left", "right", " ":
+		switch e.Focus {
+		case fieldStatus:
+			if keyMsg.String() == "left" {
+				e.StatusIndex = cycle(e.StatusIndex, -1, len(internal.ListStatuses))
+			} else {
+				e.StatusIndex = cycle(e.StatusIndex, 1, len(internal.ListStatuses))
+			}
+			return e, nil
+		case fieldPrivate:
+			e.Private = !e.Private
+			return e, nil
+		case fieldHidden:
+			e.Hidden = !e.Hidden
+			return e, nil
+		}
+	}
+
+	if isTextField(e.Focus) {
+		var cmd tea.Cmd
+		e.Inputs[e.Focus], cmd = e.Inputs[e.Focus].Update(msg)
+		return e, cmd
+	}
+	return e, nil
+}
+
+// Build validates the editor fields and returns the update to send to AniList
+func (e EntryEditor) Build() (internal.ListEntryUpdate, error) {
+	score, err := internal.ParseScore(e.ScoreFormat, e.Inputs[fieldScore].Value())
+	if err != nil {
+		return internal.ListEntryUpdate{}, fmt.Errorf("Score: %w", err)
+	}
+
+	startedAt, err := internal.ParseDate(e.Inputs[fieldStarted].Value())
+	if err != nil {
+		return internal.ListEntryUpdate{}, fmt.Errorf("Started: %w", err)
+	}
+
+	completedAt, err := internal.ParseDate(e.Inputs[fieldCompleted].Value())
+	if err != nil {
+		return internal.ListEntryUpdate{}, fmt.Errorf("Completed: %w", err)
+	}
+
+	repeat := 0
+	if value := strings.TrimSpace(e.Inputs[fieldRepeat].Value()); value != "" {
+		repeat, err = strconv.Atoi(value)
+		if err != nil || repeat < 0 {
+			return internal.ListEntryUpdate{}, fmt.Errorf("Rewatches: must be a whole number of 0 or more")
+		}
+	}
+
+	status := internal.ListStatuses[e.StatusIndex]
+	notes := e.Inputs[fieldNotes].Value()
+	private := e.Private
+	hidden := e.Hidden
+
+	return internal.ListEntryUpdate{
+		MediaID:               e.Anime.AnimeEntry.ID,
+		Status:                &status,
+		Score:                 &score,
+		Repeat:                &repeat,
+		Notes:                 &notes,
+		Private:               &private,
+		HiddenFromStatusLists: &hidden,
+		StartedAt:             &startedAt,
+		CompletedAt:           &completedAt,
+	}, nil
+}
+
+// View renders the editor
+func (e EntryEditor) View() string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("\n   %s\n\n", TitleStyle.Render("Edit "+e.Anime.AnimeEntry.Title)))
+
+	for field := 0; field < fieldCount; field++ {
+		var value string
+		switch field {
+		case fieldStatus:
+			value = "< " + internal.ListStatuses[e.StatusIndex] + " >"
+		case fieldPrivate:
+			value = checkbox(e.Private)
+		case fieldHidden:
+			value = checkbox(e.Hidden)
+		default:
+			value = e.Inputs[field].View()
+		}
+
+		label := fmt.Sprintf("%-24s", fieldLabels[field])
+		if field == e.Focus {
+			b.WriteString("   " + SelectedStyle.Render("> "+label) + value + "\n")
+		} else {
+			b.WriteString("     " + label + value + "\n")
+		}
+	}
+
+	if e.Err != "" {
+		b.WriteString("\n   " + ErrorStyle.Render(e.Err) + "\n")
+	}
+
+	b.WriteString("\n   Tab/arrows to move, ←/→ or space to change, Enter to save, Esc to cancel\n")
+	return b.String()
+}
+
+// checkbox renders a boolean as a checkbox
+func checkbox(checked bool) string {
+	if checked {
+		return "[x]"
+	}
+	return "[ ]"
+}
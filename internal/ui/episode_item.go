@@ -3,21 +3,45 @@ package ui
 import (
 	"fmt"
 	"strconv"
+	"strings"
+
+	"github.com/daannte/aniview/internal"
 )
 
 // EpisodeItem represents an episode in the episode list
 type EpisodeItem struct {
 	Number int
+	Info   *internal.Episode // nil until the metadata is loaded, or when Jikan has none
 }
 
 func (e EpisodeItem) Title() string {
+	if e.Info != nil && e.Info.Title != "" {
+		return fmt.Sprintf("Episode %d: %s", e.Number, e.Info.Title)
+	}
 	return fmt.Sprintf("Episode %d", e.Number)
 }
 
 func (e EpisodeItem) Description() string {
-	return ""
+	if e.Info == nil {
+		return ""
+	}
+
+	var details []string
+	if e.Info.Filler {
+		details = append(details, "Filler")
+	}
+	if e.Info.Recap {
+		details = append(details, "Recap")
+	}
+	if !e.Info.Aired.IsZero() {
+		details = append(details, "Aired "+e.Info.Aired.Format("2006-01-02"))
+	}
+	return strings.Join(details, " · ")
 }
 
 func (e EpisodeItem) FilterValue() string {
+	if e.Info != nil {
+		return strconv.Itoa(e.Number) + " " + e.Info.Title
+	}
 	return strconv.Itoa(e.Number)
 }
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/daannte/aniview/internal"
+)
+
+// LoadEpisodes fetches the episode metadata of a show from Jikan
+func (m *Model) LoadEpisodes(malID int) tea.Cmd {
+	return func() tea.Msg {
+		episodes, err := internal.GetEpisodes(malID)
+		return EpisodesMsg{MalID: malID, Episodes: episodes, Err: err}
+	}
+}
+
+// openEpisodeList shows the episode list of an anime with the given episode selected.
+// next tells whether that is the next unwatched episode, which skipping filler may move past.
+func (m *Model) openEpisodeList(selectedItem AnimeItem, episode int, next bool) tea.Cmd {
+	m.SelectedAnime = &selectedItem
+	m.State = StateEpisode
+	m.EpisodeNext = next
+	m.fillEpisodeList(episode)
+
+	// Episode metadata is fetched once per show
+	malID := selectedItem.AnimeEntry.MalId
+	if _, ok := m.EpisodeInfo[malID]; ok || malID == 0 {
+		return nil
+	}
+	return m.LoadEpisodes(malID)
+}
+
+// fillEpisodeList fills the episode list with the metadata loaded so far, leaving out
+// filler when it is hidden, and selects the given episode
+func (m *Model) fillEpisodeList(episode int) {
+	info := make(map[int]*internal.Episode)
+	episodes := m.EpisodeInfo[m.SelectedAnime.AnimeEntry.MalId]
+	for i := range episodes {
+		info[episodes[i].Number] = &episodes[i]
+	}
+	isFiller := func(number int) bool {
+		return info[number] != nil && info[number].Filler
+	}
+
+	filler := m.Config.Episodes.Filler
+	episodeCount := m.SelectedAnime.AnimeEntry.Episodes
+	items := make([]list.Item, 0, episodeCount)
+	for number := 1; number <= episodeCount; number++ {
+		if filler == internal.FillerHide && isFiller(number) {
+			continue
+		}
+		items = append(items, EpisodeItem{Number: number, Info: info[number]})
+	}
+	m.EpisodeList.SetItems(items)
+
+	// Move past filler to the next episode worth watching
+	if filler == internal.FillerSkip && m.EpisodeNext {
+		for episode < episodeCount && isFiller(episode) {
+			episode++
+		}
+	}
+	for i, item := range items {
+		if item.(EpisodeItem).Number >= episode {
+			m.EpisodeList.Select(i)
+			return
+		}
+	}
+	if len(items) > 0 {
+		m.EpisodeList.Select(len(items) - 1)
+	}
+}
+
+// selectedEpisode returns the number of the selected episode, or 0 when the list is empty
+func (m *Model) selectedEpisode() int {
+	if item, ok := m.EpisodeList.SelectedItem().(EpisodeItem); ok {
+		return item.Number
+	}
+	return 0
+}
+
+// handleEpisodes stores the episode metadata of a show, refreshing its episode list if it is open
+func (m *Model) handleEpisodes(msg EpisodesMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		// The list works without metadata, leave it as it is
+		return m, nil
+	}
+
+	if m.EpisodeInfo == nil {
+		m.EpisodeInfo = make(map[int][]internal.Episode)
+	}
+	m.EpisodeInfo[msg.MalID] = msg.Episodes
+
+	if m.State == StateEpisode && m.SelectedAnime.AnimeEntry.MalId == msg.MalID {
+		m.fillEpisodeList(m.selectedEpisode())
+	}
+	return m, nil
+}
+
+// onlyFillerBefore reports whether filler is skipped or hidden and every episode between
+// the entry's progress and the given episode is known to be filler
+func (m *Model) onlyFillerBefore(entry internal.AnimeEntry, episode int) bool {
+	if m.Config.Episodes.Filler == internal.FillerShow || episode <= entry.Progress+1 {
+		return false
+	}
+
+	filler := make(map[int]bool)
+	for _, info := range m.EpisodeInfo[entry.MalId] {
+		filler[info.Number] = info.Filler
+	}
+	for number := entry.Progress + 1; number < episode; number++ {
+		if !filler[number] {
+			return false
+		}
+	}
+	return true
+}
+
+// cycleFiller switches to the next way of treating filler episodes and saves it
+func (m *Model) cycleFiller() tea.Cmd {
+	current := 0
+	for i, mode := range internal.FillerModes {
+		if mode == m.Config.Episodes.Filler {
+			current = i
+		}
+	}
+	m.Config.Episodes.Filler = internal.FillerModes[cycle(current, 1, len(internal.FillerModes))]
+	m.fillEpisodeList(m.selectedEpisode())
+
+	config := *m.Config
+	return func() tea.Msg {
+		if err := internal.SaveConfig(&config); err != nil {
+			return ErrMsg{Err: err}
+		}
+		return nil
+	}
+}
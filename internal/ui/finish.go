@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/daannte/aniview/internal"
+)
+
+// FinishPrompt holds the state of the screen shown after watching the final episode of a series
+type FinishPrompt struct {
+	Anime     AnimeItem
+	Completed bool
+	Changed   bool // Whether any list was changed from this screen
+	Relations list.Model
+	Loading   bool
+	Status    string
+	Err       string
+}
+
+// NewFinishPrompt creates the prompt for a finished anime
+func NewFinishPrompt(anime AnimeItem, relations list.Model) FinishPrompt {
+	relations.SetItems(nil)
+	relations.ResetSelected()
+
+	return FinishPrompt{
+		Anime:     anime,
+		Relations: relations,
+		Loading:   true,
+	}
+}
+
+// SetRelations shows the related anime, sequels first
+func (f *FinishPrompt) SetRelations(relations []internal.MediaRelation) {
+	var items []list.Item
+	for _, relationType := range []string{"SEQUEL", "SIDE_STORY", "PREQUEL"} {
+		for _, relation := range relations {
+			if relation.RelationType == relationType {
+				items = append(items, MediaItem{Media: relation.Media, Relation: relation.RelationType})
+			}
+		}
+	}
+
+	f.Relations.SetItems(items)
+	f.Relations.ResetSelected()
+	f.Loading = false
+}
+
+// MarkListed records that a related anime was added to the user's list with the given status
+func (f *FinishPrompt) MarkListed(mediaID int, status string) {
+	markListed(&f.Relations, mediaID, status)
+}
+
+// View renders the prompt
+func (f FinishPrompt) View() string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("\n\n   %s\n\n", TitleStyle.Render("Finished "+f.Anime.AnimeEntry.Title)))
+
+	if f.Completed {
+		b.WriteString("   Marked as Completed.\n\n")
+	} else {
+		b.WriteString("   You watched the final episode. Press [y] to mark it as Completed.\n\n")
+	}
+
+	switch {
+	case f.Loading:
+		b.WriteString("   Looking for related anime...\n")
+	case len(f.Relations.Items()) == 0:
+		b.WriteString("   No sequels or related anime found.\n")
+	default:
+		b.WriteString(f.Relations.View())
+	}
+
+	if f.Err != "" {
+		b.WriteString("\n   " + ErrorStyle.Render(f.Err) + "\n")
+	} else if f.Status != "" {
+		b.WriteString("\n   " + InfoStyle.Render(f.Status) + "\n")
+	}
+
+	b.WriteString("\n\n   Press [p] to add to Planning, [c] to add to Currently Watching, Esc when done\n")
+	return b.String()
+}
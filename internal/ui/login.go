@@ -0,0 +1,245 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/daannte/aniview/internal"
+	"github.com/pkg/browser"
+)
+
+// loginTimeout is how long the login screen waits for the redirect
+const loginTimeout = 10 * time.Minute
+
+// expiryWarning is how long before the token expires the user is warned
+const expiryWarning = 14 * 24 * time.Hour
+
+// LoginPrompt holds the state of the screen for logging in to the profile's tracker again
+type LoginPrompt struct {
+	Reason  string
+	Tracker string
+	Session *internal.LoginSession // nil when the loopback listener could not start
+	AuthURL string
+	Input   textinput.Model // Only used for AniList, which shows the token to paste
+	Saving  bool
+	Err     string
+}
+
+// NewLoginPrompt creates a login screen, starting the loopback listener when possible
+func NewLoginPrompt(config *internal.Config, reason string) LoginPrompt {
+	input := textinput.New()
+	input.Prompt = ""
+	input.Placeholder = "Paste the token or the whole URL"
+	input.CharLimit = 0
+	input.Width = 60
+
+	prompt := LoginPrompt{Reason: reason, Tracker: config.Tracker, Input: input}
+
+	session, err := internal.StartLogin(config)
+	if err != nil {
+		prompt.Err = err.Error()
+		// Fall back to pasting the token shown by AniList
+		if config.UsesAniList() {
+			prompt.AuthURL = internal.ManualAuthURL()
+		}
+	} else {
+		prompt.Session = session
+		prompt.AuthURL = session.AuthURL
+	}
+	return prompt
+}
+
+// canPaste reports whether the token can be pasted instead of captured
+func (l LoginPrompt) canPaste() bool {
+	return l.Tracker != internal.TrackerMyAnimeList
+}
+
+// Close stops the loopback listener
+func (l *LoginPrompt) Close() {
+	if l.Session != nil {
+		l.Session.Close()
+		l.Session = nil
+	}
+}
+
+// View renders the login screen
+func (l LoginPrompt) View() string {
+	var b strings.Builder
+
+	tracker := internal.TrackerName(l.Tracker)
+	b.WriteString(fmt.Sprintf("\n\n   %s\n\n", TitleStyle.Render("Log in to "+tracker)))
+	if l.Reason != "" {
+		b.WriteString("   " + l.Reason + "\n\n")
+	}
+
+	if l.Session != nil {
+		b.WriteString(fmt.Sprintf("   Waiting for %s in your browser. If it didn't open, visit:\n", tracker))
+		b.WriteString("   " + InfoStyle.Render(l.AuthURL) + "\n\n")
+		if l.canPaste() {
+			// AniList refuses the redirect when it isn't registered for the client
+			b.WriteString("   If AniList shows an error, log in at the following URL and paste the token below:\n")
+			b.WriteString("   " + InfoStyle.Render(internal.ManualAuthURL()) + "\n\n")
+		}
+	} else if l.canPaste() {
+		b.WriteString("   Log in at the following URL and paste the token below:\n")
+		b.WriteString("   " + InfoStyle.Render(l.AuthURL) + "\n\n")
+	}
+	if l.canPaste() {
+		b.WriteString("   Token: " + l.Input.View() + "\n")
+	}
+
+	if l.Saving {
+		b.WriteString("\n   " + InfoStyle.Render("Checking login...") + "\n")
+	} else if l.Err != "" {
+		b.WriteString("\n   " + ErrorStyle.Render(l.Err) + "\n")
+	}
+
+	if l.canPaste() {
+		b.WriteString("\n   Press Enter to use the pasted token, Esc to go back\n")
+	} else {
+		b.WriteString("\n   Press Esc to go back\n")
+	}
+	return b.String()
+}
+
+// openLogin shows the login screen and opens the browser
+func (m *Model) openLogin(reason string) tea.Cmd {
+	m.Login.Close()
+	m.Login = NewLoginPrompt(m.Config, reason)
+	m.Loading = false
+	m.State = StateLogin
+
+	authURL := m.Login.AuthURL
+	openBrowser := func() tea.Msg {
+		browser.OpenURL(authURL)
+		return nil
+	}
+
+	if authURL == "" {
+		return nil
+	}
+	cmds := []tea.Cmd{m.Login.Input.Focus(), openBrowser}
+	if m.Login.Session != nil {
+		cmds = append(cmds, m.WaitForLogin(m.Login.Session))
+	}
+	return tea.Batch(cmds...)
+}
+
+// WaitForLogin waits for the redirect carrying the token or authorization code
+func (m *Model) WaitForLogin(session *internal.LoginSession) tea.Cmd {
+	return func() tea.Msg {
+		secret, err := session.Wait(loginTimeout)
+		return LoginTokenMsg{Session: session, Secret: secret, Err: err}
+	}
+}
+
+// SaveLogin checks what the redirect carried, or the pasted token when session is nil,
+// and stores the new credentials in the current profile
+func (m *Model) SaveLogin(session *internal.LoginSession, secret string) tea.Cmd {
+	m.Login.Saving = true
+	m.Login.Err = ""
+	config := *m.Config
+	return func() tea.Msg {
+		var err error
+		if session != nil {
+			if err = session.Complete(&config, secret); err == nil {
+				err = internal.SaveConfig(&config)
+			}
+		} else {
+			err = internal.Relogin(&config, secret)
+		}
+		if err != nil {
+			return LoggedInMsg{Err: err}
+		}
+		return LoggedInMsg{Config: config}
+	}
+}
+
+// handleLoginKey handles keyboard input on the login screen
+func (m *Model) handleLoginKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.Login.Close()
+		return m, tea.Quit
+	case "esc":
+		m.Login.Close()
+		m.State = StateSelecting
+		return m, nil
+	case "enter":
+		if m.Login.canPaste() && !m.Login.Saving && strings.TrimSpace(m.Login.Input.Value()) != "" {
+			return m, m.SaveLogin(nil, m.Login.Input.Value())
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.Login.Input, cmd = m.Login.Input.Update(msg)
+	return m, cmd
+}
+
+// handleLoginToken handles the token captured from the AniList redirect
+func (m *Model) handleLoginToken(msg LoginTokenMsg) (tea.Model, tea.Cmd) {
+	// Ignore a listener that was already closed
+	if m.State != StateLogin || msg.Session != m.Login.Session {
+		return m, nil
+	}
+	if msg.Err != nil {
+		m.Login.Close()
+		m.Login.Err = msg.Err.Error()
+		if m.Login.canPaste() {
+			m.Login.Err += ", paste the token instead"
+			m.Login.AuthURL = internal.ManualAuthURL()
+		}
+		return m, nil
+	}
+	return m, m.SaveLogin(msg.Session, msg.Secret)
+}
+
+// handleLoggedIn handles the result of logging in again
+func (m *Model) handleLoggedIn(msg LoggedInMsg) (tea.Model, tea.Cmd) {
+	if m.State != StateLogin {
+		return m, nil
+	}
+	m.Login.Saving = false
+	if msg.Err != nil {
+		m.Login.Err = msg.Err.Error()
+		return m, nil
+	}
+	*m.Config = msg.Config
+	// The new clients keep using the profile's open journal
+	tracker, anilist, err := internal.NewProfileClients(m.Config)
+	if err != nil {
+		m.Login.Err = err.Error()
+		return m, nil
+	}
+	m.setClients(tracker, anilist)
+	m.Login.Close()
+	return m.leaveScreen(true)
+}
+
+// showError shows an error, offering to log in again when AniList rejected the token
+func (m *Model) showError(err error) (tea.Model, tea.Cmd) {
+	if internal.IsAuthError(err) {
+		return m, m.openLogin(fmt.Sprintf("Your %s login has expired or was revoked.", internal.TrackerName(m.Config.Tracker)))
+	}
+	m.Err = err
+	m.Loading = false
+	m.State = StateError
+	return m, nil
+}
+
+// expiryNotice returns a warning when the token is about to expire
+func (m *Model) expiryNotice() string {
+	// MyAnimeList tokens are refreshed automatically
+	if !m.Config.UsesAniList() || !m.Config.TokenExpiresWithin(expiryWarning) {
+		return ""
+	}
+	expiry := time.Unix(m.Config.TokenExpiresAt, 0)
+	if time.Now().After(expiry) {
+		return internal.TrackerName(m.Config.Tracker) + " login expired, press [L] to log in again"
+	}
+	return fmt.Sprintf("%s login expires on %s, press [L] to log in again", internal.TrackerName(m.Config.Tracker), expiry.Format("2 Jan 2006"))
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/daannte/aniview/internal"
+)
+
+// MediaItem represents an AniList catalog entry in the UI
+type MediaItem struct {
+	Media    internal.Media
+	Relation string // How the media relates to another one, if shown as a relation
+	Rating   int    // Recommendation rating, if shown as a recommendation
+}
+
+func (i MediaItem) Title() string {
+	if i.Relation != "" {
+		return relationName(i.Relation) + ": " + i.Media.Title.Preferred()
+	}
+	return i.Media.Title.Preferred()
+}
+
+func (i MediaItem) Description() string {
+	var parts []string
+
+	if i.Media.Format != "" {
+		parts = append(parts, i.Media.Format)
+	}
+	if i.Media.AverageScore > 0 {
+		parts = append(parts, fmt.Sprintf("%d%%", i.Media.AverageScore))
+	}
+	if i.Media.Episodes > 0 {
+		parts = append(parts, fmt.Sprintf("%d episodes", i.Media.Episodes))
+	} else {
+		parts = append(parts, "? episodes")
+	}
+	if i.Rating > 0 {
+		parts = append(parts, fmt.Sprintf("+%d recommended", i.Rating))
+	}
+	if i.Media.MediaListEntry != nil {
+		parts = append(parts, "On list: "+listName(i.Media.MediaListEntry.Status))
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+func (i MediaItem) FilterValue() string {
+	return i.Media.Title.Preferred()
+}
+
+// markListed records that a media shown in a list was added to the user's list with the given status
+func markListed(l *list.Model, mediaID int, status string) {
+	for i, item := range l.Items() {
+		if mediaItem, ok := item.(MediaItem); ok && mediaItem.Media.ID == mediaID {
+			mediaItem.Media.MediaListEntry = &internal.MediaListStatus{Status: status}
+			l.SetItem(i, mediaItem)
+		}
+	}
+}
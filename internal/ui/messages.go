@@ -1,13 +1,21 @@
 package ui
 
-import "github.com/daannte/aniview/internal"
+import (
+	"time"
+
+	"github.com/daannte/aniview/internal"
+)
 
 // Custom tea.Msg types for the UI
 
 // AnimeListsMsg contains the anime lists data from the API
 type AnimeListsMsg struct {
-	Watching []internal.AnimeEntry
-	Planned  []internal.AnimeEntry
+	Watching            []internal.AnimeEntry
+	Planned             []internal.AnimeEntry
+	Completed           []internal.AnimeEntry
+	ScoreFormat         string
+	UnreadNotifications int
+	SyncErr             error // Why queued list changes were not sent, including the ones that were dropped
 }
 
 // ErrMsg represents an error message
@@ -25,7 +33,132 @@ type EpisodePlayedMsg struct {
 	Err error
 }
 
+// ProgressSavedMsg represents the result of saving the progress of a watched episode
+type ProgressSavedMsg struct {
+	Anime   AnimeItem // The anime as it was before the episode was watched
+	Tab     int       // Tab the anime is listed on
+	Episode int
+	Update  internal.ListEntryUpdate
+	Err     error
+}
+
+// MovedToWatchingMsg represents the result of moving a planned anime to Currently Watching
+type MovedToWatchingMsg struct {
+	Err error
+}
+
 // StatusChangeMsg represents a confirmation for status change
 type StatusChangeMsg struct {
 	Confirmed bool
 }
+
+// EntrySavedMsg represents the result of saving an entry from the editor
+type EntrySavedMsg struct {
+	Entry  *internal.MediaListEntry
+	Update internal.ListEntryUpdate
+	Err    error
+}
+
+// EpisodesMsg contains the episode metadata of a show from Jikan
+type EpisodesMsg struct {
+	MalID    int
+	Episodes []internal.Episode
+	Err      error
+}
+
+// StatusesSavedMsg represents the result of a bulk status change, with an error per anime
+type StatusesSavedMsg struct {
+	Titles []string
+	Errs   []error
+}
+
+// CatalogResultsMsg contains the results of an AniList catalog search
+type CatalogResultsMsg struct {
+	Results []internal.Media
+	Err     error
+}
+
+// AddedToListMsg represents the result of adding an anime to the user's list
+type AddedToListMsg struct {
+	MediaID int
+	Title   string
+	Status  string
+	Err     error
+}
+
+// CompletedMsg represents the result of marking a finished series as completed
+type CompletedMsg struct {
+	Err error
+}
+
+// SeasonChartMsg contains the anime of a season chart
+type SeasonChartMsg struct {
+	Season string
+	Year   int
+	Media  []internal.Media
+	Err    error
+}
+
+// ScheduleMsg contains the airing schedule for a week
+type ScheduleMsg struct {
+	WeekStart time.Time
+	Airings   []internal.AiringSchedule
+	Err       error
+}
+
+// NotificationsMsg contains the user's notifications
+type NotificationsMsg struct {
+	Notifications []internal.Notification
+	Unread        int
+	Err           error
+}
+
+// RelationsMsg contains the anime related to a finished series
+type RelationsMsg struct {
+	MediaID   int
+	Relations []internal.MediaRelation
+	Err       error
+}
+
+// RecommendationsMsg contains recommendations for an anime, or the "for you" list when MediaID is 0
+type RecommendationsMsg struct {
+	MediaID         int
+	Recommendations []internal.Recommendation
+	Err             error
+}
+
+// SyncedMsg represents the result of sending queued list changes to AniList
+type SyncedMsg struct {
+	Sent int
+	Err  error
+}
+
+// ScoreSavedMsg represents the result of saving a score from the score prompt
+type ScoreSavedMsg struct {
+	Err error
+}
+
+// RewatchStartedMsg represents the result of starting a rewatch
+type RewatchStartedMsg struct {
+	Title string
+	Err   error
+}
+
+// LoginTokenMsg contains the AniList token or MyAnimeList authorization code captured from the redirect
+type LoginTokenMsg struct {
+	Session *internal.LoginSession
+	Secret  string
+	Err     error
+}
+
+// TokenRefreshedMsg contains tokens a tracker refreshed for a profile, which still have to be saved
+type TokenRefreshedMsg struct {
+	Profile string
+	Token   internal.Credentials
+}
+
+// LoggedInMsg represents the result of logging in to AniList again
+type LoggedInMsg struct {
+	Config internal.Config // The profile with the new token
+	Err    error
+}
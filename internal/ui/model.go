@@ -1,8 +1,11 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -23,29 +26,77 @@ const (
 	StateError       UIState = "error"
 	StateConfirming  UIState = "confirming"
 	StateAnimeSelect UIState = "animeselect" // New state for anime selection
+	StateEditing     UIState = "editing"
+	StateSearch      UIState = "search"
+	StateChart       UIState = "chart"
+	StateSchedule    UIState = "schedule"
+	StateInbox       UIState = "inbox"
+	StateFinished    UIState = "finished"
+	StateForYou      UIState = "foryou"
+	StateScoring     UIState = "scoring"
+	StateProfiles    UIState = "profiles"
+	StateLogin       UIState = "login"
+	StateBulk        UIState = "bulk"
+	StateSaving      UIState = "saving"
 )
 
+// Tabs of the selection screen
+const (
+	tabWatching = iota
+	tabPlanned
+	tabCompleted
+)
+
+// syncInterval is how often queued list changes are retried
+const syncInterval = 30 * time.Second
+
 // Model represents the UI state
 type Model struct {
-	Config             *internal.Config
-	Anilist            *internal.AniListClient
+	Profiles           *internal.Profiles
+	Config             *internal.Config        // nil until a profile is chosen
+	Tracker            internal.Tracker        // Keeps the profile's list
+	Anilist            *internal.AniListClient // Catalog, notifications and recommendations
 	AnimeList          list.Model
 	PlannedList        list.Model
+	CompletedList      list.Model
 	EpisodeList        list.Model
 	AnimeSearchList    list.Model // New list for anime search results
 	AnimeEntries       []internal.AnimeEntry
 	PlannedEntries     []internal.AnimeEntry
+	CompletedEntries   []internal.AnimeEntry
 	Loading            bool
 	Spinner            spinner.Model
 	Err                error
 	SelectedAnime      *AnimeItem
 	State              UIState
-	ActiveTab          int // 0 = watching, 1 = planned
+	ActiveTab          int // One of tabWatching, tabPlanned or tabCompleted
 	Tabs               []string
 	ConfirmingStatus   bool
 	Viewport           viewport.Model
 	AnimeSearchResults map[string]string // Store search results
 	SelectedEpisode    int               // Store selected episode for resuming after selection
+	ScoreFormat        string            // The user's AniList score format
+	Editor             EntryEditor
+	Search             CatalogSearch
+	Chart              SeasonChart
+	Schedule           Schedule
+	NotificationList   list.Model
+	InboxErr           string
+	UnreadCount        int // Unread AniList notifications
+	Finish             FinishPrompt
+	DetailsPane        int // 0 = details, 1 = recommendations
+	Recommendations    RecommendationPane
+	ForYou             RecommendationPane
+	EpisodeInfo        map[int][]internal.Episode // Episode metadata keyed by MyAnimeList ID, loaded once per show
+	EpisodeNext        bool                       // Whether the episode list opened on the next unwatched episode
+	SyncErr            string                     // Last error from sending list changes to AniList
+	ScorePrompt        ScorePrompt
+	Switcher           ProfileSwitcher
+	Login              LoginPrompt
+	Bulk               BulkEdit
+	Presence           *internal.Presence     // Shows the episode being watched on Discord
+	NowPlaying         *internal.MPRIS        // Publishes the episode being watched over MPRIS, nil when unavailable
+	TokenRefreshes     chan TokenRefreshedMsg // Tokens refreshed by the tracker, applied to the profile in Update
 }
 
 // Define a new type for search results
@@ -59,8 +110,8 @@ func (a AnimeSearchItem) Title() string       { return a.AnimeTitle }
 func (a AnimeSearchItem) Description() string { return a.EpisodeCount }
 func (a AnimeSearchItem) FilterValue() string { return a.AnimeTitle }
 
-// NewModel creates a new UI model
-func NewModel(config *internal.Config, anilist *internal.AniListClient) *Model {
+// NewModel creates a new UI model. Without a config the profile switcher is shown first.
+func NewModel(profiles *internal.Profiles, config *internal.Config, tracker internal.Tracker, anilist *internal.AniListClient, presence *internal.Presence, nowPlaying *internal.MPRIS) *Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
@@ -77,6 +128,11 @@ func NewModel(config *internal.Config, anilist *internal.AniListClient) *Model {
 	plannedList.SetShowStatusBar(false)
 	plannedList.SetFilteringEnabled(true)
 	plannedList.SetShowTitle(false)
+	// Create completed list
+	completedList := list.New([]list.Item{}, animeDelegate, 0, 0)
+	completedList.SetShowStatusBar(false)
+	completedList.SetFilteringEnabled(true)
+	completedList.SetShowTitle(false)
 	// Create episode list with compact delegate
 	episodeList := list.New([]list.Item{}, NewCompactDelegate(), 0, 0)
 	episodeList.Title = "Select Episode"
@@ -89,52 +145,180 @@ func NewModel(config *internal.Config, anilist *internal.AniListClient) *Model {
 	animeSearchList.SetShowStatusBar(false)
 	animeSearchList.SetFilteringEnabled(true)
 	animeSearchList.Styles.Title = TitleStyle
+	// Create catalog search results list
+	catalogList := list.New([]list.Item{}, animeDelegate, 0, 0)
+	catalogList.SetShowStatusBar(false)
+	catalogList.SetFilteringEnabled(false)
+	catalogList.SetShowTitle(false)
+	// Create season chart list
+	chartList := list.New([]list.Item{}, animeDelegate, 0, 0)
+	chartList.SetShowStatusBar(false)
+	chartList.SetFilteringEnabled(true)
+	chartList.SetShowTitle(false)
+	season, year := internal.CurrentSeason(time.Now())
+	// Create notifications list
+	notificationList := list.New([]list.Item{}, animeDelegate, 0, 0)
+	notificationList.SetShowStatusBar(false)
+	notificationList.SetFilteringEnabled(false)
+	notificationList.SetShowTitle(false)
+	// Create related anime list for finished series
+	relationList := list.New([]list.Item{}, animeDelegate, 0, 0)
+	relationList.SetShowStatusBar(false)
+	relationList.SetFilteringEnabled(false)
+	relationList.SetShowTitle(false)
+	// Create recommendation lists
+	recommendationList := list.New([]list.Item{}, animeDelegate, 0, 0)
+	recommendationList.SetShowStatusBar(false)
+	recommendationList.SetFilteringEnabled(false)
+	recommendationList.SetShowTitle(false)
+	forYouList := list.New([]list.Item{}, animeDelegate, 0, 0)
+	forYouList.SetShowStatusBar(false)
+	forYouList.SetFilteringEnabled(false)
+	forYouList.SetShowTitle(false)
+	// Create profile list
+	profileList := list.New([]list.Item{}, animeDelegate, 0, 0)
+	profileList.SetShowStatusBar(false)
+	profileList.SetFilteringEnabled(true)
+	profileList.SetShowTitle(false)
 	vp := viewport.New(0, 0)
 	vp.Style = lipgloss.NewStyle().Padding(1, 2)
-	return &Model{
-		Config:          config,
-		Anilist:         anilist,
-		AnimeList:       animeList,
-		PlannedList:     plannedList,
-		EpisodeList:     episodeList,
-		AnimeSearchList: animeSearchList,
-		Spinner:         s,
-		Loading:         true,
-		State:           StateLoading,
-		ActiveTab:       0,
-		Tabs:            []string{"Currently Watching", "Planned"},
-		Viewport:        vp,
+	scheduleVp := viewport.New(0, 0)
+	scheduleVp.Style = lipgloss.NewStyle().Padding(0, 3)
+	m := &Model{
+		Profiles:         profiles,
+		Config:           config,
+		Presence:         presence,
+		NowPlaying:       nowPlaying,
+		AnimeList:        animeList,
+		PlannedList:      plannedList,
+		CompletedList:    completedList,
+		EpisodeList:      episodeList,
+		AnimeSearchList:  animeSearchList,
+		Search:           NewCatalogSearch(catalogList),
+		Chart:            NewSeasonChart(season, year, chartList),
+		Schedule:         NewSchedule(time.Now(), scheduleVp),
+		NotificationList: notificationList,
+		Finish:           FinishPrompt{Relations: relationList},
+		Recommendations:  RecommendationPane{List: recommendationList},
+		ForYou:           RecommendationPane{List: forYouList},
+		Spinner:          s,
+		Loading:          true,
+		State:            StateLoading,
+		ActiveTab:        max(slices.Index(internal.UITabs, profiles.UI.DefaultTab), tabWatching),
+		Tabs:             []string{"Currently Watching", "Planned", "Completed"},
+		Viewport:         vp,
+		Switcher:         ProfileSwitcher{List: profileList},
+		TokenRefreshes:   make(chan TokenRefreshedMsg, 1),
 	}
+	m.setClients(tracker, anilist)
+	if config == nil {
+		m.Switcher.Open(profiles, nil)
+		m.State = StateProfiles
+		m.Loading = false
+	}
+	return m
 }
 
 // InitAnimeLists initializes both anime lists
 func (m *Model) InitAnimeLists() tea.Cmd {
 	return func() tea.Msg {
+		// Send changes queued while offline first so the lists include them
+		_, syncErr := m.Tracker.ReplayPending()
 		// Get currently watching anime
-		animeEntries, err := m.Anilist.GetCurrentlyWatching(m.Config.UserID)
+		animeEntries, err := m.Tracker.GetCurrentlyWatching(m.Config.UserID)
 		if err != nil {
 			return ErrMsg{Err: err}
 		}
 		// Get planned anime
-		plannedEntries, err := m.Anilist.GetPlanned(m.Config.UserID)
+		plannedEntries, err := m.Tracker.GetPlanned(m.Config.UserID)
+		if err != nil {
+			return ErrMsg{Err: err}
+		}
+		// Get completed anime
+		completedEntries, err := m.Tracker.GetCompleted(m.Config.UserID)
+		if err != nil {
+			return ErrMsg{Err: err}
+		}
+		// Get the score format used by the editor
+		scoreFormat, err := m.Tracker.GetScoreFormat()
 		if err != nil {
 			return ErrMsg{Err: err}
 		}
+		// Get the unread count for the notifications badge, which only AniList has
+		unread := 0
+		if m.Config.UsesAniList() {
+			unread, err = m.Anilist.GetUnreadNotificationCount()
+			if err != nil {
+				return ErrMsg{Err: err}
+			}
+		}
 		return AnimeListsMsg{
-			Watching: animeEntries,
-			Planned:  plannedEntries,
+			Watching:            animeEntries,
+			Planned:             plannedEntries,
+			Completed:           completedEntries,
+			ScoreFormat:         scoreFormat,
+			UnreadNotifications: unread,
+			SyncErr:             syncErr,
 		}
 	}
 }
 
 // Init initializes the UI
 func (m *Model) Init() tea.Cmd {
+	if m.Config == nil {
+		// The lists are loaded once a profile is chosen
+		return tea.Batch(m.Spinner.Tick, m.SyncPending(), m.WaitForTokenRefresh())
+	}
 	return tea.Batch(
 		m.Spinner.Tick,
 		m.InitAnimeLists(),
+		m.SyncPending(),
+		m.WaitForTokenRefresh(),
 	)
 }
 
+// setClients makes the clients of a profile current. Refreshed tokens are sent to Update, so
+// the profile is only changed from there.
+func (m *Model) setClients(tracker internal.Tracker, anilist *internal.AniListClient) {
+	if mal, ok := tracker.(*internal.MALClient); ok {
+		mal.OnTokenRefresh(func(profile string, token internal.Credentials) error {
+			// Never wait on Update, only the latest tokens matter so pending ones are replaced
+			msg := TokenRefreshedMsg{Profile: profile, Token: token}
+			for {
+				select {
+				case m.TokenRefreshes <- msg:
+					return nil
+				default:
+				}
+				select {
+				case <-m.TokenRefreshes:
+				default:
+				}
+			}
+		})
+	}
+	m.Tracker = tracker
+	m.Anilist = anilist
+}
+
+// WaitForTokenRefresh waits for the tracker to refresh a profile's tokens
+func (m *Model) WaitForTokenRefresh() tea.Cmd {
+	return func() tea.Msg {
+		return <-m.TokenRefreshes
+	}
+}
+
+// SyncPending retries sending queued list changes after the sync interval
+func (m *Model) SyncPending() tea.Cmd {
+	return tea.Tick(syncInterval, func(time.Time) tea.Msg {
+		if m.Tracker == nil {
+			return SyncedMsg{}
+		}
+		sent, err := m.Tracker.ReplayPending()
+		return SyncedMsg{Sent: sent, Err: err}
+	})
+}
+
 // PromptStatusChange prompts the user to confirm a status change
 func (m *Model) PromptStatusChange() tea.Cmd {
 	m.State = StateConfirming
@@ -156,7 +340,7 @@ type AnimeSearchResultsMsg struct {
 // StartAnimeSearch searches for anime and displays results for selection
 func (m *Model) StartAnimeSearch(animeTitle string, epNum int) tea.Cmd {
 	return func() tea.Msg {
-		animeResults, err := internal.SearchAnime(animeTitle, "sub") // Use "sub" as the default mode
+		animeResults, err := internal.SearchAnime(animeTitle, m.Profiles.Source.Translation)
 		if err != nil {
 			return AnimeSearchResultsMsg{Err: err}
 		}
@@ -180,7 +364,7 @@ func (m *Model) StartPlayEpisode() tea.Cmd {
 		}
 		epNum := epItem.Number
 		animeTitle := m.SelectedAnime.AnimeEntry.Title
-		animeResults, err := internal.SearchAnime(animeTitle, "sub") // Use "sub" as the default mode
+		animeResults, err := internal.SearchAnime(animeTitle, m.Profiles.Source.Translation)
 		if err != nil {
 			return EpisodePlayedMsg{Err: fmt.Errorf("failed to search anime: %v", err)}
 		}
@@ -215,36 +399,229 @@ func (m *Model) StartPlayEpisode() tea.Cmd {
 		}
 
 		// Get the episode URL
-		links, err := internal.GetEpisodeURL(animeID, epNum)
+		links, err := internal.GetEpisodeURL(animeID, epNum, m.Profiles.Source)
 		if err != nil {
 			return EpisodePlayedMsg{Err: fmt.Errorf("failed to get episode URL: %v", err)}
 		}
 		// Update the current episode
 		m.SelectedAnime.AnimeEntry.CurrentEpisode = epNum
-		internal.GetEpisodeData(m.SelectedAnime.AnimeEntry.MalId, epNum, &m.SelectedAnime.AnimeEntry)
 		// Play the episode
-		err = internal.PlayEpisode(links, m.SelectedAnime.AnimeEntry)
+		err = internal.PlayEpisode(m.Profiles.Player, links, m.SelectedAnime.AnimeEntry, m.playbackListeners()...)
 		return EpisodePlayedMsg{Err: err}
 	}
 }
 
+// playbackListeners returns the services told about the episode being played
+func (m *Model) playbackListeners() []internal.PlaybackListener {
+	listeners := []internal.PlaybackListener{m.Presence}
+	if m.NowPlaying != nil {
+		listeners = append(listeners, m.NowPlaying)
+	}
+	return listeners
+}
+
 // PlaySelectedAnime plays the episode with the selected anime ID
 func (m *Model) PlaySelectedAnime(animeID string, epNum int) tea.Cmd {
 	return func() tea.Msg {
 		// Get the episode URL
-		links, err := internal.GetEpisodeURL(animeID, epNum)
+		links, err := internal.GetEpisodeURL(animeID, epNum, m.Profiles.Source)
 		if err != nil {
 			return EpisodePlayedMsg{Err: fmt.Errorf("failed to get episode URL: %v", err)}
 		}
 		// Update the current episode
 		m.SelectedAnime.AnimeEntry.CurrentEpisode = epNum
-		internal.GetEpisodeData(m.SelectedAnime.AnimeEntry.MalId, epNum, &m.SelectedAnime.AnimeEntry)
 		// Play the episode
-		err = internal.PlayEpisode(links, m.SelectedAnime.AnimeEntry)
+		err = internal.PlayEpisode(m.Profiles.Player, links, m.SelectedAnime.AnimeEntry, m.playbackListeners()...)
 		return EpisodePlayedMsg{Err: err}
 	}
 }
 
+// SaveProgress sends the progress of a watched episode to the tracker
+func (m *Model) SaveProgress(anime AnimeItem, tab int, episode int, update internal.ListEntryUpdate) tea.Cmd {
+	return func() tea.Msg {
+		_, err := m.Tracker.SaveEntry(update)
+		return ProgressSavedMsg{Anime: anime, Tab: tab, Episode: episode, Update: update, Err: err}
+	}
+}
+
+// MoveToWatching moves a planned anime to Currently Watching
+func (m *Model) MoveToWatching(anime internal.AnimeEntry) tea.Cmd {
+	return func() tea.Msg {
+		err := internal.UpdateAnime(m.Tracker, anime.ID, anime.Progress, "CURRENT")
+		return MovedToWatchingMsg{Err: err}
+	}
+}
+
+// SaveEditedEntry sends the editor's changes to AniList
+func (m *Model) SaveEditedEntry(update internal.ListEntryUpdate) tea.Cmd {
+	return func() tea.Msg {
+		entry, err := m.Tracker.SaveEntry(update)
+		return EntrySavedMsg{Entry: entry, Update: update, Err: err}
+	}
+}
+
+// SearchCatalog searches the AniList catalog with the given filter
+func (m *Model) SearchCatalog(filter internal.MediaSearchFilter) tea.Cmd {
+	return func() tea.Msg {
+		results, _, err := m.Anilist.SearchMedia(filter, 1)
+		return CatalogResultsMsg{Results: results, Err: err}
+	}
+}
+
+// AddToList adds an anime to the user's list with the given status
+func (m *Model) AddToList(media internal.Media, status string) tea.Cmd {
+	return func() tea.Msg {
+		_, err := internal.AddToList(m.Tracker, media.ID, status)
+		return AddedToListMsg{MediaID: media.ID, Title: media.Title.Preferred(), Status: status, Err: err}
+	}
+}
+
+// LoadSeasonChart fetches the anime of the given season
+func (m *Model) LoadSeasonChart(season string, year int) tea.Cmd {
+	return func() tea.Msg {
+		media, err := m.Anilist.GetSeasonChart(season, year)
+		return SeasonChartMsg{Season: season, Year: year, Media: media, Err: err}
+	}
+}
+
+// LoadSchedule fetches the airing schedule of the user's lists for the week starting at weekStart
+func (m *Model) LoadSchedule(weekStart time.Time) tea.Cmd {
+	entries := m.listedEntries()
+	mediaIDs := make([]int, 0, len(entries))
+	for id := range entries {
+		mediaIDs = append(mediaIDs, id)
+	}
+
+	return func() tea.Msg {
+		airings, err := m.Anilist.GetAiringSchedule(mediaIDs, weekStart, weekStart.AddDate(0, 0, 7))
+		return ScheduleMsg{WeekStart: weekStart, Airings: airings, Err: err}
+	}
+}
+
+// LoadRelations fetches the sequels, prequels and side stories of an anime
+func (m *Model) LoadRelations(mediaID int) tea.Cmd {
+	return func() tea.Msg {
+		relations, err := m.Anilist.GetRelations(mediaID)
+		return RelationsMsg{MediaID: mediaID, Relations: relations, Err: err}
+	}
+}
+
+// StartRewatch moves a completed anime back to Currently Watching as a rewatch
+func (m *Model) StartRewatch(anime internal.AnimeEntry) tea.Cmd {
+	return func() tea.Msg {
+		err := internal.StartRewatch(m.Tracker, anime.ID)
+		return RewatchStartedMsg{Title: anime.Title, Err: err}
+	}
+}
+
+// LoadRecommendations fetches the recommendations of an anime
+func (m *Model) LoadRecommendations(mediaID int) tea.Cmd {
+	return func() tea.Msg {
+		recommendations, err := m.Anilist.GetRecommendations(mediaID)
+		return RecommendationsMsg{MediaID: mediaID, Recommendations: recommendations, Err: err}
+	}
+}
+
+// LoadForYou fetches recommendations based on the user's highest scored completed anime
+func (m *Model) LoadForYou() tea.Cmd {
+	completed, listed := m.CompletedEntries, m.listStatuses()
+	return func() tea.Msg {
+		recommendations, err := m.Anilist.GetForYou(completed, listed)
+		return RecommendationsMsg{Recommendations: recommendations, Err: err}
+	}
+}
+
+// MarkCompleted sets the status of an anime to COMPLETED
+func (m *Model) MarkCompleted(anime internal.AnimeEntry) tea.Cmd {
+	return func() tea.Msg {
+		err := internal.UpdateAnime(m.Tracker, anime.ID, anime.Progress, "COMPLETED")
+		return CompletedMsg{Err: err}
+	}
+}
+
+// LoadNotifications fetches the user's notifications, optionally marking them all as read
+func (m *Model) LoadNotifications(markRead bool) tea.Cmd {
+	return func() tea.Msg {
+		notifications, unread, err := m.Anilist.GetNotifications(markRead)
+		return NotificationsMsg{Notifications: notifications, Unread: unread, Err: err}
+	}
+}
+
+// findListed looks up an anime on the user's lists by media ID and returns its item and tab
+func (m *Model) findListed(mediaID int) (AnimeItem, int, bool) {
+	for i, entry := range m.AnimeEntries {
+		if entry.ID == mediaID {
+			return AnimeItem{AnimeEntry: entry, Index: i}, tabWatching, true
+		}
+	}
+	for i, entry := range m.PlannedEntries {
+		if entry.ID == mediaID {
+			return AnimeItem{AnimeEntry: entry, Index: i}, tabPlanned, true
+		}
+	}
+	return AnimeItem{}, 0, false
+}
+
+// listedEntries returns the entries of both lists keyed by media ID
+func (m *Model) listedEntries() map[int]internal.AnimeEntry {
+	entries := make(map[int]internal.AnimeEntry, len(m.AnimeEntries)+len(m.PlannedEntries))
+	for _, entry := range m.PlannedEntries {
+		entries[entry.ID] = entry
+	}
+	for _, entry := range m.AnimeEntries {
+		entries[entry.ID] = entry
+	}
+	return entries
+}
+
+// listStatuses returns the status of every anime on the user's lists keyed by media ID
+func (m *Model) listStatuses() map[int]string {
+	statuses := make(map[int]string)
+	for _, entries := range [][]internal.AnimeEntry{m.CompletedEntries, m.PlannedEntries, m.AnimeEntries} {
+		for _, entry := range entries {
+			statuses[entry.ID] = entry.Status
+		}
+	}
+	return statuses
+}
+
+// markOnList sets which media are on the user's lists from the loaded lists, since the
+// AniList client of a MyAnimeList profile doesn't see the user's lists
+func markOnList(media *internal.Media, statuses map[int]string) {
+	media.MediaListEntry = nil
+	if status, ok := statuses[media.ID]; ok {
+		media.MediaListEntry = &internal.MediaListStatus{Status: status}
+	}
+}
+
+// replaceEntry updates an anime entry in place and refreshes its row in the matching list
+func (m *Model) replaceEntry(tab int, index int, entry internal.AnimeEntry) {
+	m.tabEntries(tab)[index] = entry
+	m.tabList(tab).SetItem(index, AnimeItem{AnimeEntry: entry, Index: index})
+}
+
+// tabList returns the list shown on a tab of the selection screen
+func (m *Model) tabList(tab int) *list.Model {
+	switch tab {
+	case tabPlanned:
+		return &m.PlannedList
+	case tabCompleted:
+		return &m.CompletedList
+	}
+	return &m.AnimeList
+}
+
+// tabEntries returns the entries shown on a tab of the selection screen
+func (m *Model) tabEntries(tab int) []internal.AnimeEntry {
+	switch tab {
+	case tabPlanned:
+		return m.PlannedEntries
+	case tabCompleted:
+		return m.CompletedEntries
+	}
+	return m.AnimeEntries
+}
+
 // Update updates the UI state
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -252,16 +629,32 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		h, v := msg.Width-4, msg.Height-6 // Leave some margin plus space for tabs
 		m.AnimeList.SetSize(h, v)
 		m.PlannedList.SetSize(h, v)
+		m.CompletedList.SetSize(h, v)
 		m.EpisodeList.SetSize(h, v)
 		m.AnimeSearchList.SetSize(h, v)
+		m.Search.Results.SetSize(h, v-searchFieldCount-4) // Leave space for the search form
+		m.Chart.List.SetSize(h, v-4)                      // Leave space for the format tabs
 		m.Viewport.Width = h
 		m.Viewport.Height = v
+		m.NotificationList.SetSize(h, v-2)
+		m.Finish.Relations.SetSize(h, v-6)
+		m.Recommendations.List.SetSize(h, v-4)
+		m.ForYou.List.SetSize(h, v-4)
+		m.Switcher.List.SetSize(h, v-5)
+		m.Schedule.Viewport.Width = h
+		m.Schedule.Viewport.Height = v - 2
 		return m, nil
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
 	case AnimeListsMsg:
 		m.AnimeEntries = msg.Watching
 		m.PlannedEntries = msg.Planned
+		m.CompletedEntries = msg.Completed
+		m.ScoreFormat = msg.ScoreFormat
+		m.UnreadCount = msg.UnreadNotifications
+		if msg.SyncErr != nil {
+			m.SyncErr = m.syncErrorText(msg.SyncErr)
+		}
 		// Create items for watching list
 		watchingItems := make([]list.Item, len(m.AnimeEntries))
 		for i, entry := range m.AnimeEntries {
@@ -274,14 +667,30 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			plannedItems[i] = AnimeItem{AnimeEntry: entry, Index: i}
 		}
 		m.PlannedList.SetItems(plannedItems)
+		// Create items for completed list
+		completedItems := make([]list.Item, len(m.CompletedEntries))
+		for i, entry := range m.CompletedEntries {
+			completedItems[i] = AnimeItem{AnimeEntry: entry, Index: i}
+		}
+		m.CompletedList.SetItems(completedItems)
 		m.Loading = false
 		m.State = StateSelecting
 		return m, nil
 	case ErrMsg:
-		m.Err = msg.Err
-		m.Loading = false
-		m.State = StateError
-		return m, nil
+		return m.showError(msg.Err)
+	case LoginTokenMsg:
+		return m.handleLoginToken(msg)
+	case LoggedInMsg:
+		return m.handleLoggedIn(msg)
+	case TokenRefreshedMsg:
+		// The profile may no longer be current, its tokens are saved all the same
+		if config, ok := m.Profiles.Profiles[msg.Profile]; ok {
+			config.SetCredentials(msg.Token)
+			if err := m.Profiles.Save(); err != nil {
+				m.SyncErr = errorText(err)
+			}
+		}
+		return m, m.WaitForTokenRefresh()
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.Spinner, cmd = m.Spinner.Update(msg)
@@ -290,6 +699,164 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleEpisodePlayed(msg)
 	case StatusChangeMsg:
 		return m.handleStatusChange(msg)
+	case ProgressSavedMsg:
+		return m.handleProgressSaved(msg)
+	case MovedToWatchingMsg:
+		if msg.Err != nil && !errors.Is(msg.Err, internal.ErrQueued) {
+			return m.showError(msg.Err)
+		}
+		// Reload the lists since an item moved from planned to current
+		m.State = StateLoading
+		m.Loading = true
+		return m, m.InitAnimeLists()
+	case CatalogResultsMsg:
+		if msg.Err != nil {
+			m.Search.Err = errorText(msg.Err)
+			return m, nil
+		}
+		statuses := m.listStatuses()
+		items := make([]list.Item, len(msg.Results))
+		for i, media := range msg.Results {
+			markOnList(&media, statuses)
+			items[i] = MediaItem{Media: media}
+		}
+		m.Search.Results.SetItems(items)
+		m.Search.Results.ResetSelected()
+		m.Search.Status = fmt.Sprintf("%d results", len(items))
+		if len(items) > 0 {
+			m.Search.InResults = true
+			m.Search.Query.Blur()
+			m.Search.Year.Blur()
+		}
+		return m, nil
+	case AddedToListMsg:
+		return m.handleAdded(msg)
+	case CompletedMsg:
+		return m.handleCompleted(msg)
+	case RewatchStartedMsg:
+		if msg.Err != nil && !errors.Is(msg.Err, internal.ErrQueued) {
+			return m.showError(msg.Err)
+		}
+		// Show the rewatch on the Currently Watching tab
+		m.ActiveTab = tabWatching
+		m.Loading = true
+		return m, m.InitAnimeLists()
+	case ScoreSavedMsg:
+		if msg.Err != nil && !errors.Is(msg.Err, internal.ErrQueued) {
+			m.ScorePrompt.Err = errorText(msg.Err)
+			return m, nil
+		}
+		m.Finish.Changed = true
+		m.Finish.Status = "Score saved"
+		m.State = StateFinished
+		return m, nil
+	case SyncedMsg:
+		if msg.Err != nil {
+			m.SyncErr = m.syncErrorText(msg.Err)
+		} else if msg.Sent > 0 {
+			m.SyncErr = ""
+		}
+		return m, m.SyncPending()
+	case RecommendationsMsg:
+		pane := &m.ForYou
+		if msg.MediaID != 0 {
+			pane = &m.Recommendations
+		}
+		// Ignore recommendations for an anime the user already moved away from
+		if msg.MediaID != pane.MediaID {
+			return m, nil
+		}
+		if msg.Err != nil {
+			pane.Loading = false
+			pane.Err = errorText(msg.Err)
+			return m, nil
+		}
+		statuses := m.listStatuses()
+		for i := range msg.Recommendations {
+			markOnList(&msg.Recommendations[i].Media, statuses)
+		}
+		pane.SetRecommendations(msg.Recommendations)
+		return m, nil
+	case ScheduleMsg:
+		// Ignore schedules for a week the user already moved away from
+		if !msg.WeekStart.Equal(m.Schedule.WeekStart) {
+			return m, nil
+		}
+		m.Schedule.Loading = false
+		if msg.Err != nil {
+			m.Schedule.Err = errorText(msg.Err)
+			return m, nil
+		}
+		m.Schedule.Airings = msg.Airings
+		m.Schedule.Render(m.listedEntries(), time.Now())
+		return m, nil
+	case RelationsMsg:
+		// Ignore relations for a series the user already moved away from
+		if !m.Finish.Loading || msg.MediaID != m.Finish.Anime.AnimeEntry.ID {
+			return m, nil
+		}
+		if msg.Err != nil {
+			m.Finish.Loading = false
+			m.Finish.Err = errorText(msg.Err)
+			return m, nil
+		}
+		statuses := m.listStatuses()
+		for i := range msg.Relations {
+			markOnList(&msg.Relations[i].Media, statuses)
+		}
+		m.Finish.SetRelations(msg.Relations)
+		return m, nil
+	case NotificationsMsg:
+		if msg.Err != nil {
+			m.InboxErr = errorText(msg.Err)
+			return m, nil
+		}
+		items := make([]list.Item, len(msg.Notifications))
+		for i, notification := range msg.Notifications {
+			items[i] = NotificationItem{Notification: notification}
+		}
+		m.NotificationList.SetItems(items)
+		m.UnreadCount = msg.Unread
+		m.InboxErr = ""
+		return m, nil
+	case SeasonChartMsg:
+		// Ignore charts for a season the user already moved away from
+		if msg.Season != m.Chart.Season || msg.Year != m.Chart.Year {
+			return m, nil
+		}
+		if msg.Err != nil {
+			m.Chart.Err = errorText(msg.Err)
+			return m, nil
+		}
+		m.Chart.Status = ""
+		m.Chart.Loaded = true
+		statuses := m.listStatuses()
+		for i := range msg.Media {
+			markOnList(&msg.Media[i], statuses)
+		}
+		m.Chart.SetMedia(msg.Media)
+		return m, nil
+	case EpisodesMsg:
+		return m.handleEpisodes(msg)
+	case StatusesSavedMsg:
+		return m.handleStatusesSaved(msg)
+	case EntrySavedMsg:
+		if msg.Err != nil && !errors.Is(msg.Err, internal.ErrQueued) {
+			// Keep the editor open so the user can retry
+			m.Editor.Err = errorText(msg.Err)
+			m.State = StateEditing
+			return m, nil
+		}
+		entry := m.Editor.Anime.AnimeEntry
+		if msg.Entry != nil {
+			entry.ApplyListEntry(*msg.Entry)
+		} else {
+			// The change is queued, show it locally until it is sent
+			entry.ApplyUpdate(msg.Update)
+		}
+		m.replaceEntry(m.Editor.Tab, m.Editor.Anime.Index, entry)
+		m.State = StateSelecting
+		return m, nil
 	case AnimeSearchResultsMsg:
 		if msg.Err != nil {
 			m.Err = msg.Err
@@ -319,13 +886,10 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Handle state-specific updates
 	switch m.State {
-	case StateSelecting:
+	case StateSelecting, StateBulk:
+		activeList := m.tabList(m.ActiveTab)
 		var cmd tea.Cmd
-		if m.ActiveTab == 0 {
-			m.AnimeList, cmd = m.AnimeList.Update(msg)
-		} else {
-			m.PlannedList, cmd = m.PlannedList.Update(msg)
-		}
+		*activeList, cmd = activeList.Update(msg)
 		return m, cmd
 	case StateEpisode:
 		var cmd tea.Cmd
@@ -339,48 +903,628 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		var cmd tea.Cmd
 		m.AnimeSearchList, cmd = m.AnimeSearchList.Update(msg)
 		return m, cmd
+	case StateEditing:
+		var cmd tea.Cmd
+		m.Editor, cmd = m.Editor.Update(msg)
+		return m, cmd
+	case StateSearch:
+		var cmd tea.Cmd
+		m.Search, cmd = m.Search.Update(msg)
+		return m, cmd
+	case StateChart:
+		var cmd tea.Cmd
+		m.Chart.List, cmd = m.Chart.List.Update(msg)
+		return m, cmd
+	case StateSchedule:
+		var cmd tea.Cmd
+		m.Schedule.Viewport, cmd = m.Schedule.Viewport.Update(msg)
+		return m, cmd
+	case StateInbox:
+		var cmd tea.Cmd
+		m.NotificationList, cmd = m.NotificationList.Update(msg)
+		return m, cmd
+	case StateFinished:
+		var cmd tea.Cmd
+		m.Finish.Relations, cmd = m.Finish.Relations.Update(msg)
+		return m, cmd
+	case StateForYou:
+		var cmd tea.Cmd
+		m.ForYou.List, cmd = m.ForYou.List.Update(msg)
+		return m, cmd
+	case StateScoring:
+		var cmd tea.Cmd
+		m.ScorePrompt.Input, cmd = m.ScorePrompt.Input.Update(msg)
+		return m, cmd
 	}
 	return m, nil
 }
 
+// SaveScore saves the score entered in the score prompt
+func (m *Model) SaveScore(mediaID int, score float64) tea.Cmd {
+	return func() tea.Msg {
+		_, err := m.Tracker.SaveEntry(internal.ListEntryUpdate{
+			MediaID: mediaID,
+			Score:   &score,
+		})
+		return ScoreSavedMsg{Err: err}
+	}
+}
+
+// handleScoreKey handles keyboard input on the score prompt, returning to the finished series screen when done
+func (m *Model) handleScoreKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.State = StateFinished
+		return m, nil
+	case "enter":
+		score, err := internal.ParseScore(m.ScoreFormat, m.ScorePrompt.Input.Value())
+		if err != nil {
+			m.ScorePrompt.Err = err.Error()
+			return m, nil
+		}
+		m.ScorePrompt.Err = ""
+		return m, m.SaveScore(m.ScorePrompt.Anime.ID, score)
+	}
+
+	var cmd tea.Cmd
+	m.ScorePrompt.Input, cmd = m.ScorePrompt.Input.Update(msg)
+	return m, cmd
+}
+
+// handleFinishKey handles keyboard input on the finished series screen
+func (m *Model) handleFinishKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c", "n":
+		// Reload the lists if anything was moved between them
+		return m.leaveScreen(m.Finish.Changed)
+	case "y":
+		if !m.Finish.Completed {
+			m.Finish.Err = ""
+			return m, m.MarkCompleted(m.Finish.Anime.AnimeEntry)
+		}
+		return m, nil
+	case "p", "c":
+		if selectedItem, ok := m.Finish.Relations.SelectedItem().(MediaItem); ok {
+			status := "PLANNING"
+			if msg.String() == "c" {
+				status = "CURRENT"
+			}
+			m.Finish.Err = ""
+			m.Finish.Status = fmt.Sprintf("Adding %s...", selectedItem.Media.Title.Preferred())
+			return m, m.AddToList(selectedItem.Media, status)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.Finish.Relations, cmd = m.Finish.Relations.Update(msg)
+	return m, cmd
+}
+
+// handleInboxKey handles keyboard input on the notifications screen
+func (m *Model) handleInboxKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.State = StateSelecting
+		return m, nil
+	case "r":
+		return m, m.LoadNotifications(true)
+	case "enter":
+		selectedItem, ok := m.NotificationList.SelectedItem().(NotificationItem)
+		if !ok || selectedItem.Notification.Type != "AIRING" {
+			return m, nil
+		}
+		animeItem, tab, ok := m.findListed(selectedItem.Notification.AnimeID)
+		if !ok {
+			m.InboxErr = "This show is not on your Currently Watching or Planned list"
+			return m, nil
+		}
+		m.ActiveTab = tab
+		return m, m.openEpisodeList(animeItem, selectedItem.Notification.Episode, false)
+	}
+
+	var cmd tea.Cmd
+	m.NotificationList, cmd = m.NotificationList.Update(msg)
+	return m, cmd
+}
+
+// handleScheduleKey handles keyboard input on the schedule screen
+func (m *Model) handleScheduleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.State = StateSelecting
+		return m, nil
+	case "[", "]":
+		delta := 1
+		if msg.String() == "[" {
+			delta = -1
+		}
+		m.Schedule.ShiftWeek(delta)
+		m.Schedule.Err = ""
+		m.Schedule.Loading = true
+		return m, m.LoadSchedule(m.Schedule.WeekStart)
+	}
+
+	var cmd tea.Cmd
+	m.Schedule.Viewport, cmd = m.Schedule.Viewport.Update(msg)
+	return m, cmd
+}
+
+// handleChartKey handles keyboard input on the season chart screen
+func (m *Model) handleChartKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Let the list handle keys while its filter is being typed
+	if m.Chart.List.FilterState() == list.Filtering {
+		var cmd tea.Cmd
+		m.Chart.List, cmd = m.Chart.List.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		if m.Chart.List.FilterState() == list.FilterApplied {
+			m.Chart.List.ResetFilter()
+			return m, nil
+		}
+		// Reload the lists if anything new was added to them
+		added := m.Chart.Added
+		m.Chart.Added = false
+		return m.leaveScreen(added)
+	case "tab":
+		m.Chart.SwitchFormat(1)
+		return m, nil
+	case "shift+tab":
+		m.Chart.SwitchFormat(-1)
+		return m, nil
+	case "[", "]":
+		delta := 1
+		if msg.String() == "[" {
+			delta = -1
+		}
+		m.Chart.Season, m.Chart.Year = internal.ShiftSeason(m.Chart.Season, m.Chart.Year, delta)
+		m.Chart.Err = ""
+		m.Chart.Status = "Loading..."
+		m.Chart.Loaded = false
+		m.Chart.SetMedia(nil)
+		return m, m.LoadSeasonChart(m.Chart.Season, m.Chart.Year)
+	case "o":
+		m.Chart.NextSort()
+		return m, nil
+	case "p":
+		if selectedItem, ok := m.Chart.List.SelectedItem().(MediaItem); ok {
+			m.Chart.Err = ""
+			m.Chart.Status = fmt.Sprintf("Adding %s...", selectedItem.Title())
+			return m, m.AddToList(selectedItem.Media, "PLANNING")
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.Chart.List, cmd = m.Chart.List.Update(msg)
+	return m, cmd
+}
+
+// handleAdded shows the result of adding an anime to a list on the screen it was added from
+func (m *Model) handleAdded(msg AddedToListMsg) (tea.Model, tea.Cmd) {
+	status := fmt.Sprintf("Added %s to %s", msg.Title, listName(msg.Status))
+	if errors.Is(msg.Err, internal.ErrQueued) {
+		status += " (waiting to sync)"
+		msg.Err = nil
+	}
+
+	switch m.State {
+	case StateFinished:
+		if msg.Err != nil {
+			m.Finish.Err = errorText(msg.Err)
+			return m, nil
+		}
+		m.Finish.Changed = true
+		m.Finish.Status = status
+		m.Finish.MarkListed(msg.MediaID, msg.Status)
+	case StateChart:
+		if msg.Err != nil {
+			m.Chart.Err = errorText(msg.Err)
+			return m, nil
+		}
+		m.Chart.Added = true
+		m.Chart.Status = status
+		m.Chart.MarkListed(msg.MediaID, msg.Status)
+	case StateDetails, StateForYou:
+		pane := &m.ForYou
+		if m.State == StateDetails {
+			pane = &m.Recommendations
+		}
+		if msg.Err != nil {
+			pane.Err = errorText(msg.Err)
+			return m, nil
+		}
+		pane.Added = true
+		pane.Status = status
+		pane.MarkListed(msg.MediaID, msg.Status)
+	case StateSearch:
+		if msg.Err != nil {
+			m.Search.Err = errorText(msg.Err)
+			return m, nil
+		}
+		m.Search.Added = true
+		m.Search.Status = status
+	}
+	return m, nil
+}
+
+// handleCompleted shows the result of marking a finished series as completed, then asks for a score
+func (m *Model) handleCompleted(msg CompletedMsg) (tea.Model, tea.Cmd) {
+	status := "Marked as Completed"
+	if errors.Is(msg.Err, internal.ErrQueued) {
+		status += " (waiting to sync)"
+	} else if msg.Err != nil {
+		m.Finish.Err = errorText(msg.Err)
+		return m, nil
+	}
+
+	m.Finish.Completed = true
+	m.Finish.Changed = true
+	m.Finish.Status = status
+	if m.Config.Tracking.PromptScore {
+		m.ScorePrompt = NewScorePrompt(m.Finish.Anime.AnimeEntry, m.ScoreFormat)
+		m.State = StateScoring
+		return m, m.ScorePrompt.Input.Focus()
+	}
+	return m, nil
+}
+
+// syncErrorText explains why queued list changes were not sent, naming the ones the tracker
+// refused and dropped from the queue
+func (m *Model) syncErrorText(err error) string {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	var dropped []string
+	var other error
+	for _, err := range errs {
+		var droppedErr *internal.DroppedError
+		if errors.As(err, &droppedErr) {
+			dropped = append(dropped, fmt.Sprintf("%s (%s)", m.entryTitle(droppedErr.Update.MediaID), errorText(droppedErr.Err)))
+		} else if other == nil {
+			other = err
+		}
+	}
+
+	if len(dropped) == 0 {
+		return errorText(other)
+	}
+	text := fmt.Sprintf("%s refused and dropped queued changes to %s", m.Tracker.Name(), strings.Join(dropped, ", "))
+	if other != nil {
+		text += "; " + errorText(other)
+	}
+	return text
+}
+
+// entryTitle returns the title of an anime on the user's lists, or its ID when it isn't listed
+func (m *Model) entryTitle(mediaID int) string {
+	for _, entries := range [][]internal.AnimeEntry{m.AnimeEntries, m.PlannedEntries, m.CompletedEntries} {
+		for _, entry := range entries {
+			if entry.ID == mediaID {
+				return entry.Title
+			}
+		}
+	}
+	return fmt.Sprintf("anime %d", mediaID)
+}
+
+// leaveScreen returns to the selection screen, reloading the lists if they were changed
+func (m *Model) leaveScreen(changed bool) (tea.Model, tea.Cmd) {
+	if changed {
+		m.State = StateLoading
+		m.Loading = true
+		return m, m.InitAnimeLists()
+	}
+	m.State = StateSelecting
+	return m, nil
+}
+
+// handleDetailsKey handles keyboard input on the details screen
+func (m *Model) handleDetailsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		added := m.Recommendations.Added
+		m.Recommendations.Added = false
+		return m.leaveScreen(added)
+	case "tab", "shift+tab":
+		m.DetailsPane = (m.DetailsPane + 1) % 2
+		if m.DetailsPane == 1 && !m.Recommendations.Loaded && !m.Recommendations.Loading {
+			m.Recommendations.Loading = true
+			return m, m.LoadRecommendations(m.Recommendations.MediaID)
+		}
+		return m, nil
+	}
+
+	if m.DetailsPane == 1 {
+		return m, m.handleRecommendationKey(&m.Recommendations, msg)
+	}
+
+	if msg.String() == "enter" {
+		added := m.Recommendations.Added
+		m.Recommendations.Added = false
+		return m.leaveScreen(added)
+	}
+
+	var cmd tea.Cmd
+	m.Viewport, cmd = m.Viewport.Update(msg)
+	return m, cmd
+}
+
+// handleForYouKey handles keyboard input on the "for you" screen
+func (m *Model) handleForYouKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		added := m.ForYou.Added
+		m.ForYou.Added = false
+		return m.leaveScreen(added)
+	}
+	return m, m.handleRecommendationKey(&m.ForYou, msg)
+}
+
+// handleRecommendationKey handles the keys shared by recommendation panes
+func (m *Model) handleRecommendationKey(pane *RecommendationPane, msg tea.KeyMsg) tea.Cmd {
+	if msg.String() == "p" {
+		if selectedItem, ok := pane.List.SelectedItem().(MediaItem); ok {
+			pane.Err = ""
+			pane.Status = fmt.Sprintf("Adding %s...", selectedItem.Media.Title.Preferred())
+			return m.AddToList(selectedItem.Media, "PLANNING")
+		}
+		return nil
+	}
+
+	var cmd tea.Cmd
+	pane.List, cmd = pane.List.Update(msg)
+	return cmd
+}
+
+// handleSearchKey handles keyboard input on the catalog search screen
+func (m *Model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.Search.InResults {
+		switch msg.String() {
+		case "esc":
+			m.Search.InResults = false
+			return m, m.Search.setFocus(searchQuery)
+		case "p", "c":
+			if selectedItem, ok := m.Search.Results.SelectedItem().(MediaItem); ok {
+				status := "PLANNING"
+				if msg.String() == "c" {
+					status = "CURRENT"
+				}
+				m.Search.Err = ""
+				m.Search.Status = fmt.Sprintf("Adding %s...", selectedItem.Title())
+				return m, m.AddToList(selectedItem.Media, status)
+			}
+			return m, nil
+		}
+	} else {
+		switch msg.String() {
+		case "esc", "ctrl+c":
+			// Reload the lists if anything new was added to them
+			added := m.Search.Added
+			m.Search.Added = false
+			return m.leaveScreen(added)
+		case "enter":
+			filter, err := m.Search.Filter()
+			if err != nil {
+				m.Search.Err = err.Error()
+				return m, nil
+			}
+			m.Search.Err = ""
+			m.Search.Status = "Searching..."
+			return m, m.SearchCatalog(filter)
+		}
+	}
+
+	var cmd tea.Cmd
+	m.Search, cmd = m.Search.Update(msg)
+	return m, cmd
+}
+
+// handleEditorKey handles keyboard input while the entry editor is open
+func (m *Model) handleEditorKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.State = StateSelecting
+		return m, nil
+	case "enter":
+		update, err := m.Editor.Build()
+		if err != nil {
+			m.Editor.Err = err.Error()
+			return m, nil
+		}
+		m.Editor.Err = ""
+		m.State = StateLoading
+		return m, m.SaveEditedEntry(update)
+	}
+
+	var cmd tea.Cmd
+	m.Editor, cmd = m.Editor.Update(msg)
+	return m, cmd
+}
+
+// isFiltering reports whether the active tab's list filter is being typed
+func (m *Model) isFiltering() bool {
+	return m.tabList(m.ActiveTab).FilterState() == list.Filtering
+}
+
 // handleKeyPress handles keyboard input
 func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.State == StateEditing {
+		return m.handleEditorKey(msg)
+	}
+	if m.State == StateSearch {
+		return m.handleSearchKey(msg)
+	}
+	if m.State == StateChart {
+		return m.handleChartKey(msg)
+	}
+	if m.State == StateSchedule {
+		return m.handleScheduleKey(msg)
+	}
+	if m.State == StateInbox {
+		return m.handleInboxKey(msg)
+	}
+	if m.State == StateFinished {
+		return m.handleFinishKey(msg)
+	}
+	if m.State == StateScoring {
+		return m.handleScoreKey(msg)
+	}
+	if m.State == StateDetails {
+		return m.handleDetailsKey(msg)
+	}
+	if m.State == StateForYou {
+		return m.handleForYouKey(msg)
+	}
+	if m.State == StateProfiles {
+		return m.handleProfileKey(msg)
+	}
+	if m.State == StateLogin {
+		return m.handleLoginKey(msg)
+	}
+	if m.State == StateBulk {
+		return m.handleBulkKey(msg)
+	}
+
 	switch msg.String() {
 	case "ctrl+c":
-		if m.State == StateDetails || m.State == StateAnimeSelect {
-			// Return to selection screen from details view or anime selection
+		if m.State == StateAnimeSelect {
+			// Return to selection screen from anime selection
 			m.State = StateSelecting
 			return m, nil
 		}
 		return m, tea.Quit
 	case "i", "I":
 		if m.State == StateSelecting {
-			var isFiltering bool
-			if m.ActiveTab == 0 {
-				isFiltering = m.AnimeList.FilterState() == list.Filtering
-			} else {
-				isFiltering = m.PlannedList.FilterState() == list.Filtering
-			}
-
-			if !isFiltering {
-				var selectedItem AnimeItem
-				var ok bool
-				if m.ActiveTab == 0 {
-					selectedItem, ok = m.AnimeList.SelectedItem().(AnimeItem)
-				} else {
-					selectedItem, ok = m.PlannedList.SelectedItem().(AnimeItem)
-				}
+			if !m.isFiltering() {
+				selectedItem, ok := m.tabList(m.ActiveTab).SelectedItem().(AnimeItem)
 				if ok {
 					m.Viewport.SetContent(selectedItem.DetailedView())
 					m.Viewport.GotoTop()
+					m.DetailsPane = 0
+					m.Recommendations.Reset(selectedItem.AnimeEntry.ID)
 					m.State = StateDetails
 					return m, nil
 				}
 			}
 		}
+	case "r":
+		if m.State == StateSelecting && m.ActiveTab == tabCompleted {
+			if !m.isFiltering() {
+				if selectedItem, ok := m.CompletedList.SelectedItem().(AnimeItem); ok {
+					m.State = StateLoading
+					m.Loading = true
+					return m, m.StartRewatch(selectedItem.AnimeEntry)
+				}
+			}
+		}
+	case "e":
+		if m.State == StateSelecting {
+			if !m.isFiltering() {
+				selectedItem, ok := m.tabList(m.ActiveTab).SelectedItem().(AnimeItem)
+				if ok {
+					m.Editor = NewEntryEditor(selectedItem, m.ActiveTab, m.ScoreFormat)
+					m.State = StateEditing
+					return m, m.Editor.setFocus(fieldStatus)
+				}
+			}
+		}
+	case "s":
+		if m.State == StateSelecting {
+			if !m.isFiltering() {
+				m.Search.InResults = false
+				m.Search.Err = ""
+				m.Search.Status = ""
+				m.State = StateSearch
+				return m, m.Search.setFocus(searchQuery)
+			}
+		}
+	case "c":
+		if m.State == StateSelecting {
+			if !m.isFiltering() {
+				m.State = StateChart
+				m.Chart.Err = ""
+				if !m.Chart.Loaded {
+					m.Chart.Status = "Loading..."
+					return m, m.LoadSeasonChart(m.Chart.Season, m.Chart.Year)
+				}
+				return m, nil
+			}
+		}
+	case "n":
+		if m.State == StateConfirming {
+			return m, func() tea.Msg { return StatusChangeMsg{Confirmed: false} }
+		}
+		if m.State == StateSelecting {
+			if !m.isFiltering() {
+				m.State = StateInbox
+				m.InboxErr = ""
+				if !m.Config.UsesAniList() {
+					m.InboxErr = "Notifications are only available for AniList profiles"
+					return m, nil
+				}
+				return m, m.LoadNotifications(false)
+			}
+		}
+	case "f":
+		if m.State == StateSelecting {
+			if !m.isFiltering() {
+				m.State = StateForYou
+				if !m.Config.UsesAniList() {
+					m.ForYou.Err = "Recommendations for you are only available for AniList profiles"
+					return m, nil
+				}
+				if !m.ForYou.Loaded && !m.ForYou.Loading {
+					m.ForYou.Err = ""
+					m.ForYou.Loading = true
+					return m, m.LoadForYou()
+				}
+				return m, nil
+			}
+		}
+	case "L":
+		if m.State == StateSelecting {
+			if !m.isFiltering() {
+				return m, m.openLogin("")
+			}
+		}
+	case "F":
+		if m.State == StateEpisode && m.EpisodeList.FilterState() != list.Filtering {
+			return m, m.cycleFiller()
+		}
+	case "v":
+		if m.State == StateSelecting {
+			if !m.isFiltering() {
+				m.enterBulk()
+				return m, nil
+			}
+		}
+	case "P":
+		if m.State == StateSelecting {
+			if !m.isFiltering() {
+				m.Switcher.Open(m.Profiles, m.Config)
+				m.State = StateProfiles
+				return m, nil
+			}
+		}
+	case "w":
+		if m.State == StateSelecting {
+			if !m.isFiltering() {
+				// Always reload so progress changes are reflected
+				m.State = StateSchedule
+				m.Schedule.Err = ""
+				m.Schedule.Loading = true
+				return m, m.LoadSchedule(m.Schedule.WeekStart)
+			}
+		}
 	case "esc":
 		switch m.State {
-		case StateDetails, StateEpisode, StateAnimeSelect:
+		case StateEpisode, StateAnimeSelect:
 			m.State = StateSelecting
 			return m, nil
 		}
@@ -398,63 +1542,27 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.State == StateConfirming {
 			return m, func() tea.Msg { return StatusChangeMsg{Confirmed: true} }
 		}
-	case "n":
-		if m.State == StateConfirming {
-			return m, func() tea.Msg { return StatusChangeMsg{Confirmed: false} }
-		}
 	case "enter":
 		switch m.State {
 		case StateSelecting:
-			// Check if filter mode is active
-			var isFiltering bool
-			if m.ActiveTab == 0 {
-				isFiltering = m.AnimeList.FilterState() == list.Filtering
-			} else {
-				isFiltering = m.PlannedList.FilterState() == list.Filtering
-			}
-
 			// If filtering, let the list handle the enter key for search completion
-			if isFiltering {
+			if m.isFiltering() {
+				activeList := m.tabList(m.ActiveTab)
 				var cmd tea.Cmd
-				if m.ActiveTab == 0 {
-					m.AnimeList, cmd = m.AnimeList.Update(msg)
-				} else {
-					m.PlannedList, cmd = m.PlannedList.Update(msg)
-				}
+				*activeList, cmd = activeList.Update(msg)
 				return m, cmd
 			}
 
 			// Otherwise proceed with selection as before
-			var selectedItem AnimeItem
-			var ok bool
-			if m.ActiveTab == 0 {
-				selectedItem, ok = m.AnimeList.SelectedItem().(AnimeItem)
-			} else {
-				selectedItem, ok = m.PlannedList.SelectedItem().(AnimeItem)
-			}
+			selectedItem, ok := m.tabList(m.ActiveTab).SelectedItem().(AnimeItem)
 			if ok {
-				m.SelectedAnime = &selectedItem
-				m.State = StateEpisode
-				episodeCount := selectedItem.AnimeEntry.Episodes
-				items := make([]list.Item, episodeCount)
-				for i := 0; i < episodeCount; i++ {
-					items[i] = EpisodeItem{Number: i + 1}
-				}
-				m.EpisodeList.SetItems(items)
 				// Select the next episode by default
-				nextEp := selectedItem.AnimeEntry.Progress + 1
-				if nextEp >= 0 && nextEp <= len(items) {
-					m.EpisodeList.Select(nextEp - 1)
-				}
-				return m, nil
+				return m, m.openEpisodeList(selectedItem, selectedItem.AnimeEntry.Progress+1, true)
 			}
 		// Keep the rest of the enter key handling for other states
 		case StateEpisode:
 			m.State = StateLoading
 			return m, m.StartPlayEpisode()
-		case StateDetails:
-			m.State = StateSelecting
-			return m, nil
 		case StateAnimeSelect:
 			// User selected an anime from the search results
 			if selectedItem, ok := m.AnimeSearchList.SelectedItem().(AnimeSearchItem); ok {
@@ -467,15 +1575,10 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	switch m.State {
 	case StateSelecting:
-		if m.ActiveTab == 0 {
-			m.AnimeList, cmd = m.AnimeList.Update(msg)
-		} else {
-			m.PlannedList, cmd = m.PlannedList.Update(msg)
-		}
+		activeList := m.tabList(m.ActiveTab)
+		*activeList, cmd = activeList.Update(msg)
 	case StateEpisode:
 		m.EpisodeList, cmd = m.EpisodeList.Update(msg)
-	case StateDetails:
-		m.Viewport, cmd = m.Viewport.Update(msg)
 	case StateAnimeSelect:
 		m.AnimeSearchList, cmd = m.AnimeSearchList.Update(msg)
 	}
@@ -491,34 +1594,15 @@ func (m *Model) handleEpisodePlayed(msg EpisodePlayedMsg) (tea.Model, tea.Cmd) {
 		// Get the selected episode number
 		epItem, ok := m.EpisodeList.SelectedItem().(EpisodeItem)
 		if ok {
-			// Update progress in AniList
-			_ = m.Anilist.UpdateProgress(m.SelectedAnime.AnimeEntry.ID, epItem.Number)
-			// Update local progress if the watched episode is the next one
-			if epItem.Number == m.SelectedAnime.AnimeEntry.Progress+1 {
-				if m.ActiveTab == 0 {
-					m.AnimeEntries[m.SelectedAnime.Index].Progress = epItem.Number
-					m.SelectedAnime.AnimeEntry.Progress = epItem.Number
-					// Update the list items to reflect the progress change
-					items := make([]list.Item, len(m.AnimeEntries))
-					for i, entry := range m.AnimeEntries {
-						items[i] = AnimeItem{AnimeEntry: entry, Index: i}
-					}
-					m.AnimeList.SetItems(items)
-				} else {
-					m.PlannedEntries[m.SelectedAnime.Index].Progress = epItem.Number
-					m.SelectedAnime.AnimeEntry.Progress = epItem.Number
-					// Update the list items to reflect the progress change
-					items := make([]list.Item, len(m.PlannedEntries))
-					for i, entry := range m.PlannedEntries {
-						items[i] = AnimeItem{AnimeEntry: entry, Index: i}
-					}
-					m.PlannedList.SetItems(items)
-				}
-			}
-			if m.ActiveTab == 1 {
-				// Create a confirmation model and prompt the user
-				return m, m.PromptStatusChange()
+			entry := m.SelectedAnime.AnimeEntry
+			// Watching a completed show outside of a rewatch leaves its entry alone
+			if entry.Status == "COMPLETED" {
+				m.State = StateSelecting
+				return m, nil
 			}
+			// Update progress on the tracker, changes that cannot be sent yet are queued
+			m.State = StateSaving
+			return m, m.SaveProgress(*m.SelectedAnime, m.ActiveTab, epItem.Number, m.progressUpdate(entry, epItem.Number))
 		}
 		// Return to selection screen
 		m.State = StateSelecting
@@ -526,20 +1610,90 @@ func (m *Model) handleEpisodePlayed(msg EpisodePlayedMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleProgressSaved shows the saved progress locally and offers the next step
+func (m *Model) handleProgressSaved(msg ProgressSavedMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil && !errors.Is(msg.Err, internal.ErrQueued) {
+		// Nothing was saved, so the entry keeps its old progress and status
+		return m.showError(fmt.Errorf("failed to save progress: %w", msg.Err))
+	}
+
+	entry := msg.Anime.AnimeEntry
+	update := msg.Update
+	// Only move local progress forward if the watched episode is the next one,
+	// which may come after filler that is skipped or hidden
+	if msg.Episode != entry.Progress+1 && !m.onlyFillerBefore(entry, msg.Episode) {
+		update.Progress = nil
+	}
+	entry.ApplyUpdate(update)
+	anime := AnimeItem{AnimeEntry: entry, Index: msg.Anime.Index}
+	m.SelectedAnime = &anime
+	m.replaceEntry(msg.Tab, anime.Index, entry)
+
+	// Offer to complete the series and add its sequels after the final episode
+	if !entry.IsAiring && entry.Episodes > 0 && msg.Episode >= entry.Episodes {
+		m.Finish = NewFinishPrompt(anime, m.Finish.Relations)
+		if entry.Status == "COMPLETED" {
+			m.Finish.Completed = true
+			m.Finish.Changed = true
+		}
+		m.State = StateFinished
+		if m.Finish.Completed && m.Config.Tracking.PromptScore {
+			m.ScorePrompt = NewScorePrompt(entry, m.ScoreFormat)
+			m.State = StateScoring
+			return m, tea.Batch(m.LoadRelations(entry.ID), m.ScorePrompt.Input.Focus())
+		}
+		return m, m.LoadRelations(entry.ID)
+	}
+	if msg.Tab == tabPlanned {
+		// Create a confirmation model and prompt the user
+		return m, m.PromptStatusChange()
+	}
+	m.State = StateSelecting
+	return m, nil
+}
+
+// progressUpdate builds the list update for watching an episode, setting dates and status as configured
+func (m *Model) progressUpdate(entry internal.AnimeEntry, episode int) internal.ListEntryUpdate {
+	update := internal.ListEntryUpdate{
+		MediaID:  entry.ID,
+		Progress: &episode,
+	}
+
+	today := internal.DateOf(time.Now())
+	tracking := m.Config.Tracking
+
+	// Finishing a rewatch counts it and restores the completed status
+	if entry.Status == "REPEATING" {
+		if entry.Episodes > 0 && episode >= entry.Episodes {
+			status := "COMPLETED"
+			repeat := entry.Repeat + 1
+			update.Status = &status
+			update.Repeat = &repeat
+		}
+		return update
+	}
+
+	// Set the start date when the first episode is watched
+	if tracking.SetStartDate && entry.Progress == 0 && entry.StartedAt.IsEmpty() {
+		update.StartedAt = &today
+	}
+
+	// Complete the entry when the final episode of a show that finished airing is watched
+	if tracking.AutoComplete && entry.MediaStatus == "FINISHED" && entry.Episodes > 0 && episode >= entry.Episodes {
+		status := "COMPLETED"
+		update.Status = &status
+		update.CompletedAt = &today
+	}
+
+	return update
+}
+
 // handleStatusChange handles the user's response to a status change prompt
 func (m *Model) handleStatusChange(msg StatusChangeMsg) (tea.Model, tea.Cmd) {
 	if msg.Confirmed {
 		// User confirmed, update the anime status to CURRENT
-		err := m.Anilist.UpdateAnime(m.SelectedAnime.AnimeEntry.ID, m.SelectedAnime.AnimeEntry.Progress, "CURRENT")
-		if err != nil {
-			m.Err = err
-			m.State = StateError
-			return m, nil
-		}
-		// We should refresh the lists since we've moved an item from planned to current
-		m.State = StateLoading
-		m.Loading = true
-		return m, m.InitAnimeLists()
+		m.State = StateSaving
+		return m, m.MoveToWatching(m.SelectedAnime.AnimeEntry)
 	}
 	// User declined or operation complete, return to selection
 	m.State = StateSelecting
@@ -552,39 +1706,68 @@ func (m *Model) View() string {
 		return fmt.Sprintf("\n\n   %s Loading anime list...\n\n", m.Spinner.View())
 	}
 	if m.Err != nil {
-		return fmt.Sprintf("\n\n   %s\n\n", ErrorStyle.Render(m.Err.Error()))
+		return fmt.Sprintf("\n\n   %s\n\n", ErrorStyle.Render(errorText(m.Err)))
 	}
 	switch m.State {
 	case StateSelecting:
 		var b strings.Builder
-		// Render tabs
-		b.WriteString(fmt.Sprintf("\n   %s\n\n", RenderTabs(m.Tabs, m.ActiveTab)))
+		// Render tabs with the unread notifications badge
+		tabs := RenderTabs(m.Tabs, m.ActiveTab)
+		if m.UnreadCount > 0 {
+			badge := BadgeStyle.Render(fmt.Sprintf("%d unread", m.UnreadCount))
+			tabs = lipgloss.JoinHorizontal(lipgloss.Top, tabs, TabGap.Render("  "), badge)
+		}
+		// Show whose lists these are when the PC is shared
+		if len(m.Profiles.Profiles) > 1 {
+			tabs = lipgloss.JoinHorizontal(lipgloss.Top, tabs, TabGap.Render("  "), InfoStyle.Render(m.Config.Profile))
+		}
+		b.WriteString(fmt.Sprintf("\n   %s\n", tabs))
+		// Show list changes that have not reached AniList yet
+		if pending := m.Tracker.PendingChanges(); pending > 0 {
+			b.WriteString("   " + InfoStyle.Render(fmt.Sprintf("⟳ %d change(s) waiting to sync", pending)))
+		}
+		if m.SyncErr != "" {
+			b.WriteString("   " + ErrorStyle.Render(m.SyncErr))
+		}
+		if notice := m.expiryNotice(); notice != "" {
+			b.WriteString("   " + ErrorStyle.Render(notice))
+		}
+		b.WriteString("\n")
 		// Render appropriate list
-		if m.ActiveTab == 0 {
-			b.WriteString(m.AnimeList.View())
+		b.WriteString(m.tabList(m.ActiveTab).View())
+		return b.String()
+	case StateDetails:
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("\n   %s\n\n", RenderTabs([]string{"Anime Details", "Recommendations"}, m.DetailsPane)))
+		if m.DetailsPane == 1 {
+			b.WriteString(m.Recommendations.View())
+			b.WriteString("\n\n   Press [p] to add to Planning, Tab to switch pane, Esc to go back\n")
 		} else {
-			b.WriteString(m.PlannedList.View())
+			b.WriteString(m.Viewport.View())
 		}
 		return b.String()
-	case StateDetails:
+	case StateForYou:
 		var b strings.Builder
-		b.WriteString("\n   " + TitleStyle.Render("Anime Details") + "\n\n")
-		b.WriteString(m.Viewport.View())
+		b.WriteString(fmt.Sprintf("\n   %s\n\n", TitleStyle.Render("For You")))
+		b.WriteString(m.ForYou.View())
+		b.WriteString("\n\n   Press [p] to add to Planning, Esc to go back\n")
 		return b.String()
 	case StateEpisode:
 		var b strings.Builder
 		b.WriteString(fmt.Sprintf("\n   %s\n\n", TitleStyle.Render(m.SelectedAnime.AnimeEntry.Title)))
-		// Show progress
+		// Show progress, keeping a rewatch apart from the original watch
 		progress := m.SelectedAnime.AnimeEntry.Progress
 		episodes := m.SelectedAnime.AnimeEntry.Episodes
-		if episodes > 0 {
+		if m.SelectedAnime.AnimeEntry.Status == "REPEATING" {
+			b.WriteString(fmt.Sprintf("   Rewatch #%d: %d/%d episodes (completed %d time(s) before)\n\n", m.SelectedAnime.AnimeEntry.Repeat+1, progress, episodes, m.SelectedAnime.AnimeEntry.Repeat+1))
+		} else if episodes > 0 {
 			b.WriteString(fmt.Sprintf("   Progress: %d/%d episodes\n\n", progress, episodes))
 		} else {
 			b.WriteString(fmt.Sprintf("   Progress: %d episodes watched\n\n", progress))
 		}
 		// Show the episode list
 		b.WriteString(m.EpisodeList.View())
-		b.WriteString("\n\n   Press Enter to watch, Esc to go back\n")
+		b.WriteString(fmt.Sprintf("\n\n   Press Enter to watch, [F] to change filler (%s), Esc to go back\n", m.Config.Episodes.Filler))
 		return b.String()
 	case StateAnimeSelect:
 		var b strings.Builder
@@ -593,8 +1776,37 @@ func (m *Model) View() string {
 		b.WriteString(m.AnimeSearchList.View())
 		b.WriteString("\n\n   Press Enter to select, Esc to go back\n")
 		return b.String()
+	case StateEditing:
+		return m.Editor.View()
+	case StateSearch:
+		return m.Search.View()
+	case StateChart:
+		return m.Chart.View()
+	case StateSchedule:
+		return m.Schedule.View()
+	case StateFinished:
+		return m.Finish.View()
+	case StateScoring:
+		return m.ScorePrompt.View()
+	case StateProfiles:
+		return m.Switcher.View(m.Config != nil)
+	case StateLogin:
+		return m.Login.View()
+	case StateBulk:
+		return m.viewBulk()
+	case StateInbox:
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("\n   %s\n\n", TitleStyle.Render("Notifications")))
+		b.WriteString(m.NotificationList.View())
+		if m.InboxErr != "" {
+			b.WriteString("\n   " + ErrorStyle.Render(m.InboxErr) + "\n")
+		}
+		b.WriteString("\n\n   Press Enter on an airing notification to open the episode, [r] to mark all as read, Esc to go back\n")
+		return b.String()
 	case StateLoading:
 		return fmt.Sprintf("\n\n   %s Loading episode...\n\n", m.Spinner.View())
+	case StateSaving:
+		return fmt.Sprintf("\n\n   %s Saving progress...\n\n", m.Spinner.View())
 	case StateConfirming:
 		var b strings.Builder
 		b.WriteString(fmt.Sprintf("\n\n   %s\n\n", TitleStyle.Render("Move to Currently Watching?")))
@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daannte/aniview/internal"
+)
+
+// NotificationItem represents an AniList notification in the notifications list
+type NotificationItem struct {
+	Notification internal.Notification
+}
+
+func (i NotificationItem) Title() string {
+	n := i.Notification
+
+	var title string
+	if n.Media != nil {
+		title = n.Media.Title.Preferred()
+	}
+
+	switch {
+	case n.Type == "AIRING" && len(n.Contexts) == 3:
+		return fmt.Sprintf("%s%d%s%s%s", n.Contexts[0], n.Episode, n.Contexts[1], title, n.Contexts[2])
+	case n.User != nil:
+		return n.User.Name + n.Context
+	case title != "":
+		return strings.TrimSpace(title + n.Context)
+	}
+	return n.Context
+}
+
+func (i NotificationItem) Description() string {
+	created := time.Unix(i.Notification.CreatedAt, 0).Local().Format("Mon 2 Jan 15:04")
+	if i.Notification.Reason != "" {
+		return created + " · " + i.Notification.Reason
+	}
+	return created
+}
+
+func (i NotificationItem) FilterValue() string {
+	return i.Title()
+}
@@ -0,0 +1,174 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/daannte/aniview/internal"
+)
+
+// ProfileItem represents a profile in the profile switcher
+type ProfileItem struct {
+	Config  *internal.Config
+	Current bool
+}
+
+func (i ProfileItem) Title() string {
+	if i.Current {
+		return i.Config.Profile + " (current)"
+	}
+	return i.Config.Profile
+}
+
+func (i ProfileItem) Description() string {
+	tracker := internal.TrackerName(i.Config.Tracker)
+	if i.Config.Username == "" {
+		return tracker + " user unknown"
+	}
+	return tracker + " user " + i.Config.Username
+}
+
+func (i ProfileItem) FilterValue() string {
+	return i.Config.Profile
+}
+
+// ProfileSwitcher holds the state of the profile switcher screen
+type ProfileSwitcher struct {
+	List list.Model
+	Err  string
+}
+
+// Open fills the switcher with every profile, selecting the current one
+func (p *ProfileSwitcher) Open(profiles *internal.Profiles, current *internal.Config) {
+	p.Err = ""
+
+	names := profiles.Names()
+	items := make([]list.Item, len(names))
+	selected := 0
+	for i, name := range names {
+		isCurrent := current != nil && current.Profile == name
+		if isCurrent {
+			selected = i
+		}
+		items[i] = ProfileItem{Config: profiles.Profiles[name], Current: isCurrent}
+	}
+	p.List.SetItems(items)
+	p.List.Select(selected)
+}
+
+// View renders the profile switcher
+func (p ProfileSwitcher) View(canGoBack bool) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("\n   %s\n\n", TitleStyle.Render("Profiles")))
+	b.WriteString(p.List.View())
+	if p.Err != "" {
+		b.WriteString("\n   " + ErrorStyle.Render(p.Err) + "\n")
+	}
+
+	if canGoBack {
+		b.WriteString("\n\n   Press Enter to switch profile, Esc to go back\n")
+	} else {
+		b.WriteString("\n\n   Press Enter to choose a profile, Ctrl+C to quit\n")
+	}
+	b.WriteString("   Run with --profile <name> to add a profile\n")
+	return b.String()
+}
+
+// handleProfileKey handles keyboard input on the profile switcher
+func (m *Model) handleProfileKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		// There is nothing to go back to before a profile is chosen
+		if m.Config != nil && !m.Switcher.List.SettingFilter() {
+			m.State = StateSelecting
+			return m, nil
+		}
+	case "enter":
+		if m.Switcher.List.SettingFilter() {
+			break
+		}
+		item, ok := m.Switcher.List.SelectedItem().(ProfileItem)
+		if !ok {
+			return m, nil
+		}
+		if item.Current {
+			m.State = StateSelecting
+			return m, nil
+		}
+		if err := m.switchProfile(item.Config); err != nil {
+			m.Switcher.Err = err.Error()
+			return m, nil
+		}
+		m.State = StateLoading
+		m.Loading = true
+		return m, m.InitAnimeLists()
+	}
+
+	var cmd tea.Cmd
+	m.Switcher.List, cmd = m.Switcher.List.Update(msg)
+	return m, cmd
+}
+
+// switchProfile makes the given profile current, dropping everything loaded for the previous one
+func (m *Model) switchProfile(config *internal.Config) error {
+	tracker, anilist, err := internal.NewProfileClients(config)
+	if err != nil {
+		return fmt.Errorf("failed to open profile %s: %w", config.Profile, err)
+	}
+
+	m.Config = config
+	m.setClients(tracker, anilist)
+
+	// Remember the profile for the next start
+	m.Profiles.Default = config.Profile
+	if err := m.Profiles.Save(); err != nil {
+		m.SyncErr = err.Error()
+	} else {
+		m.SyncErr = ""
+	}
+
+	m.ScoreFormat = ""
+	m.UnreadCount = 0
+	m.Chart.Loaded = false
+	m.Chart.SetMedia(nil)
+	m.ForYou.Reset(0)
+	m.NotificationList.SetItems(nil)
+	m.resetListState()
+	return nil
+}
+
+// resetListState drops the entries of the previous profile and everything opened from them,
+// so nothing can act on an entry that belongs to another list
+func (m *Model) resetListState() {
+	m.AnimeEntries = nil
+	m.PlannedEntries = nil
+	m.CompletedEntries = nil
+	for _, tab := range []int{tabWatching, tabPlanned, tabCompleted} {
+		// Also drops the bulk selection, which is kept on the items
+		m.tabList(tab).ResetFilter()
+		m.tabList(tab).SetItems(nil)
+	}
+	m.ActiveTab = tabWatching
+
+	m.SelectedAnime = nil
+	m.SelectedEpisode = 0
+	m.AnimeSearchResults = nil
+	m.EpisodeList.SetItems(nil)
+	m.Editor = EntryEditor{}
+	m.Bulk = BulkEdit{}
+	m.ScorePrompt = ScorePrompt{}
+	m.Finish = NewFinishPrompt(AnimeItem{}, m.Finish.Relations)
+	m.DetailsPane = 0
+	m.Recommendations.Reset(0)
+	m.Search.Results.SetItems(nil)
+	m.Search.InResults = false
+	m.Search.Added = false
+	m.Chart.Added = false
+	m.Schedule.Airings = nil
+	m.InboxErr = ""
+}
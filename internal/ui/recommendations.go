@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/daannte/aniview/internal"
+)
+
+// RecommendationPane holds a list of recommended anime that can be added to Planning
+type RecommendationPane struct {
+	MediaID int // The anime the recommendations are for, 0 for the "for you" list
+	List    list.Model
+	Loaded  bool
+	Loading bool
+	Status  string
+	Err     string
+	Added   bool // Whether anything was added to the user's lists
+}
+
+// Reset clears the pane so it can be loaded for another anime
+func (r *RecommendationPane) Reset(mediaID int) {
+	r.MediaID = mediaID
+	r.List.SetItems(nil)
+	r.List.ResetSelected()
+	r.Loaded = false
+	r.Loading = false
+	r.Status = ""
+	r.Err = ""
+}
+
+// SetRecommendations shows the recommendations in rating order
+func (r *RecommendationPane) SetRecommendations(recommendations []internal.Recommendation) {
+	items := make([]list.Item, len(recommendations))
+	for i, recommendation := range recommendations {
+		items[i] = MediaItem{Media: recommendation.Media, Rating: recommendation.Rating}
+	}
+
+	r.List.SetItems(items)
+	r.List.ResetSelected()
+	r.Loaded = true
+	r.Loading = false
+}
+
+// MarkListed records that a recommended anime was added to the user's list with the given status
+func (r *RecommendationPane) MarkListed(mediaID int, status string) {
+	markListed(&r.List, mediaID, status)
+}
+
+// View renders the pane
+func (r RecommendationPane) View() string {
+	var b strings.Builder
+
+	switch {
+	case r.Loading:
+		b.WriteString("   Loading recommendations...\n")
+	case r.Loaded && len(r.List.Items()) == 0:
+		b.WriteString("   No recommendations found.\n")
+	default:
+		b.WriteString(r.List.View())
+	}
+
+	if r.Err != "" {
+		b.WriteString("\n   " + ErrorStyle.Render(r.Err) + "\n")
+	} else if r.Status != "" {
+		b.WriteString("\n   " + InfoStyle.Render(r.Status) + "\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/daannte/aniview/internal"
+)
+
+// Schedule holds the state of the weekly airing schedule screen
+type Schedule struct {
+	WeekStart time.Time
+	Airings   []internal.AiringSchedule
+	Viewport  viewport.Model
+	Loading   bool
+	Err       string
+}
+
+// NewSchedule creates a schedule for the week containing the given time
+func NewSchedule(now time.Time, vp viewport.Model) Schedule {
+	return Schedule{
+		WeekStart: startOfWeek(now),
+		Viewport:  vp,
+	}
+}
+
+// WeekEnd returns the end of the displayed week
+func (s Schedule) WeekEnd() time.Time {
+	return s.WeekStart.AddDate(0, 0, 7)
+}
+
+// ShiftWeek moves the schedule forwards or backwards by whole weeks
+func (s *Schedule) ShiftWeek(delta int) {
+	s.WeekStart = s.WeekStart.AddDate(0, 0, 7*delta)
+	s.Airings = nil
+}
+
+// Render builds the timetable for the week from the user's list entries
+func (s *Schedule) Render(entries map[int]internal.AnimeEntry, now time.Time) {
+	var b strings.Builder
+
+	for day := 0; day < 7; day++ {
+		dayStart := s.WeekStart.AddDate(0, 0, day)
+		dayEnd := dayStart.AddDate(0, 0, 1)
+
+		heading := dayStart.Format("Monday 2 Jan")
+		if !now.Before(dayStart) && now.Before(dayEnd) {
+			heading += " (Today)"
+		}
+		b.WriteString(SelectedStyle.Render(heading) + "\n")
+
+		count := 0
+		for _, airing := range s.Airings {
+			airingAt := time.Unix(airing.AiringAt, 0).Local()
+			if airingAt.Before(dayStart) || !airingAt.Before(dayEnd) {
+				continue
+			}
+			count++
+
+			anime := entries[airing.MediaID]
+			line := fmt.Sprintf("  %s  %s - Episode %d", airingAt.Format("15:04"), anime.Title, airing.Episode)
+			if airingAt.Before(now) {
+				if airing.Episode > anime.Progress {
+					line += "  " + ErrorStyle.Render("[not watched]")
+				} else {
+					line += "  " + InfoStyle.Render("[watched]")
+				}
+			}
+			b.WriteString(line + "\n")
+		}
+
+		if count == 0 {
+			b.WriteString(InfoStyle.Render("  Nothing airing") + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	s.Viewport.SetContent(b.String())
+	s.Viewport.GotoTop()
+}
+
+// View renders the schedule screen
+func (s Schedule) View() string {
+	var b strings.Builder
+
+	title := fmt.Sprintf("Schedule %s - %s", s.WeekStart.Format("2 Jan"), s.WeekEnd().AddDate(0, 0, -1).Format("2 Jan 2006"))
+	b.WriteString(fmt.Sprintf("\n   %s\n\n", TitleStyle.Render(title)))
+
+	switch {
+	case s.Err != "":
+		b.WriteString("   " + ErrorStyle.Render(s.Err) + "\n")
+	case s.Loading:
+		b.WriteString("   Loading schedule...\n")
+	default:
+		b.WriteString(s.Viewport.View())
+	}
+
+	b.WriteString("\n\n   [ and ] to change week, Esc to go back\n")
+	return b.String()
+}
+
+// startOfWeek returns midnight on the Monday of the week containing t
+func startOfWeek(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
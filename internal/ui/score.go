@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/daannte/aniview/internal"
+)
+
+// ScorePrompt holds the state of the prompt asking for a score after completing a show
+type ScorePrompt struct {
+	Anime       internal.AnimeEntry
+	ScoreFormat string
+	Input       textinput.Model
+	Err         string
+}
+
+// NewScorePrompt creates a score prompt for an anime
+func NewScorePrompt(anime internal.AnimeEntry, scoreFormat string) ScorePrompt {
+	input := textinput.New()
+	input.Prompt = ""
+	input.Placeholder = fmt.Sprintf("0-%g", internal.ScoreRange(scoreFormat))
+	input.CharLimit = 5
+	input.SetValue(internal.FormatScore(scoreFormat, anime.Score))
+
+	return ScorePrompt{
+		Anime:       anime,
+		ScoreFormat: scoreFormat,
+		Input:       input,
+	}
+}
+
+// View renders the prompt
+func (s ScorePrompt) View() string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("\n\n   %s\n\n", TitleStyle.Render("Rate "+s.Anime.Title)))
+	b.WriteString(fmt.Sprintf("   Score (0-%g): %s\n", internal.ScoreRange(s.ScoreFormat), s.Input.View()))
+
+	if s.Err != "" {
+		b.WriteString("\n   " + ErrorStyle.Render(s.Err) + "\n")
+	}
+
+	b.WriteString("\n   Press Enter to save, Esc to skip\n")
+	return b.String()
+}
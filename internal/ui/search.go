@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/daannte/aniview/internal"
+)
+
+// Search form fields in the order they are shown
+const (
+	searchQuery = iota
+	searchFormat
+	searchYear
+	searchSeason
+	searchGenre
+	searchFieldCount
+)
+
+var searchLabels = []string{"Title", "Format", "Year", "Season", "Genre"}
+
+// CatalogSearch holds the state of the AniList catalog search screen
+type CatalogSearch struct {
+	Focus       int
+	InResults   bool // Whether keys go to the results list instead of the form
+	Query       textinput.Model
+	Year        textinput.Model
+	FormatIndex int // 0 = any format
+	SeasonIndex int // 0 = any season
+	GenreIndex  int // 0 = any genre
+	Results     list.Model
+	Status      string
+	Err         string
+	Added       bool // Whether anything was added to the user's lists
+}
+
+// NewCatalogSearch creates an empty catalog search screen
+func NewCatalogSearch(results list.Model) CatalogSearch {
+	query := textinput.New()
+	query.Prompt = ""
+	query.Placeholder = "Any title"
+	query.Width = 40
+
+	year := textinput.New()
+	year.Prompt = ""
+	year.Placeholder = "Any year"
+	year.CharLimit = 4
+
+	return CatalogSearch{
+		Query:   query,
+		Year:    year,
+		Results: results,
+	}
+}
+
+// setFocus moves focus to the given form field
+func (s *CatalogSearch) setFocus(field int) tea.Cmd {
+	s.Query.Blur()
+	s.Year.Blur()
+	s.Focus = cycle(field, 0, searchFieldCount)
+	switch s.Focus {
+	case searchQuery:
+		return s.Query.Focus()
+	case searchYear:
+		return s.Year.Focus()
+	}
+	return nil
+}
+
+// Update handles input for the search form
+func (s CatalogSearch) Update(msg tea.Msg) (CatalogSearch, tea.Cmd) {
+	if s.InResults {
+		var cmd tea.Cmd
+		s.Results, cmd = s.Results.Update(msg)
+		return s, cmd
+	}
+
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "tab", "down":
+			return s, s.setFocus(s.Focus + 1)
+		case "shift+tab", "up":
+			return s, s.setFocus(s.Focus - 1)
+		case "left", "right":
+			delta := 1
+			if keyMsg.String() == "left" {
+				delta = -1
+			}
+			switch s.Focus {
+			case searchFormat:
+				s.FormatIndex = cycle(s.FormatIndex, delta, len(internal.MediaFormats)+1)
+				return s, nil
+			case searchSeason:
+				s.SeasonIndex = cycle(s.SeasonIndex, delta, len(internal.MediaSeasons)+1)
+				return s, nil
+			case searchGenre:
+				s.GenreIndex = cycle(s.GenreIndex, delta, len(internal.MediaGenres)+1)
+				return s, nil
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	switch s.Focus {
+	case searchQuery:
+		s.Query, cmd = s.Query.Update(msg)
+	case searchYear:
+		s.Year, cmd = s.Year.Update(msg)
+	}
+	return s, cmd
+}
+
+// Filter validates the form and returns the search filter
+func (s CatalogSearch) Filter() (internal.MediaSearchFilter, error) {
+	filter := internal.MediaSearchFilter{
+		Search: strings.TrimSpace(s.Query.Value()),
+	}
+
+	if value := strings.TrimSpace(s.Year.Value()); value != "" {
+		year, err := strconv.Atoi(value)
+		if err != nil || year < 1940 {
+			return filter, fmt.Errorf("Year: must be a four digit year")
+		}
+		filter.SeasonYear = year
+	}
+	if s.FormatIndex > 0 {
+		filter.Format = internal.MediaFormats[s.FormatIndex-1]
+	}
+	if s.SeasonIndex > 0 {
+		filter.Season = internal.MediaSeasons[s.SeasonIndex-1]
+	}
+	if s.GenreIndex > 0 {
+		filter.Genre = internal.MediaGenres[s.GenreIndex-1]
+	}
+
+	return filter, nil
+}
+
+// View renders the search screen
+func (s CatalogSearch) View() string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("\n   %s\n\n", TitleStyle.Render("Search AniList")))
+
+	for field := 0; field < searchFieldCount; field++ {
+		var value string
+		switch field {
+		case searchQuery:
+			value = s.Query.View()
+		case searchYear:
+			value = s.Year.View()
+		case searchFormat:
+			value = "< " + optionLabel(internal.MediaFormats, s.FormatIndex) + " >"
+		case searchSeason:
+			value = "< " + optionLabel(internal.MediaSeasons, s.SeasonIndex) + " >"
+		case searchGenre:
+			value = "< " + optionLabel(internal.MediaGenres, s.GenreIndex) + " >"
+		}
+
+		label := fmt.Sprintf("%-10s", searchLabels[field])
+		if field == s.Focus && !s.InResults {
+			b.WriteString("   " + SelectedStyle.Render("> "+label) + value + "\n")
+		} else {
+			b.WriteString("     " + label + value + "\n")
+		}
+	}
+
+	if s.Err != "" {
+		b.WriteString("\n   " + ErrorStyle.Render(s.Err) + "\n")
+	} else if s.Status != "" {
+		b.WriteString("\n   " + InfoStyle.Render(s.Status) + "\n")
+	}
+	b.WriteString("\n")
+
+	if s.InResults {
+		b.WriteString(s.Results.View())
+		b.WriteString("\n\n   Press [p] to add to Planning, [c] to add to Currently Watching, Esc to edit the search\n")
+	} else {
+		b.WriteString("   Press Enter to search, Esc to go back\n")
+	}
+
+	return b.String()
+}
@@ -16,6 +16,9 @@ var (
 	InfoStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#DDDDDD"))
 
+	MutedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#808080"))
+
 	SelectedStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#04B575"))
 
@@ -36,4 +39,10 @@ var (
 			Bold(true) // Bold text for active tab
 
 	TabGap = lipgloss.NewStyle().Width(1)
+
+	BadgeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFFFFF")).
+			Background(lipgloss.Color("#FF5F87")).
+			Padding(0, 1).
+			Bold(true)
 )
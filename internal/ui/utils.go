@@ -1,10 +1,12 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/daannte/aniview/internal"
 )
 
 // FormatTimeUntil formats time until airing in a human-readable format
@@ -106,3 +108,74 @@ func parseAnimeTitle(fullTitle string) (string, string) {
 
 	return title, episodeCount
 }
+
+// cycle moves an index by delta, wrapping around within n options
+func cycle(index int, delta int, n int) int {
+	return (index + delta + n) % n
+}
+
+// optionLabel returns the label of an optional choice, where index 0 means no choice
+func optionLabel(options []string, index int) string {
+	if index == 0 {
+		return "Any"
+	}
+	return options[index-1]
+}
+
+// listName returns the display name of a list status
+func listName(status string) string {
+	switch status {
+	case "CURRENT":
+		return "Currently Watching"
+	case "PLANNING":
+		return "Planned"
+	case "COMPLETED":
+		return "Completed"
+	case "REPEATING":
+		return "Rewatching"
+	case "PAUSED":
+		return "Paused"
+	case "DROPPED":
+		return "Dropped"
+	}
+	return status
+}
+
+// seasonName returns the display name of a season, e.g. "Winter" for WINTER
+func seasonName(season string) string {
+	if season == "" {
+		return ""
+	}
+	return season[:1] + strings.ToLower(season[1:])
+}
+
+// relationName returns the display name of a media relation type
+func relationName(relation string) string {
+	switch relation {
+	case "SEQUEL":
+		return "Sequel"
+	case "PREQUEL":
+		return "Prequel"
+	case "SIDE_STORY":
+		return "Side story"
+	}
+	return relation
+}
+
+// errorText describes an error along with what the user can do about it
+func errorText(err error) string {
+	var apiErr *internal.APIError
+	switch {
+	case internal.IsAuthError(err):
+		return "Your login has expired or was revoked, press [L] on the lists to log in again"
+	case internal.IsRateLimited(err):
+		return "Too many requests were sent, wait a minute and try again"
+	case errors.Is(err, internal.ErrNotFound):
+		return "The tracker couldn't find it, it may have been deleted or merged into another entry"
+	case errors.Is(err, internal.ErrValidation) && errors.As(err, &apiErr):
+		return fmt.Sprintf("The change was rejected (%s), check the values and try again", apiErr.Error())
+	case internal.IsTemporary(err):
+		return "The server couldn't be reached, check your connection and try again"
+	}
+	return err.Error()
+}